@@ -11,13 +11,29 @@
 package main
 
 import (
+	"context"
 	_ "go-user-api/docs"
+	"go-user-api/internal/authn"
 	"go-user-api/internal/db"
+	"go-user-api/internal/federation"
 	"go-user-api/internal/handler"
+	"go-user-api/internal/logging"
+	"go-user-api/internal/mail"
+	"go-user-api/internal/metrics"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/policy"
+	"go-user-api/internal/ratelimit"
+	"go-user-api/internal/rebac"
 	"go-user-api/internal/repository"
 	"go-user-api/internal/routes"
+	"go-user-api/internal/tracing"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/joho/godotenv"
@@ -26,29 +42,342 @@ import (
 
 func main() {
 	// load .env file
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found proceeding with system env vars")
+	envErr := godotenv.Load()
+
+	// LOG_LEVEL is debug, info, warn or error and LOG_FORMAT json or text
+	logger, err := logging.New(os.Stdout, os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(logger)
+
+	if envErr != nil {
+		slog.Info("No .env file found proceeding with system env vars")
+	}
+
+	// OTEL_TRACES_EXPORTER is otlp, stdout or none, the default
+	shutdownTracing, err := tracing.Setup(context.Background(), os.Getenv("OTEL_TRACES_EXPORTER"))
+	if err != nil {
+		fatal("Failed to set up tracing", err)
 	}
+	defer shutdownTracing(context.Background())
 
 	conn, err := db.Connect()
 
 	if err != nil {
-		log.Fatal("Failed to connect to DB:", err)
+		fatal("Failed to connect to DB", err)
 	}
 
 	defer conn.Close()
+	metrics.RegisterPool(conn)
 
 	UserRepo := repository.NewUserRepo(conn)
-	userHandler := handler.NewUserHandler(UserRepo)
-	authHandler := handler.NewAuthRouteHandler(UserRepo)
+	AuditRepo := repository.NewAuditRepo(conn)
+	userHandler := handler.NewUserHandler(UserRepo, AuditRepo)
+	auditHandler := handler.NewAuditHandler(AuditRepo)
+
+	OrganizationRepo := repository.NewOrganizationRepo(conn)
+	organizationHandler := handler.NewOrganizationHandler(OrganizationRepo, UserRepo, AuditRepo)
+
+	var authenticator authn.Authenticator = authn.NewLocal(UserRepo)
+	ldapConfig, err := authn.LoadLDAPConfig()
+	if err != nil {
+		fatal("Failed to load LDAP config", err)
+	}
+
+	if ldapConfig != nil {
+		directory, err := authn.NewLDAP(*ldapConfig, UserRepo)
+		if err != nil {
+			fatal("Failed to configure LDAP", err)
+		}
+
+		// directory users sign in with directory passwords; everyone else
+		// falls through to local passwords
+		authenticator = authn.Chain{directory, authn.NewLocal(UserRepo)}
+	}
+
+	SessionRepo := repository.NewSessionRepo(conn)
+	sessionHandler := handler.NewSessionHandler(SessionRepo)
+	impersonationHandler := handler.NewImpersonationHandler(UserRepo, AuditRepo)
+
+	GroupRepo := repository.NewGroupRepo(conn)
+	groupHandler := handler.NewGroupHandler(GroupRepo, UserRepo, AuditRepo)
+
+	mailer := mail.FromEnv()
+	LoginAttemptRepo := repository.NewLoginAttemptRepo(conn)
+
+	authOptions := []handler.AuthOption{
+		handler.WithAuthenticator(authenticator), handler.WithSessions(SessionRepo), handler.WithAudit(AuditRepo),
+		handler.WithLoginHistory(LoginAttemptRepo, mailer),
+	}
+	if os.Getenv("AUTH_COOKIE_MODE") == "true" {
+		// browser front ends get an HttpOnly session cookie instead of a token
+		authOptions = append(authOptions, handler.WithCookieAuth())
+	}
+	if os.Getenv("TOKEN_GROUP_CLAIMS") == "true" {
+		// tokens name the user's groups, for services that authorize by them
+		authOptions = append(authOptions, handler.WithGroupClaims(GroupRepo))
+	}
+
+	authHandler := handler.NewAuthRouteHandler(UserRepo, authOptions...)
+
+	ClientRepo := repository.NewClientRepo(conn)
+	GrantRepo := repository.NewGrantRepo(conn)
+	// tokens of ended sessions, revoked access tokens and tokens of disabled
+	// users are refused, by the API and by introspection alike, and requests
+	// made while impersonating are audited
+	tokenChecks := []middleware.TokenCheck{middleware.SessionCheck(SessionRepo), middleware.RevocationCheck(GrantRepo), middleware.ActiveUserCheck(UserRepo)}
+	jwtAuth := middleware.NewJWTAuth(tokenChecks...).OnImpersonation(impersonationHandler.AuditRequests)
+	clientHandler := handler.NewClientHandler(ClientRepo)
+	oauthHandler := handler.NewOAuthHandler(ClientRepo, GrantRepo, UserRepo, authHandler, tokenChecks...)
+	oidcHandler := handler.NewOIDCHandler(UserRepo)
+
+	providers, err := federation.LoadProviders()
+	if err != nil {
+		fatal("Failed to load OIDC providers", err)
+	}
+
+	IdentityRepo := repository.NewIdentityRepo(conn)
+	federationHandler := handler.NewFederationHandler(providers, IdentityRepo, UserRepo, authHandler)
+
+	magicLinkHandler := handler.NewMagicLinkHandler(UserRepo, GrantRepo, mailer, authHandler)
+
+	InvitationRepo := repository.NewInvitationRepo(conn)
+	invitationHandler := handler.NewInvitationHandler(InvitationRepo, OrganizationRepo, UserRepo, mailer, authHandler, AuditRepo)
+
+	scimHandler := handler.NewSCIMHandler(UserRepo, GroupRepo)
+
+	schemaSource := rebac.DefaultSchema
+	if path := os.Getenv("REBAC_SCHEMA_FILE"); path != "" {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fatal("Failed to read ReBAC schema", err)
+		}
+		schemaSource = string(src)
+	}
+
+	schema, err := rebac.ParseSchema(schemaSource)
+	if err != nil {
+		fatal("Failed to parse ReBAC schema", err)
+	}
+
+	authz := rebac.NewEngine(schema, repository.NewTupleRepo(conn))
+	relationHandler := handler.NewRelationHandler(authz)
+
+	// the user routes predate authentication, so guarding them is opt-in
+	var userRoutesAuthz *rebac.Engine
+	if os.Getenv("REBAC_USER_ROUTES") == "true" {
+		userRoutesAuthz = authz
+	}
+
+	// TRUSTED_PROXIES lists the load balancers, as addresses or CIDR
+	// ranges, whose X-Forwarded-For is believed
+	trustedProxies, err := middleware.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		fatal("Invalid TRUSTED_PROXIES", err)
+	}
+
+	// REQUEST_TIMEOUT bounds the time a request may take, 0 disables it
+	requestTimeout := 30 * time.Second
+	if v := os.Getenv("REQUEST_TIMEOUT"); v != "" {
+		if requestTimeout, err = time.ParseDuration(v); err != nil || requestTimeout < 0 {
+			fatal("Invalid REQUEST_TIMEOUT", v)
+		}
+	}
+
+	// RATE_LIMIT_STORE keeps rate limits in memory, per instance, or in
+	// postgres, shared by every replica
+	var rateLimits ratelimit.Store
+	switch store := os.Getenv("RATE_LIMIT_STORE"); store {
+	case "", "memory":
+		rateLimits = ratelimit.NewMemoryStore()
+	case "postgres":
+		rateLimitRepo := repository.NewRateLimitRepo(conn)
+		rateLimits = rateLimitRepo
+		go func() {
+			for range time.Tick(time.Minute) {
+				if _, err := rateLimitRepo.DeleteExpired(context.Background(), time.Now()); err != nil {
+					slog.Error("failed to delete expired rate limits", "error", err)
+				}
+			}
+		}()
+	default:
+		fatal("Invalid RATE_LIMIT_STORE", store)
+	}
+
+	// rates are given as limit/period[:burst], e.g. 10/1m, and off turns a
+	// limit off
+	rateLimit := func(env, fallback string, key middleware.RateLimitKey) func(http.Handler) http.Handler {
+		v := os.Getenv(env)
+		if v == "" {
+			v = fallback
+		}
+		if v == "off" {
+			return nil
+		}
+
+		rate, err := ratelimit.ParseRate(v)
+		if err != nil {
+			fatal("Invalid "+env, err)
+		}
+		return middleware.RateLimit(rateLimits, middleware.RateLimitPolicy{Name: strings.ToLower(env), Rate: rate, Key: key})
+	}
+
+	// RATE_LIMIT_AUTH limits sign-ups and logins, including the OAuth login
+	// form, per client address
+	var authLimits []func(http.Handler) http.Handler
+	if limit := rateLimit("RATE_LIMIT_AUTH", "10/1m", middleware.KeyByIP); limit != nil {
+		authLimits = append(authLimits, limit)
+	}
+
+	// RATE_LIMIT_API limits all requests per RATE_LIMIT_API_KEY, which is
+	// token (the default), user or ip
+	apiKey := middleware.KeyByToken(jwtAuth)
+	switch v := os.Getenv("RATE_LIMIT_API_KEY"); v {
+	case "", "token":
+	case "user":
+		apiKey = middleware.KeyByUser(jwtAuth)
+	case "ip":
+		apiKey = middleware.KeyByIP
+	default:
+		fatal("Invalid RATE_LIMIT_API_KEY", v)
+	}
+	apiLimit := rateLimit("RATE_LIMIT_API", "off", apiKey)
+
+	// CORS_ALLOWED_ORIGINS lists the origins, such as https://app.example.com
+	// or https://*.example.com, whose scripts may call the API
+	var cors func(http.Handler) http.Handler
+	if origins := envList("CORS_ALLOWED_ORIGINS"); len(origins) > 0 {
+		corsOptions := middleware.DefaultCORSOptions()
+		corsOptions.AllowedOrigins = origins
+		if methods := envList("CORS_ALLOWED_METHODS"); len(methods) > 0 {
+			corsOptions.AllowedMethods = methods
+		}
+		if headers := envList("CORS_ALLOWED_HEADERS"); len(headers) > 0 {
+			corsOptions.AllowedHeaders = headers
+		}
+		if headers := envList("CORS_EXPOSED_HEADERS"); len(headers) > 0 {
+			corsOptions.ExposedHeaders = headers
+		}
+		corsOptions.AllowCredentials = os.Getenv("CORS_ALLOW_CREDENTIALS") == "true"
+		if v := os.Getenv("CORS_MAX_AGE"); v != "" {
+			if corsOptions.MaxAge, err = time.ParseDuration(v); err != nil || corsOptions.MaxAge < 0 {
+				fatal("Invalid CORS_MAX_AGE", v)
+			}
+		}
+		if err := corsOptions.Validate(); err != nil {
+			fatal("Invalid CORS configuration", err)
+		}
+		cors = middleware.CORS(corsOptions)
+	}
+
+	// HSTS_MAX_AGE is how long browsers must stick to HTTPS, 0 leaves the
+	// header out
+	hstsMaxAge := 365 * 24 * time.Hour
+	if v := os.Getenv("HSTS_MAX_AGE"); v != "" {
+		if hstsMaxAge, err = time.ParseDuration(v); err != nil || hstsMaxAge < 0 {
+			fatal("Invalid HSTS_MAX_AGE", v)
+		}
+	}
+
+	// MAX_BODY_SIZE caps request bodies in bytes, 0 lifts the cap
+	maxBodySize := int64(1 << 20)
+	if v := os.Getenv("MAX_BODY_SIZE"); v != "" {
+		if maxBodySize, err = strconv.ParseInt(v, 10, 64); err != nil || maxBodySize < 0 {
+			fatal("Invalid MAX_BODY_SIZE", v)
+		}
+	}
 
 	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(middleware.RealIP(trustedProxies))
+	r.Use(middleware.RequestLogger(logger))
+	r.Use(middleware.Tracing)
+	if os.Getenv("ACCESS_LOG") != "false" {
+		r.Use(middleware.AccessLog)
+	}
+	r.Use(middleware.Metrics)
+	r.Use(middleware.Recoverer)
+	if requestTimeout > 0 {
+		r.Use(middleware.Timeout(requestTimeout))
+	}
+	if cors != nil {
+		r.Use(cors)
+	}
+	r.Use(middleware.SecurityHeaders(hstsMaxAge))
+	if maxBodySize > 0 {
+		r.Use(middleware.BodyLimit(maxBodySize))
+	}
+	if apiLimit != nil {
+		r.Use(apiLimit)
+	}
+
+	// organizations are named by the X-Tenant-ID header or a subdomain of
+	// TENANT_BASE_DOMAIN, e.g. acme.example.com
+	r.Use(middleware.ResolveTenant(OrganizationRepo, os.Getenv("TENANT_BASE_DOMAIN")))
+
+	// POLICY_FILE holds CEL authorization rules; changes to it are picked
+	// up every POLICY_RELOAD_INTERVAL without a restart
+	if path := os.Getenv("POLICY_FILE"); path != "" {
+		policies, err := policy.NewEngine(path)
+		if err != nil {
+			fatal("Failed to load policy", err)
+		}
+
+		interval := 10 * time.Second
+		if v := os.Getenv("POLICY_RELOAD_INTERVAL"); v != "" {
+			if interval, err = time.ParseDuration(v); err != nil || interval <= 0 {
+				fatal("Invalid POLICY_RELOAD_INTERVAL", v)
+			}
+		}
+
+		p := policies.Policy()
+		slog.Info("policy loaded", "version", p.Version, "mode", p.Mode)
+		go policies.Watch(context.Background(), interval)
+		r.Use(middleware.Policy(policies, jwtAuth, UserRepo))
+	}
 
 	// register routes
-	r.Get("/swagger/*", httpSwagger.WrapHandler)
-	routes.RegisterUserRoutes(r, userHandler)
-	routes.RegisterAuthRoutes(r, authHandler)
+	r.With(middleware.ContentSecurityPolicy(middleware.SwaggerContentSecurityPolicy)).Get("/swagger/*", httpSwagger.WrapHandler)
+	r.Method(http.MethodGet, "/metrics", metrics.Handler())
+	routes.RegisterUserRoutes(r, userHandler, jwtAuth, userRoutesAuthz)
+	routes.RegisterAuthRoutes(r, authHandler, jwtAuth, authLimits...)
+	routes.RegisterOAuthRoutes(r, oauthHandler, authLimits...)
+	routes.RegisterOIDCRoutes(r, oidcHandler, jwtAuth)
+	routes.RegisterFederationRoutes(r, federationHandler, jwtAuth)
+	routes.RegisterMagicLinkRoutes(r, magicLinkHandler)
+	routes.RegisterSessionRoutes(r, sessionHandler, jwtAuth, UserRepo)
+	routes.RegisterImpersonationRoutes(r, impersonationHandler, jwtAuth, UserRepo)
+	routes.RegisterAuditRoutes(r, auditHandler, jwtAuth, UserRepo)
+	routes.RegisterClientRoutes(r, clientHandler, jwtAuth, UserRepo)
+	routes.RegisterOrganizationRoutes(r, organizationHandler, jwtAuth, UserRepo)
+	routes.RegisterInvitationRoutes(r, invitationHandler, jwtAuth, UserRepo)
+	routes.RegisterGroupRoutes(r, groupHandler, jwtAuth, UserRepo)
+	routes.RegisterRelationRoutes(r, relationHandler, jwtAuth, UserRepo)
 
-	log.Println("Server running on :8080")
+	if scimToken := os.Getenv("SCIM_TOKEN"); scimToken != "" {
+		routes.RegisterSCIMRoutes(r, scimHandler, scimToken)
+	} else {
+		slog.Warn("SCIM_TOKEN not set, SCIM provisioning is disabled")
+	}
+
+	slog.Info("Server running on :8080")
 	http.ListenAndServe(":8080", r)
 }
+
+// fatal logs err and exits.
+func fatal(msg string, err any) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// envList splits a comma-separated environment variable.
+func envList(name string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(name), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
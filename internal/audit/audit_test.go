@@ -0,0 +1,70 @@
+package audit_test
+
+import (
+	"encoding/json"
+	"go-user-api/internal/audit"
+	"go-user-api/internal/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	before := model.User{ID: 7, Name: "Jane", Email: "jane@example.com", Password: "old-hash"}
+	after := model.User{ID: 7, Name: "Jane Doe", Email: "jane@example.com", Password: "new-hash"}
+
+	b, a, err := audit.Diff(before, after)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name": "Jane", "password": "[REDACTED]"}`, string(b))
+	assert.JSONEq(t, `{"name": "Jane Doe", "password": "[REDACTED]"}`, string(a))
+
+	// creations record the whole new state
+	b, a, err = audit.Diff(nil, &after)
+	assert.NoError(t, err)
+	assert.Nil(t, b)
+	assert.Contains(t, string(a), `"email":"jane@example.com"`)
+	assert.NotContains(t, string(a), "new-hash")
+}
+
+func TestVerifierDetectsTampering(t *testing.T) {
+	var events []*model.AuditEvent
+	prev := ""
+	for i, action := range []string{audit.ActionUserCreate, audit.ActionUserUpdate, audit.ActionUserDelete} {
+		e := &model.AuditEvent{
+			ID:         int64(i + 1),
+			OccurredAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+			ActorID:    1,
+			Action:     action,
+			TargetType: audit.TargetUser,
+			TargetID:   "7",
+			After:      json.RawMessage(`{"b": 1, "a": 2}`),
+			PrevHash:   prev,
+		}
+		e.Hash = audit.Hash(prev, e)
+		prev = e.Hash
+		events = append(events, e)
+	}
+
+	check := func(events []*model.AuditEvent) error {
+		var v audit.Verifier
+		for _, e := range events {
+			if err := v.Check(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	assert.NoError(t, check(events))
+
+	// the database may hand JSON back reformatted
+	events[0].After = json.RawMessage(`{"a":2,"b":1}`)
+	assert.NoError(t, check(events))
+
+	events[1].ActorID = 2
+	assert.Equal(t, &audit.ChainError{ID: 2}, check(events))
+
+	events[1].ActorID = 1
+	assert.Equal(t, &audit.ChainError{ID: 3}, check([]*model.AuditEvent{events[0], events[2]}))
+}
@@ -1,15 +1,74 @@
 package auth
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"go-user-api/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var tracer = otel.Tracer("go-user-api/internal/auth")
 
 func HashPassword(password string) (string, error) {
+	return HashPasswordContext(context.Background(), password)
+}
+
+// HashPasswordContext is HashPassword traced as a child of ctx.
+func HashPasswordContext(ctx context.Context, password string) (string, error) {
+	_, span := tracer.Start(ctx, "bcrypt.hash")
+	defer span.End()
+
+	timer := prometheus.NewTimer(metrics.PasswordHashDuration.WithLabelValues("hash"))
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	timer.ObserveDuration()
 
 	return string(bytes), err
 }
 
 func ComparePassword(password string, hashedPassword string) bool {
+	return ComparePasswordContext(context.Background(), password, hashedPassword)
+}
+
+// ComparePasswordContext is ComparePassword traced as a child of ctx.
+func ComparePasswordContext(ctx context.Context, password string, hashedPassword string) bool {
+	_, span := tracer.Start(ctx, "bcrypt.compare")
+	defer span.End()
+
+	timer := prometheus.NewTimer(metrics.PasswordHashDuration.WithLabelValues("compare"))
 	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	timer.ObserveDuration()
 
+	span.SetAttributes(attribute.Bool("auth.password.match", err == nil))
 	return err == nil
 }
+
+// GenerateSecret returns a random URL-safe string carrying n bytes of entropy.
+func GenerateSecret(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashSecret hashes a machine-generated secret. These are high entropy random
+// values, so unlike passwords a fast digest is enough and keeps the token
+// endpoint cheap.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func CompareSecret(secret string, hashedSecret string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashSecret(secret)), []byte(hashedSecret)) == 1
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// Cookie auth mode names. The __Host- prefix makes browsers refuse the
+// cookies unless they are Secure, host-only and scoped to /, so a sibling
+// subdomain cannot plant them.
+const (
+	SessionCookie = "__Host-session"
+	CSRFCookie    = "__Host-csrf"
+	CSRFHeader    = "X-CSRF-Token"
+)
+
+// CSRFToken derives the CSRF token that goes with a session token. It is
+// bound to the session, so a token taken from one session is useless in
+// another, and nothing needs to be stored to check it.
+func CSRFToken(sessionToken string) string {
+	mac := hmac.New(sha256.New, purposeKey("csrf"))
+	mac.Write([]byte(sessionToken))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// CheckCSRFToken reports whether token is the CSRF token of sessionToken.
+func CheckCSRFToken(sessionToken string, token string) bool {
+	return token != "" && hmac.Equal([]byte(CSRFToken(sessionToken)), []byte(token))
+}
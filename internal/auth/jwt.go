@@ -1,30 +1,200 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"go-user-api/internal/metrics"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var jwtKey = []byte("samplesecretkey") // Store securely (e.g., in env vars)
 
-func GenerateJWT(userId int) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userId,
-		"exp":     time.Now().Add(72 * time.Hour).Unix(), // 3 days expiry
+const (
+	// ClientTokenTTL is the lifetime of access tokens issued to service accounts.
+	ClientTokenTTL = time.Hour
+	// AccessTokenTTL is the lifetime of user access tokens issued to OAuth2
+	// clients. They can be renewed with a refresh token.
+	AccessTokenTTL = time.Hour
+	// SessionTTL is the lifetime of tokens issued at login, and so of the
+	// session they belong to.
+	SessionTTL = 72 * time.Hour
+	// ImpersonationTTL is the lifetime of tokens an admin gets to act as
+	// another user. They are short lived and cannot be renewed.
+	ImpersonationTTL = 30 * time.Minute
+)
+
+// Claims are the claims carried by every token this service issues. User
+// tokens set UserID, service account tokens only set ClientID. User tokens
+// issued to an OAuth2 client carry both.
+type Claims struct {
+	UserID   int    `json:"user_id,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	// SessionID is set on tokens issued at login.
+	SessionID int `json:"sid,omitempty"`
+	// TenantID is the organization of the user.
+	TenantID int `json:"tid,omitempty"`
+	// Actor is set when an admin impersonates the user.
+	Actor *Actor `json:"act,omitempty"`
+	// Groups names the user's effective groups when group claims are
+	// enabled. They are a snapshot taken when the token was issued.
+	Groups []string `json:"groups,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Actor identifies who acts on behalf of the subject (RFC 8693 section 4.1).
+type Actor struct {
+	Subject string `json:"sub"`
+}
+
+// ActorID returns the user ID of the impersonating admin, or 0 when the
+// user acts as themselves.
+func (c *Claims) ActorID() int {
+	if c.Actor == nil {
+		return 0
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	id, _ := strconv.Atoi(c.Actor.Subject)
+	return id
+}
+
+// HasScope reports whether the token was granted the given scope.
+func (c *Claims) HasScope(scope string) bool {
+	for _, s := range ParseScope(c.Scope) {
+		if s == scope {
+			return true
+		}
+	}
 
-	return token.SignedString(jwtKey)
+	return false
 }
 
-// DecodeJWT verifies the token and returns user_id if valid
-func DecodeJWT(tokenStr string) (int, error) {
-	// Parse token with claims
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+// TokenOption customizes the claims of a user token.
+type TokenOption func(*Claims)
+
+// WithClientID marks the token as issued to an OAuth2 client on behalf of the user.
+func WithClientID(clientID string) TokenOption {
+	return func(c *Claims) { c.ClientID = clientID }
+}
+
+// WithScopes limits the token to the given scopes.
+func WithScopes(scopes []string) TokenOption {
+	return func(c *Claims) { c.Scope = FormatScope(scopes) }
+}
+
+// WithSessionID ties the token to a login session, so it stops working when
+// the session is revoked.
+func WithSessionID(id int) TokenOption {
+	return func(c *Claims) { c.SessionID = id }
+}
+
+// WithTenant names the organization of the user, which the token is then
+// only accepted for.
+func WithTenant(id int) TokenOption {
+	return func(c *Claims) { c.TenantID = id }
+}
+
+// WithActor marks the token as used by actorID to impersonate the user.
+func WithActor(actorID int) TokenOption {
+	return func(c *Claims) { c.Actor = &Actor{Subject: strconv.Itoa(actorID)} }
+}
+
+// WithGroups embeds the names of the user's groups.
+func WithGroups(names []string) TokenOption {
+	return func(c *Claims) { c.Groups = names }
+}
+
+// WithTTL overrides the default token lifetime.
+func WithTTL(ttl time.Duration) TokenOption {
+	return func(c *Claims) { c.ExpiresAt = jwt.NewNumericDate(c.IssuedAt.Add(ttl)) }
+}
+
+func GenerateJWT(userId int, opts ...TokenOption) (string, error) {
+	return GenerateJWTContext(context.Background(), userId, opts...)
+}
+
+// GenerateJWTContext is GenerateJWT traced as a child of ctx.
+func GenerateJWTContext(ctx context.Context, userId int, opts ...TokenOption) (string, error) {
+	_, span := tracer.Start(ctx, "jwt.sign")
+	defer span.End()
+
+	now := time.Now()
+	claims := &Claims{
+		UserID: userId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        newTokenID(),
+			Subject:   strconv.Itoa(userId),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(SessionTTL)),
+		},
+	}
+
+	for _, opt := range opts {
+		opt(claims)
+	}
+
+	return signToken(claims)
+}
+
+// GenerateClientToken issues a scoped access token for a service account.
+func GenerateClientToken(clientID string, scopes []string) (string, error) {
+	now := time.Now()
+	claims := &Claims{
+		ClientID: clientID,
+		Scope:    FormatScope(scopes),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        newTokenID(),
+			Subject:   clientID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ClientTokenTTL)),
+		},
+	}
+
+	return signToken(claims)
+}
+
+// newTokenID returns a unique jti, which lets a token be revoked before it expires.
+func newTokenID() string {
+	id, _ := GenerateSecret(16)
+	return id
+}
+
+func signToken(claims *Claims) (string, error) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtKey)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case claims.Actor != nil:
+		metrics.TokensIssued.WithLabelValues("impersonation").Inc()
+	case claims.UserID == 0:
+		metrics.TokensIssued.WithLabelValues("client").Inc()
+	default:
+		metrics.TokensIssued.WithLabelValues("access").Inc()
+	}
+
+	return token, nil
+}
+
+// ParseToken verifies the token signature and expiry and returns its claims.
+func ParseToken(tokenStr string) (*Claims, error) {
+	return ParseTokenContext(context.Background(), tokenStr)
+}
+
+// ParseTokenContext is ParseToken traced as a child of ctx.
+func ParseTokenContext(ctx context.Context, tokenStr string) (*Claims, error) {
+	_, span := tracer.Start(ctx, "jwt.parse")
+	defer span.End()
+
+	claims := &Claims{}
+
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
 		// Ensure the signing method is HMAC
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -32,21 +202,25 @@ func DecodeJWT(tokenStr string) (int, error) {
 		return jwtKey, nil
 	})
 
-	if err != nil || !token.Valid {
-		return 0, errors.New("invalid token")
+	valid := err == nil && token.Valid
+	span.SetAttributes(attribute.Bool("auth.token.valid", valid))
+	if !valid {
+		return nil, errors.New("invalid token")
 	}
 
-	// Extract claims
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return 0, errors.New("could not parse claims")
+	return claims, nil
+}
+
+// DecodeJWT verifies the token and returns user_id if valid
+func DecodeJWT(tokenStr string) (int, error) {
+	claims, err := ParseToken(tokenStr)
+	if err != nil {
+		return 0, err
 	}
 
-	// Extract user_id from claims
-	userIDFloat, ok := claims["user_id"].(float64)
-	if !ok {
+	if claims.UserID == 0 {
 		return 0, errors.New("user_id not found in token")
 	}
 
-	return int(userIDFloat), nil
+	return claims.UserID, nil
 }
@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"go-user-api/internal/metrics"
+	"go-user-api/internal/model"
+	"log/slog"
+	"math/big"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// IDTokenTTL is the lifetime of OpenID Connect ID tokens.
+const IDTokenTTL = time.Hour
+
+// Scopes with a meaning defined by OpenID Connect Core section 5.4.
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// Issuer is the OpenID Connect issuer identifier, taken from OIDC_ISSUER.
+func Issuer() string {
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		return issuer
+	}
+
+	return "http://localhost:8080"
+}
+
+var (
+	signingKeyOnce sync.Once
+	signingKey     *rsa.PrivateKey
+	signingKeyID   string
+	signingKeyErr  error
+)
+
+// loadSigningKey returns the RSA key used to sign ID tokens. It is read from
+// the PEM file named by OIDC_SIGNING_KEY_FILE. Without one an ephemeral key is
+// generated, which invalidates every ID token on restart.
+func loadSigningKey() (*rsa.PrivateKey, string, error) {
+	signingKeyOnce.Do(func() {
+		path := os.Getenv("OIDC_SIGNING_KEY_FILE")
+		if path == "" {
+			slog.Warn("OIDC_SIGNING_KEY_FILE not set, generating an ephemeral ID token signing key")
+			signingKey, signingKeyErr = rsa.GenerateKey(rand.Reader, 2048)
+		} else {
+			signingKey, signingKeyErr = readRSAKey(path)
+		}
+
+		if signingKeyErr == nil {
+			sum := sha256.Sum256(signingKey.N.Bytes())
+			signingKeyID = base64.RawURLEncoding.EncodeToString(sum[:8])
+		}
+	})
+
+	return signingKey, signingKeyID, signingKeyErr
+}
+
+func readRSAKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found in signing key file")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("signing key must be RSA, got %T", key)
+	}
+
+	return rsaKey, nil
+}
+
+// IDTokenClaims are the claims of an OpenID Connect ID token.
+type IDTokenClaims struct {
+	Nonce         string `json:"nonce,omitempty"`
+	AuthTime      int64  `json:"auth_time,omitempty"`
+	Name          string `json:"name,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// GenerateIDToken issues an RS256 signed ID token for user to the client.
+// Profile and email claims are only included when their scope was granted.
+func GenerateIDToken(user *model.User, clientID string, scopes []string, nonce string, authTime time.Time) (string, error) {
+	key, kid, err := loadSigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := &IDTokenClaims{
+		Nonce: nonce,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    Issuer(),
+			Subject:   strconv.Itoa(user.ID),
+			Audience:  jwt.ClaimStrings{clientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(IDTokenTTL)),
+		},
+	}
+
+	if !authTime.IsZero() {
+		claims.AuthTime = authTime.Unix()
+	}
+
+	userClaims := UserInfoClaims(user, scopes)
+	claims.Name, _ = userClaims["name"].(string)
+	claims.Email, _ = userClaims["email"].(string)
+	if verified, ok := userClaims["email_verified"].(bool); ok {
+		claims.EmailVerified = &verified
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		return "", err
+	}
+
+	metrics.TokensIssued.WithLabelValues("id").Inc()
+	return signed, nil
+}
+
+// UserInfoClaims maps the granted scopes to the standard claims about user
+// (OpenID Connect Core section 5.4).
+func UserInfoClaims(user *model.User, scopes []string) map[string]any {
+	claims := map[string]any{"sub": strconv.Itoa(user.ID)}
+
+	for _, scope := range scopes {
+		switch scope {
+		case ScopeProfile:
+			claims["name"] = user.Name
+		case ScopeEmail:
+			claims["email"] = user.Email
+			claims["email_verified"] = user.EmailVerified
+		}
+	}
+
+	return claims
+}
+
+// JSONWebKey is the public part of an RSA signing key (RFC 7517).
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS returns the key set clients use to verify ID tokens.
+func JWKS() (*JSONWebKeySet, error) {
+	key, kid, err := loadSigningKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return &JSONWebKeySet{Keys: []JSONWebKey{{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: jwt.SigningMethodRS256.Alg(),
+		Kid: kid,
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}, nil
+}
+
+// ParseIDToken verifies an ID token issued by this server.
+func ParseIDToken(tokenStr string) (*IDTokenClaims, error) {
+	key, _, err := loadSigningKey()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &IDTokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}), jwt.WithIssuer(Issuer()))
+
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,24 @@
+package auth_test
+
+import (
+	"go-user-api/internal/auth"
+	"testing"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	// example from RFC 7636 appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	if !auth.VerifyPKCE(verifier, challenge) {
+		t.Fatal("expected RFC 7636 example verifier to match")
+	}
+
+	if auth.VerifyPKCE(verifier[:42], challenge) {
+		t.Fatal("expected verifier shorter than 43 characters to be rejected")
+	}
+
+	if auth.VerifyPKCE("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXj", challenge) {
+		t.Fatal("expected wrong verifier to be rejected")
+	}
+}
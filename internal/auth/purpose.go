@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"go-user-api/internal/metrics"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// PurposeClaims are the claims of a short-lived signed token used inside a
+// single flow, such as the state of an OIDC login.
+type PurposeClaims struct {
+	Data map[string]string `json:"data,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// purposeKey derives a signing key per purpose, so a token minted for one
+// flow is never accepted by another one or as an access token.
+func purposeKey(purpose string) []byte {
+	mac := hmac.New(sha256.New, jwtKey)
+	mac.Write([]byte(purpose))
+
+	return mac.Sum(nil)
+}
+
+// GeneratePurposeToken signs data for the given purpose. It returns the token
+// and its jti, which callers can record to make the token single use.
+func GeneratePurposeToken(purpose string, subject string, data map[string]string, ttl time.Duration) (string, string, error) {
+	now := time.Now()
+	claims := &PurposeClaims{
+		Data: data,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        newTokenID(),
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(purposeKey(purpose))
+	if err != nil {
+		return "", "", err
+	}
+
+	metrics.TokensIssued.WithLabelValues(purpose).Inc()
+	return token, claims.ID, nil
+}
+
+// ParsePurposeToken verifies a token created by GeneratePurposeToken for the
+// same purpose.
+func ParsePurposeToken(purpose string, tokenStr string) (*PurposeClaims, error) {
+	claims := &PurposeClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return purposeKey(purpose), nil
+	})
+
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,29 @@
+package auth
+
+import "strings"
+
+// ParseScope splits a space-delimited OAuth2 scope string.
+func ParseScope(scope string) []string {
+	return strings.Fields(scope)
+}
+
+// FormatScope joins scopes into a space-delimited OAuth2 scope string.
+func FormatScope(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// ScopesAllowed reports whether every requested scope is in allowed.
+func ScopesAllowed(requested, allowed []string) bool {
+	set := make(map[string]bool, len(allowed))
+	for _, s := range allowed {
+		set[s] = true
+	}
+
+	for _, s := range requested {
+		if !set[s] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,50 @@
+// Package authn verifies the credentials users sign in with. Each backend,
+// local passwords or a directory, implements Authenticator.
+package authn
+
+import (
+	"context"
+	"errors"
+	"go-user-api/internal/model"
+)
+
+var (
+	// ErrUserNotFound means the backend doesn't know the user, so another
+	// backend may.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrInvalidPassword means the user exists but the password is wrong.
+	ErrInvalidPassword = errors.New("invalid password")
+)
+
+// Authenticator checks a login and returns the local user it belongs to.
+// Any error other than ErrUserNotFound and ErrInvalidPassword means the
+// backend could not be asked.
+type Authenticator interface {
+	Authenticate(ctx context.Context, email string, password string) (*model.User, error)
+}
+
+// Chain tries each authenticator in order and returns the first success.
+// When all fail it reports the most significant error: a backend failure,
+// then a wrong password, then an unknown user.
+type Chain []Authenticator
+
+func (c Chain) Authenticate(ctx context.Context, email string, password string) (*model.User, error) {
+	result := ErrUserNotFound
+
+	for _, a := range c {
+		user, err := a.Authenticate(ctx, email, password)
+		switch {
+		case err == nil:
+			return user, nil
+		case errors.Is(err, ErrUserNotFound):
+		case errors.Is(err, ErrInvalidPassword):
+			if errors.Is(result, ErrUserNotFound) {
+				result = err
+			}
+		default:
+			result = err
+		}
+	}
+
+	return nil, result
+}
@@ -0,0 +1,245 @@
+package authn
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/model"
+	"go-user-api/internal/repository"
+	"go-user-api/internal/tenant"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPConfig configures sign in against an LDAP or Active Directory server.
+type LDAPConfig struct {
+	// URL is ldap://host:389 or ldaps://host:636.
+	URL string `json:"url"`
+	// StartTLS upgrades an ldap:// connection before any credentials are sent.
+	StartTLS bool `json:"start_tls"`
+	// CACertFile verifies the server certificate against a private CA.
+	CACertFile         string `json:"ca_cert_file"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+	// BindDN and BindPassword are the service account that searches for
+	// users. Without them the search is anonymous.
+	BindDN       string `json:"bind_dn"`
+	BindPassword string `json:"bind_password"`
+	BaseDN       string `json:"base_dn"`
+	// UserFilter finds the entry of the user signing in. {email} is replaced
+	// with the escaped login, e.g. for Active Directory
+	// (&(objectClass=user)(|(mail={email})(userPrincipalName={email}))).
+	UserFilter string         `json:"user_filter"`
+	Attributes LDAPAttributes `json:"attributes"`
+	// CreateUsers creates the local user on the first directory sign in to
+	// the default organization. Otherwise, and in other organizations, only
+	// users that already exist locally can sign in.
+	CreateUsers    bool `json:"create_users"`
+	TimeoutSeconds int  `json:"timeout_seconds"`
+}
+
+// LDAPAttributes names the directory attributes mapped onto model.User.
+type LDAPAttributes struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+// LoadLDAPConfig reads the directory configuration from the JSON file named
+// by LDAP_CONFIG_FILE. It returns nil when directory sign in is disabled.
+func LoadLDAPConfig() (*LDAPConfig, error) {
+	path := os.Getenv("LDAP_CONFIG_FILE")
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg LDAPConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+// LDAP checks passwords by binding to the directory as the user. The entry is
+// looked up first, so users sign in with their email address rather than
+// their DN.
+type LDAP struct {
+	cfg       LDAPConfig
+	tlsConfig *tls.Config
+	users     repository.UserRepository
+}
+
+func NewLDAP(cfg LDAPConfig, users repository.UserRepository) (*LDAP, error) {
+	if cfg.URL == "" || cfg.BaseDN == "" {
+		return nil, errors.New("ldap: url and base_dn are required")
+	}
+
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(&(objectClass=person)(mail={email}))"
+	}
+
+	if !strings.Contains(cfg.UserFilter, "{email}") {
+		return nil, errors.New("ldap: user_filter must contain {email}")
+	}
+
+	if cfg.Attributes.Email == "" {
+		cfg.Attributes.Email = "mail"
+	}
+
+	if cfg.Attributes.Name == "" {
+		cfg.Attributes.Name = "cn"
+	}
+
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 10
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+	if cfg.CACertFile != "" {
+		pem, err := os.ReadFile(cfg.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ldap: no certificates found in %s", cfg.CACertFile)
+		}
+	}
+
+	return &LDAP{cfg: cfg, tlsConfig: tlsConfig, users: users}, nil
+}
+
+func (l *LDAP) Authenticate(ctx context.Context, email string, password string) (*model.User, error) {
+	// servers treat a bind with an empty password as anonymous and let it
+	// succeed, so it must never count as a login
+	if password == "" {
+		return nil, ErrInvalidPassword
+	}
+
+	conn, err := l.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := l.findEntry(conn, email)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidPassword
+		}
+		return nil, fmt.Errorf("ldap: bind: %w", err)
+	}
+
+	return l.localUser(ctx, entry, email)
+}
+
+func (l *LDAP) dial() (*ldap.Conn, error) {
+	timeout := time.Duration(l.cfg.TimeoutSeconds) * time.Second
+
+	conn, err := ldap.DialURL(l.cfg.URL,
+		ldap.DialWithTLSConfig(l.tlsConfig), ldap.DialWithDialer(&net.Dialer{Timeout: timeout}))
+	if err != nil {
+		return nil, fmt.Errorf("ldap: dial: %w", err)
+	}
+
+	conn.SetTimeout(timeout)
+
+	if l.cfg.StartTLS {
+		if err := conn.StartTLS(l.tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap: start tls: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+func (l *LDAP) findEntry(conn *ldap.Conn, email string) (*ldap.Entry, error) {
+	if l.cfg.BindDN != "" {
+		if err := conn.Bind(l.cfg.BindDN, l.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("ldap: service bind: %w", err)
+		}
+	}
+
+	filter := strings.ReplaceAll(l.cfg.UserFilter, "{email}", ldap.EscapeFilter(email))
+	req := ldap.NewSearchRequest(l.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, l.cfg.TimeoutSeconds, false, filter, []string{l.cfg.Attributes.Email, l.cfg.Attributes.Name}, nil)
+
+	res, err := conn.Search(req)
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("ldap: search: %w", err)
+	}
+
+	switch {
+	case res == nil || len(res.Entries) == 0:
+		return nil, ErrUserNotFound
+	case len(res.Entries) > 1:
+		// never guess which entry a login belongs to
+		return nil, fmt.Errorf("ldap: user filter matches more than one entry for %s", email)
+	}
+
+	return res.Entries[0], nil
+}
+
+// localUser returns the user the directory entry maps to, creating it when
+// just-in-time provisioning is on.
+func (l *LDAP) localUser(ctx context.Context, entry *ldap.Entry, login string) (*model.User, error) {
+	email := entry.GetAttributeValue(l.cfg.Attributes.Email)
+	if email == "" {
+		email = login
+	}
+
+	if user, err := l.users.GetByEmail(ctx, email); err == nil && user != nil {
+		return user, nil
+	}
+
+	// like sign-up, only the default organization takes new users, or
+	// everyone in the directory could join any organization
+	if !l.cfg.CreateUsers || tenant.ID(ctx) != tenant.DefaultID {
+		return nil, ErrUserNotFound
+	}
+
+	// the directory owns the password; the local one is random and unusable
+	password, err := auth.GenerateSecret(32)
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := auth.HashPasswordContext(ctx, password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &model.User{
+		Name:          entry.GetAttributeValue(l.cfg.Attributes.Name),
+		Email:         email,
+		Password:      hashedPassword,
+		EmailVerified: true,
+	}
+	if user.Name == "" {
+		user.Name = email
+	}
+
+	if err := l.users.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
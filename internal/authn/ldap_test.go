@@ -0,0 +1,123 @@
+package authn_test
+
+import (
+	"context"
+	"errors"
+	"go-user-api/internal/authn"
+	"go-user-api/internal/model"
+	"go-user-api/internal/tenant"
+	"go-user-api/internal/testutils"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var directoryEntries = []testutils.StubLDAPEntry{
+	{
+		DN:         "uid=jane,ou=people,dc=example,dc=com",
+		Password:   "directory-pw",
+		Attributes: map[string][]string{"objectClass": {"person"}, "mail": {"jane@example.com"}, "cn": {"Jane Directory"}},
+	},
+	{
+		DN:         "uid=bob,ou=people,dc=example,dc=com",
+		Password:   "bob-pw",
+		Attributes: map[string][]string{"objectClass": {"person"}, "mail": {"bob@example.com"}, "cn": {"Bob"}},
+	},
+}
+
+func newDirectory(t *testing.T, cfg authn.LDAPConfig, users *testutils.MockUserRepo) *authn.LDAP {
+	stub := testutils.NewStubLDAPServer(true, directoryEntries...)
+	stub.BindDN, stub.BindPassword = "cn=svc,dc=example,dc=com", "svc-pw"
+	t.Cleanup(stub.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	assert.NoError(t, os.WriteFile(caFile, stub.CACert, 0o600))
+
+	cfg.URL = stub.URL
+	cfg.CACertFile = caFile
+	cfg.BindDN, cfg.BindPassword = stub.BindDN, stub.BindPassword
+	cfg.BaseDN = "ou=people,dc=example,dc=com"
+
+	directory, err := authn.NewLDAP(cfg, users)
+	assert.NoError(t, err)
+	return directory
+}
+
+func TestLDAPCreatesUserOnFirstLogin(t *testing.T) {
+	users := &testutils.MockUserRepo{}
+	directory := newDirectory(t, authn.LDAPConfig{CreateUsers: true}, users)
+
+	user, err := directory.Authenticate(context.Background(), "jane@example.com", "directory-pw")
+	assert.NoError(t, err)
+	assert.Equal(t, "Jane Directory", user.Name)
+	assert.True(t, user.EmailVerified)
+
+	again, err := directory.Authenticate(context.Background(), "jane@example.com", "directory-pw")
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, again.ID)
+	assert.Len(t, users.Users, 1)
+}
+
+func TestLDAPOnlyCreatesUsersInDefaultOrganization(t *testing.T) {
+	member := testutils.NewMockUser(5, "bob@example.com", "local-pw")
+	member.OrganizationID = 2
+	users := &testutils.MockUserRepo{Users: []*model.User{member}}
+	directory := newDirectory(t, authn.LDAPConfig{CreateUsers: true}, users)
+	acme := tenant.WithID(context.Background(), 2)
+
+	_, err := directory.Authenticate(acme, "jane@example.com", "directory-pw")
+	assert.ErrorIs(t, err, authn.ErrUserNotFound)
+	assert.Len(t, users.Users, 1)
+
+	user, err := directory.Authenticate(acme, "bob@example.com", "bob-pw")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, user.ID)
+}
+
+func TestLDAPRejectsBadCredentials(t *testing.T) {
+	users := &testutils.MockUserRepo{Users: []*model.User{testutils.NewMockUser(1, "bob@example.com", "local-pw")}}
+	directory := newDirectory(t, authn.LDAPConfig{}, users)
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		err      error
+	}{
+		{"wrong password", "bob@example.com", "nope", authn.ErrInvalidPassword},
+		{"empty password is not an anonymous bind", "bob@example.com", "", authn.ErrInvalidPassword},
+		{"unknown user", "eve@example.com", "x", authn.ErrUserNotFound},
+		{"filter injection", "*", "bob-pw", authn.ErrUserNotFound},
+		{"not provisioned locally", "jane@example.com", "directory-pw", authn.ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := directory.Authenticate(context.Background(), tt.email, tt.password)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	user, err := directory.Authenticate(context.Background(), "bob@example.com", "bob-pw")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, user.ID)
+}
+
+func TestChainFallsBackToLocalPasswords(t *testing.T) {
+	users := &testutils.MockUserRepo{Users: []*model.User{testutils.NewMockUser(1, "local@example.com", "local-pw")}}
+
+	unreachable, err := authn.NewLDAP(authn.LDAPConfig{URL: "ldap://127.0.0.1:1", BaseDN: "dc=example,dc=com"}, users)
+	assert.NoError(t, err)
+	chain := authn.Chain{unreachable, authn.NewLocal(users)}
+
+	user, err := chain.Authenticate(context.Background(), "local@example.com", "local-pw")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, user.ID)
+
+	// with the directory down, its failure is reported rather than a wrong password
+	_, err = chain.Authenticate(context.Background(), "local@example.com", "wrong")
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, authn.ErrInvalidPassword))
+}
@@ -0,0 +1,30 @@
+package authn
+
+import (
+	"context"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/model"
+	"go-user-api/internal/repository"
+)
+
+// Local checks passwords stored in the users table.
+type Local struct {
+	users repository.UserRepository
+}
+
+func NewLocal(users repository.UserRepository) *Local {
+	return &Local{users: users}
+}
+
+func (l *Local) Authenticate(ctx context.Context, email string, password string) (*model.User, error) {
+	user, err := l.users.GetByEmail(ctx, email)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	if !auth.ComparePasswordContext(ctx, password, user.Password) {
+		return nil, ErrInvalidPassword
+	}
+
+	return user, nil
+}
@@ -0,0 +1,195 @@
+// Package federation signs users in through upstream OpenID Connect
+// identity providers.
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-user-api/internal/auth"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// ProviderConfig configures one upstream OpenID Connect provider.
+type ProviderConfig struct {
+	// Name identifies the provider in URLs, e.g. /auth/oidc/{name}/login.
+	Name         string `json:"name"`
+	DisplayName  string `json:"display_name"`
+	IssuerURL    string `json:"issuer_url"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	// RedirectURL defaults to {OIDC_ISSUER}/auth/oidc/{name}/callback.
+	RedirectURL string   `json:"redirect_url"`
+	Scopes      []string `json:"scopes"`
+	// AutoLinkVerifiedEmail links a first-time external login to the local
+	// account with the same email, if the provider says it is verified.
+	// Only enable this for providers that are trusted to verify emails.
+	AutoLinkVerifiedEmail bool `json:"auto_link_verified_email"`
+}
+
+// Provider is a configured upstream provider. Its discovery document is
+// fetched on first use, so an unreachable provider doesn't stop the server.
+type Provider struct {
+	Config ProviderConfig
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+// Identity is what the upstream provider asserted about the user.
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+func NewProvider(cfg ProviderConfig) *Provider {
+	if cfg.RedirectURL == "" {
+		cfg.RedirectURL = auth.Issuer() + "/auth/oidc/" + cfg.Name + "/callback"
+	}
+
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+
+	if cfg.DisplayName == "" {
+		cfg.DisplayName = cfg.Name
+	}
+
+	return &Provider{Config: cfg}
+}
+
+// LoadProviders reads the provider list from the JSON file named by
+// OIDC_PROVIDERS_FILE. Without that variable federated login is disabled.
+func LoadProviders() (map[string]*Provider, error) {
+	providers := map[string]*Provider{}
+
+	path := os.Getenv("OIDC_PROVIDERS_FILE")
+	if path == "" {
+		return providers, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []ProviderConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	for _, cfg := range configs {
+		if cfg.Name == "" || cfg.IssuerURL == "" || cfg.ClientID == "" {
+			return nil, errors.New("every OIDC provider needs a name, issuer_url and client_id")
+		}
+
+		if _, dup := providers[cfg.Name]; dup {
+			return nil, fmt.Errorf("duplicate OIDC provider %q", cfg.Name)
+		}
+
+		providers[cfg.Name] = NewProvider(cfg)
+	}
+
+	return providers, nil
+}
+
+func (p *Provider) discover(ctx context.Context) (*oidc.Provider, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.provider == nil {
+		provider, err := oidc.NewProvider(ctx, p.Config.IssuerURL)
+		if err != nil {
+			return nil, err
+		}
+		p.provider = provider
+	}
+
+	return p.provider, nil
+}
+
+func (p *Provider) oauth2Config(provider *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.Config.ClientID,
+		ClientSecret: p.Config.ClientSecret,
+		RedirectURL:  p.Config.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       p.Config.Scopes,
+	}
+}
+
+// AuthCodeURL returns the upstream authorization URL to send the user to.
+func (p *Provider) AuthCodeURL(ctx context.Context, state string, nonce string, verifier string) (string, error) {
+	provider, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return p.oauth2Config(provider).AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange trades the authorization code for tokens, verifies the ID token
+// and its nonce, and returns the identity it asserts.
+func (p *Provider) Exchange(ctx context.Context, code string, verifier string, nonce string) (*Identity, error) {
+	provider, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := p.oauth2Config(provider).Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("exchanging code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: p.Config.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("verifying id_token: %w", err)
+	}
+
+	if idToken.Nonce != nonce {
+		return nil, errors.New("id_token nonce does not match")
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified any    `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	return &Identity{
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: isTrue(claims.EmailVerified),
+		Name:          claims.Name,
+	}, nil
+}
+
+// isTrue accepts email_verified as a boolean or, as some providers send it, a string.
+func isTrue(v any) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case string:
+		b, _ := strconv.ParseBool(v)
+		return b
+	}
+
+	return false
+}
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"go-user-api/internal/audit"
+	"go-user-api/internal/logging"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/repository"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 500
+)
+
+// newAuditEvent describes an action taken by the caller of r.
+func newAuditEvent(r *http.Request, action string, targetType string, targetID string) *model.AuditEvent {
+	e := &model.AuditEvent{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		IP:         clientIP(r),
+		UserAgent:  truncate(r.UserAgent(), 512),
+		RequestID:  middleware.GetRequestID(r.Context()),
+	}
+
+	e.ActorID, _ = r.Context().Value(middleware.UserIDKey).(int)
+	e.ImpersonatorID, _ = r.Context().Value(middleware.ActorIDKey).(int)
+
+	return e
+}
+
+// recordAudit appends e to the audit log. By the time an event is recorded
+// the action has happened, so a failure is logged rather than reported to
+// the caller.
+func recordAudit(r *http.Request, recorder audit.Recorder, e *model.AuditEvent) {
+	if recorder == nil {
+		return
+	}
+
+	if err := recorder.Record(r.Context(), e); err != nil {
+		logging.FromContext(r.Context()).Error("could not record audit event",
+			"action", e.Action, "target_type", e.TargetType, "target_id", e.TargetID, "error", err)
+	}
+}
+
+// recordChange records a change to a resource with the fields that changed.
+func recordChange(r *http.Request, recorder audit.Recorder, e *model.AuditEvent, before any, after any) {
+	var err error
+	if e.Before, e.After, err = audit.Diff(before, after); err != nil {
+		logging.FromContext(r.Context()).Error("could not diff audit event",
+			"action", e.Action, "target_type", e.TargetType, "target_id", e.TargetID, "error", err)
+	}
+
+	recordAudit(r, recorder, e)
+}
+
+type AuditHandler struct {
+	events repository.AuditRepository
+}
+
+func NewAuditHandler(events repository.AuditRepository) *AuditHandler {
+	return &AuditHandler{events: events}
+}
+
+// GetAuditLog godoc
+// @Summary Search the audit log
+// @Description Lists audit events, newest first. Page backwards by passing the smallest ID seen as before_id.
+// @Tags admin
+// @Produce  json
+// @Security BearerAuth
+// @Param   actor_id     query  int     false  "Acting user, or impersonating admin"
+// @Param   action       query  string  false  "Action, e.g. user.update"
+// @Param   target_type  query  string  false  "Target type, e.g. user"
+// @Param   target_id    query  string  false  "Target ID"
+// @Param   since        query  string  false  "RFC 3339 time, inclusive"
+// @Param   until        query  string  false  "RFC 3339 time, exclusive"
+// @Param   before_id    query  int     false  "Only events older than this ID"
+// @Param   limit        query  int     false  "Page size, at most 500"
+// @Success 200 {array} model.AuditEvent
+// @Failure 400 {string} string "Invalid filter"
+// @Failure 403 {string} string "Forbidden"
+// @Router /admin/audit [get]
+func (h *AuditHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := model.AuditFilter{
+		Action:     q.Get("action"),
+		TargetType: q.Get("target_type"),
+		TargetID:   q.Get("target_id"),
+		Limit:      defaultAuditPageSize,
+	}
+
+	var err error
+	parseInt := func(name string) int64 {
+		v := q.Get(name)
+		if v == "" || err != nil {
+			return 0
+		}
+		n, parseErr := strconv.ParseInt(v, 10, 64)
+		if parseErr != nil || n < 0 {
+			err = errors.New(name + " must be a positive number")
+		}
+		return n
+	}
+	parseTime := func(name string) time.Time {
+		v := q.Get(name)
+		if v == "" || err != nil {
+			return time.Time{}
+		}
+		t, parseErr := time.Parse(time.RFC3339, v)
+		if parseErr != nil {
+			err = errors.New(name + " must be an RFC 3339 time")
+		}
+		return t
+	}
+
+	filter.ActorID = int(parseInt("actor_id"))
+	filter.BeforeID = parseInt("before_id")
+	filter.Since = parseTime("since")
+	filter.Until = parseTime("until")
+	if limit := parseInt("limit"); limit > 0 {
+		filter.Limit = int(min(limit, maxAuditPageSize))
+	}
+
+	if err != nil {
+		http.Error(w, "Invalid filter: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	events, err := h.events.List(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+type AuditVerification struct {
+	Valid   bool `json:"valid"`
+	Checked int  `json:"checked"`
+	// BrokenAt is the first event that does not follow the chain.
+	BrokenAt int64 `json:"broken_at,omitempty"`
+}
+
+// VerifyAuditLog godoc
+// @Summary Verify the audit log hash chain
+// @Description Recomputes the hash chain over the whole log and reports the first event that was altered or follows a removed one.
+// @Tags admin
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} handler.AuditVerification
+// @Failure 403 {string} string "Forbidden"
+// @Router /admin/audit/verify [get]
+func (h *AuditHandler) VerifyAuditLog(w http.ResponseWriter, r *http.Request) {
+	var verifier audit.Verifier
+	result := AuditVerification{Valid: true}
+
+	err := h.events.Walk(r.Context(), verifier.Check)
+
+	var chainErr *audit.ChainError
+	switch {
+	case errors.As(err, &chainErr):
+		result.Valid = false
+		result.BrokenAt = chainErr.ID
+	case err != nil:
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	result.Checked = verifier.Checked
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
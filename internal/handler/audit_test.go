@@ -0,0 +1,103 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/testutils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserChangesAreAudited(t *testing.T) {
+	admin := testutils.NewMockUser(1, "admin@example.com", "password1")
+	admin.Role = model.RoleAdmin
+	users := &testutils.MockUserRepo{Users: []*model.User{admin, testutils.NewMockUser(2, "jane@example.com", "password1")}}
+	auditLog := &testutils.MockAuditRepo{}
+
+	userHandler := handler.NewUserHandler(users, auditLog)
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.With(middleware.NewJWTAuth().Middleware).Put("/users/{id}", userHandler.UpdateUser)
+	r.With(middleware.NewJWTAuth().Middleware).Delete("/users/{id}", userHandler.DeleteUser)
+	r.Route("/admin/audit", func(r chi.Router) {
+		r.Use(middleware.NewJWTAuth().Middleware, middleware.RequireAdmin(users))
+		r.Get("/", handler.NewAuditHandler(auditLog).GetAuditLog)
+		r.Get("/verify", handler.NewAuditHandler(auditLog).VerifyAuditLog)
+	})
+
+	token, _ := auth.GenerateJWT(admin.ID)
+	send := func(method string, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("User-Agent", "support-console")
+		req.Header.Set("X-Request-ID", "req-42")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusOK, send(http.MethodPut, "/users/2", `{"name": "Jane Doe", "email": "jane@example.com"}`).Code)
+	assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/users/2", "").Code)
+
+	update := auditLog.Events[0]
+	assert.Equal(t, "user.update", update.Action)
+	assert.Equal(t, 1, update.ActorID)
+	assert.Equal(t, "2", update.TargetID)
+	assert.Equal(t, "support-console", update.UserAgent)
+	assert.Equal(t, "req-42", update.RequestID)
+	assert.JSONEq(t, `{"name": "User jane@example.com"}`, string(update.Before))
+	assert.JSONEq(t, `{"name": "Jane Doe"}`, string(update.After))
+
+	deletion := auditLog.Events[1]
+	assert.Equal(t, "user.delete", deletion.Action)
+	assert.Nil(t, deletion.After)
+	assert.Contains(t, string(deletion.Before), `"email":"jane@example.com"`)
+	assert.Equal(t, update.Hash, deletion.PrevHash)
+
+	var events []model.AuditEvent
+	assert.NoError(t, json.NewDecoder(send(http.MethodGet, "/admin/audit?action=user.delete", "").Body).Decode(&events))
+	assert.Len(t, events, 1)
+	assert.Equal(t, int64(2), events[0].ID)
+
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/admin/audit?since=yesterday", "").Code)
+
+	var verification handler.AuditVerification
+	assert.NoError(t, json.NewDecoder(send(http.MethodGet, "/admin/audit/verify", "").Body).Decode(&verification))
+	assert.Equal(t, handler.AuditVerification{Valid: true, Checked: 2}, verification)
+
+	update.TargetID = "3"
+	verification = handler.AuditVerification{}
+	assert.NoError(t, json.NewDecoder(send(http.MethodGet, "/admin/audit/verify", "").Body).Decode(&verification))
+	assert.Equal(t, handler.AuditVerification{Valid: false, Checked: 0, BrokenAt: 1}, verification)
+}
+
+func TestLoginEventsAreAudited(t *testing.T) {
+	users := &testutils.MockUserRepo{Users: []*model.User{testutils.NewMockUser(7, "jane@example.com", "password1")}}
+	auditLog := &testutils.MockAuditRepo{}
+	h := handler.NewAuthRouteHandler(users, handler.WithAudit(auditLog))
+
+	login := func(password string) int {
+		body, _ := json.Marshal(model.LoginInput{Email: "jane@example.com", Password: password})
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(body))
+		rr := httptest.NewRecorder()
+		h.Login(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, login("wrong-password"))
+	assert.Equal(t, http.StatusOK, login("password1"))
+
+	assert.Equal(t, "auth.login_failed", auditLog.Events[0].Action)
+	assert.JSONEq(t, `{"email": "jane@example.com", "reason": "invalid password"}`, string(auditLog.Events[0].After))
+	assert.Equal(t, "auth.login", auditLog.Events[1].Action)
+	assert.Equal(t, 7, auditLog.Events[1].ActorID)
+}
@@ -2,12 +2,19 @@ package handler
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
+	"go-user-api/internal/audit"
 	"go-user-api/internal/auth"
+	"go-user-api/internal/authn"
+	"go-user-api/internal/logging"
+	"go-user-api/internal/mail"
 	"go-user-api/internal/middleware"
 	"go-user-api/internal/model"
 	"go-user-api/internal/repository"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-playground/validator"
 )
@@ -15,11 +22,74 @@ import (
 var validate = validator.New()
 
 type AuthRouteHandler struct {
-	repo repository.UserRepository
+	repo          repository.UserRepository
+	authenticator authn.Authenticator
+	sessions      repository.SessionRepository
+	cookieAuth    bool
+	auditLog      audit.Recorder
+	loginHistory  repository.LoginAttemptRepository
+	mailer        mail.Mailer
+	groupClaims   repository.GroupRepository
 }
 
-func NewAuthRouteHandler(repo repository.UserRepository) *AuthRouteHandler {
-	return &AuthRouteHandler{repo: repo}
+type AuthOption func(*AuthRouteHandler)
+
+// WithAuthenticator replaces the local password check used by Login, e.g.
+// with an authn.Chain that also asks a directory.
+func WithAuthenticator(a authn.Authenticator) AuthOption {
+	return func(h *AuthRouteHandler) {
+		h.authenticator = a
+	}
+}
+
+// WithSessions records a session for every login and ties the issued token
+// to it, so users can see and revoke where they are signed in.
+func WithSessions(sessions repository.SessionRepository) AuthOption {
+	return func(h *AuthRouteHandler) {
+		h.sessions = sessions
+	}
+}
+
+// WithCookieAuth makes interactive logins set an HttpOnly session cookie
+// instead of returning the token, so browser clients never handle it. The
+// response carries the CSRF token to send back in the X-CSRF-Token header.
+func WithCookieAuth() AuthOption {
+	return func(h *AuthRouteHandler) {
+		h.cookieAuth = true
+	}
+}
+
+// WithAudit records signups, logins, failed logins and logouts.
+func WithAudit(auditLog audit.Recorder) AuthOption {
+	return func(h *AuthRouteHandler) {
+		h.auditLog = auditLog
+	}
+}
+
+// WithLoginHistory records every login attempt and emails users when they
+// sign in from a new device. mailer may be nil to only keep the history.
+func WithLoginHistory(history repository.LoginAttemptRepository, mailer mail.Mailer) AuthOption {
+	return func(h *AuthRouteHandler) {
+		h.loginHistory = history
+		h.mailer = mailer
+	}
+}
+
+// WithGroupClaims embeds the names of the user's effective groups in the
+// tokens issued at login.
+func WithGroupClaims(groups repository.GroupRepository) AuthOption {
+	return func(h *AuthRouteHandler) {
+		h.groupClaims = groups
+	}
+}
+
+func NewAuthRouteHandler(repo repository.UserRepository, opts ...AuthOption) *AuthRouteHandler {
+	h := &AuthRouteHandler{repo: repo, authenticator: authn.NewLocal(repo)}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
 }
 
 // Signup godoc
@@ -31,6 +101,8 @@ func NewAuthRouteHandler(repo repository.UserRepository) *AuthRouteHandler {
 // @Param   user  body  model.User  true  "User Data"
 // @Success 200 {object} map[string]string
 // @Failure 400 {string} string "Invalid input"
+// @Failure 403 {string} string "Only the default organization takes sign-ups"
+// @Failure 429 {object} model.Problem "Too many requests"
 // @Router /auth/signup [post]
 func (h *AuthRouteHandler) Signup(w http.ResponseWriter, r *http.Request) {
 	var u model.User
@@ -45,7 +117,7 @@ func (h *AuthRouteHandler) Signup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hashedPassword, err := auth.HashPassword(u.Password)
+	hashedPassword, err := auth.HashPasswordContext(r.Context(), u.Password)
 
 	if err != nil {
 		http.Error(w, "Could not hash password", http.StatusInternalServerError)
@@ -53,12 +125,17 @@ func (h *AuthRouteHandler) Signup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	u.Password = hashedPassword
+	u.EmailVerified = false
 
 	if err := h.repo.Create(r.Context(), &u); err != nil {
 		http.Error(w, "failed to signup new user", http.StatusInternalServerError)
 		return
 	}
 
+	event := newAuditEvent(r, audit.ActionSignup, audit.TargetUser, strconv.Itoa(u.ID))
+	event.ActorID = u.ID
+	recordChange(r, h.auditLog, event, nil, u)
+
 	json.NewEncoder(w).Encode(u)
 
 }
@@ -73,6 +150,9 @@ func (h *AuthRouteHandler) Signup(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {object} map[string]string
 // @Failure 400 {string} string "Invalid input"
 // @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Account is disabled"
+// @Failure 503 {string} string "Authentication service unavailable"
+// @Failure 429 {object} model.Problem "Too many requests"
 // @Router /auth/login [post]
 func (h *AuthRouteHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var input model.LoginInput
@@ -88,24 +168,92 @@ func (h *AuthRouteHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.repo.GetByEmail(r.Context(), input.Email)
+	user, err := h.authenticator.Authenticate(r.Context(), input.Email, input.Password)
 	if err != nil {
+		h.recordLoginFailure(r, input.Email, err)
+	}
+
+	switch {
+	case errors.Is(err, authn.ErrUserNotFound):
 		http.Error(w, "User not found", http.StatusUnauthorized)
 		return
+	case errors.Is(err, authn.ErrInvalidPassword):
+		http.Error(w, "invalid password", http.StatusUnauthorized)
+		return
+	case err != nil:
+		logging.FromContext(r.Context()).Error("login failed", "error", err)
+		http.Error(w, "Authentication service unavailable", http.StatusServiceUnavailable)
+		return
 	}
 
-	if !auth.ComparePassword(input.Password, user.Password) {
-		http.Error(w, "invalid password", http.StatusUnauthorized)
+	h.completeLogin(w, r, user)
+}
+
+// completeLogin sends the response of a successful sign-in, whichever way
+// the user authenticated.
+func (h *AuthRouteHandler) completeLogin(w http.ResponseWriter, r *http.Request, user *model.User) {
+	if user.Disabled {
+		h.recordDisabledLogin(r, user)
+		http.Error(w, "Account is disabled", http.StatusForbidden)
 		return
 	}
 
+	event := newAuditEvent(r, audit.ActionLogin, audit.TargetUser, strconv.Itoa(user.ID))
+	event.ActorID = user.ID
+
+	opts := []auth.TokenOption{auth.WithTenant(user.OrganizationID)}
+	if h.groupClaims != nil {
+		groups, err := h.groupClaims.GetEffectiveGroups(r.Context(), user.ID)
+		if err != nil {
+			http.Error(w, "could not look up groups", http.StatusInternalServerError)
+			return
+		}
+
+		names := make([]string, 0, len(groups))
+		for _, g := range groups {
+			names = append(names, g.DisplayName)
+		}
+		opts = append(opts, auth.WithGroups(names))
+	}
+
+	if h.sessions != nil {
+		session := &model.Session{
+			UserID:    user.ID,
+			IP:        clientIP(r),
+			UserAgent: truncate(r.UserAgent(), 512),
+			ExpiresAt: time.Now().Add(auth.SessionTTL),
+		}
+
+		if err := h.sessions.Create(r.Context(), session); err != nil {
+			http.Error(w, "could not create session", http.StatusInternalServerError)
+			return
+		}
+
+		opts = append(opts, auth.WithSessionID(session.ID))
+		event.After, _ = json.Marshal(map[string]int{"session_id": session.ID})
+	}
+
 	// generate new token
-	token, jwtErr := auth.GenerateJWT(user.ID)
+	token, jwtErr := auth.GenerateJWTContext(r.Context(), user.ID, opts...)
 	if jwtErr != nil {
 		http.Error(w, "could not generate jwt token", http.StatusUnauthorized)
 		return
 	}
 
+	recordAudit(r, h.auditLog, event)
+	h.recordLoginAttempt(r, user, user.Email, model.LoginSuccess)
+
+	if h.cookieAuth {
+		csrfToken := auth.CSRFToken(token)
+		setAuthCookies(w, token, csrfToken, int(auth.SessionTTL.Seconds()))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"csrf_token": csrfToken,
+		})
+		return
+	}
+
 	// send token
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
@@ -113,6 +261,119 @@ func (h *AuthRouteHandler) Login(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// errAccountDisabled is returned by authenticatePassword for disabled
+// accounts.
+var errAccountDisabled = errors.New("account is disabled")
+
+// authenticatePassword checks the credentials of a sign-in form other than
+// Login, such as the OAuth login page, and records failures like Login does.
+func (h *AuthRouteHandler) authenticatePassword(r *http.Request, email string, password string) (*model.User, error) {
+	user, err := h.authenticator.Authenticate(r.Context(), email, password)
+	if err != nil {
+		h.recordLoginFailure(r, email, err)
+		return nil, err
+	}
+
+	if user.Disabled {
+		h.recordDisabledLogin(r, user)
+		return nil, errAccountDisabled
+	}
+
+	return user, nil
+}
+
+// recordLoginSuccess records a sign-in that doesn't go through
+// completeLogin, such as one on the OAuth login page.
+func (h *AuthRouteHandler) recordLoginSuccess(r *http.Request, user *model.User) {
+	event := newAuditEvent(r, audit.ActionLogin, audit.TargetUser, strconv.Itoa(user.ID))
+	event.ActorID = user.ID
+	recordAudit(r, h.auditLog, event)
+	h.recordLoginAttempt(r, user, user.Email, model.LoginSuccess)
+}
+
+func (h *AuthRouteHandler) recordDisabledLogin(r *http.Request, user *model.User) {
+	event := newAuditEvent(r, audit.ActionLoginFailed, audit.TargetUser, strconv.Itoa(user.ID))
+	event.After, _ = json.Marshal(map[string]string{"reason": "account disabled"})
+	recordAudit(r, h.auditLog, event)
+	h.recordLoginAttempt(r, user, user.Email, model.LoginDisabled)
+}
+
+// recordLoginFailure records a rejected password login. The email is kept
+// because there may be no account to point at.
+func (h *AuthRouteHandler) recordLoginFailure(r *http.Request, email string, err error) {
+	reason, outcome := "authentication service unavailable", model.LoginError
+	switch {
+	case errors.Is(err, authn.ErrUserNotFound):
+		reason, outcome = "user not found", model.LoginUserNotFound
+	case errors.Is(err, authn.ErrInvalidPassword):
+		reason, outcome = "invalid password", model.LoginInvalidPassword
+	}
+
+	// a wrong password shows up in the history of the account it was tried on
+	var user *model.User
+	if outcome == model.LoginInvalidPassword {
+		user, _ = h.repo.GetByEmail(r.Context(), email)
+	}
+	h.recordLoginAttempt(r, user, email, outcome)
+
+	event := newAuditEvent(r, audit.ActionLoginFailed, audit.TargetUser, "")
+	event.After, _ = json.Marshal(map[string]string{"email": truncate(email, 320), "reason": reason})
+	recordAudit(r, h.auditLog, event)
+}
+
+// Logout godoc
+// @Summary Sign out
+// @Description Revokes the session of the token and clears the session cookies. Cookie-authenticated requests must send the X-CSRF-Token header.
+// @Tags auth
+// @Security BearerAuth
+// @Success 204
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "CSRF token missing or invalid"
+// @Router /auth/logout [post]
+func (h *AuthRouteHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if h.sessions != nil && claims.SessionID != 0 {
+		if err := h.sessions.Revoke(r.Context(), claims.SessionID, claims.UserID); err != nil {
+			http.Error(w, "could not revoke session", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	recordAudit(r, h.auditLog, newAuditEvent(r, audit.ActionLogout, audit.TargetUser, strconv.Itoa(claims.UserID)))
+
+	setAuthCookies(w, "", "", -1)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setAuthCookies sets, or with a negative maxAge clears, the session cookie
+// and its CSRF companion. The CSRF cookie is readable by scripts so the front
+// end can pick the token up again after a reload.
+func setAuthCookies(w http.ResponseWriter, token string, csrfToken string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.SessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.CSRFCookie,
+		Value:    csrfToken,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
 // GetUserProfile godoc
 // @Summary      Get the authenticated user's profile
 // @Description  Requires a valid JWT token. Returns user info based on token.
@@ -131,10 +392,9 @@ func (h *AuthRouteHandler) GetUserProfile(w http.ResponseWriter, r *http.Request
 	if !ok {
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(model.ErrorResponse{Error: "Unauthorized request: invalid token"})
+		return
 	}
 
-	fmt.Println("Got User ID:", userID)
-
 	user, err := h.repo.Get(r.Context(), userID)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -144,3 +404,11 @@ func (h *AuthRouteHandler) GetUserProfile(w http.ResponseWriter, r *http.Request
 
 	json.NewEncoder(w).Encode(user)
 }
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	return strings.ToValidUTF8(s[:n], "")
+}
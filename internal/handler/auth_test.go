@@ -2,8 +2,12 @@ package handler_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"go-user-api/internal/authn"
 	"go-user-api/internal/handler"
+	"go-user-api/internal/model"
 	"go-user-api/internal/testutils"
 	"net/http"
 	"net/http/httptest"
@@ -32,3 +36,33 @@ func TestSignup(t *testing.T) {
 		t.Fatalf("expected 200 ok, got %d", rr.Code)
 	}
 }
+
+type failingAuthenticator struct{ err error }
+
+func (f failingAuthenticator) Authenticate(context.Context, string, string) (*model.User, error) {
+	return nil, f.err
+}
+
+func TestLoginMapsAuthenticatorErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{authn.ErrUserNotFound, http.StatusUnauthorized},
+		{authn.ErrInvalidPassword, http.StatusUnauthorized},
+		{errors.New("ldap: dial: connection refused"), http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		authHandler := handler.NewAuthRouteHandler(&testutils.MockUserRepo{},
+			handler.WithAuthenticator(failingAuthenticator{tt.err}))
+
+		body, _ := json.Marshal(map[string]string{"email": "jane@example.com", "password": "secret"})
+		rr := httptest.NewRecorder()
+		authHandler.Login(rr, httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(body)))
+
+		if rr.Code != tt.status {
+			t.Fatalf("%v: expected %d, got %d", tt.err, tt.status, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/model"
+	"go-user-api/internal/repository"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type ClientHandler struct {
+	repo repository.ClientRepository
+}
+
+func NewClientHandler(repo repository.ClientRepository) *ClientHandler {
+	return &ClientHandler{repo: repo}
+}
+
+// CreateClient godoc
+// @Summary Create a service account
+// @Description Register a new OAuth2 client. The client secret is only returned in this response; public clients get no secret.
+// @Tags clients
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   client  body  model.ClientInput  true  "Client Data"
+// @Success 201 {object} model.ClientCredentials
+// @Failure 400 {string} string "Invalid input"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Failed to create client"
+// @Router /admin/clients [post]
+func (h *ClientHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
+	var input model.ClientInput
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateClientInput(input); err != nil {
+		http.Error(w, "validation error: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	clientID, err := auth.GenerateSecret(16)
+	if err != nil {
+		http.Error(w, "Failed to create client", http.StatusInternalServerError)
+		return
+	}
+
+	c := model.Client{
+		ClientID:     clientID,
+		Name:         input.Name,
+		Scopes:       dedupe(input.Scopes),
+		RedirectURIs: dedupe(input.RedirectURIs),
+		Public:       input.Public,
+	}
+
+	var secret string
+	if !c.Public {
+		if secret, err = auth.GenerateSecret(32); err != nil {
+			http.Error(w, "Failed to create client", http.StatusInternalServerError)
+			return
+		}
+		c.SecretHash = auth.HashSecret(secret)
+	}
+
+	if err := h.repo.Create(r.Context(), &c); err != nil {
+		http.Error(w, "Failed to create client", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(model.ClientCredentials{Client: c, ClientSecret: secret})
+}
+
+// GetAllClients godoc
+// @Summary Retrieve all service accounts
+// @Description Get a list of all OAuth2 clients
+// @Tags clients
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} model.Client
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Something went wrong"
+// @Router /admin/clients [get]
+func (h *ClientHandler) GetAllClients(w http.ResponseWriter, r *http.Request) {
+	clients, err := h.repo.GetAllClients(r.Context())
+	if err != nil {
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(clients)
+}
+
+// GetClient godoc
+// @Summary Retrieve a service account by ID
+// @Tags clients
+// @Produce  json
+// @Security BearerAuth
+// @Param   id  path  int  true  "Client ID"
+// @Success 200 {object} model.Client
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Client not found"
+// @Router /admin/clients/{id} [get]
+func (h *ClientHandler) GetClient(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	c, err := h.repo.Get(r.Context(), id)
+
+	if err != nil {
+		http.Error(w, "Client not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(c)
+}
+
+// UpdateClient godoc
+// @Summary Update a service account
+// @Description Change the name, allowed scopes and redirect URIs of a client. Whether a client is public cannot be changed.
+// @Tags clients
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path  int                true  "Client ID"
+// @Param   client  body  model.ClientInput  true  "Client Data"
+// @Success 200 {object} model.Client
+// @Failure 400 {string} string "Invalid input"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Client not found"
+// @Router /admin/clients/{id} [put]
+func (h *ClientHandler) UpdateClient(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	var input model.ClientInput
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateClientInput(input); err != nil {
+		http.Error(w, "validation error: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c, err := h.repo.Get(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Client not found", http.StatusNotFound)
+		return
+	}
+
+	c.Name = input.Name
+	c.Scopes = dedupe(input.Scopes)
+	c.RedirectURIs = dedupe(input.RedirectURIs)
+	if err := h.repo.Update(r.Context(), c); err != nil {
+		http.Error(w, "Update failed", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(c)
+}
+
+// RotateClientSecret godoc
+// @Summary Rotate a service account secret
+// @Description Replace the client secret. The old secret stops working immediately and the new one is only returned in this response.
+// @Tags clients
+// @Produce  json
+// @Security BearerAuth
+// @Param   id  path  int  true  "Client ID"
+// @Success 200 {object} model.ClientCredentials
+// @Failure 400 {string} string "Public clients have no secret"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Client not found"
+// @Router /admin/clients/{id}/secret [post]
+func (h *ClientHandler) RotateClientSecret(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	c, err := h.repo.Get(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Client not found", http.StatusNotFound)
+		return
+	}
+
+	if c.Public {
+		http.Error(w, "Public clients have no secret", http.StatusBadRequest)
+		return
+	}
+
+	secret, err := auth.GenerateSecret(32)
+	if err != nil {
+		http.Error(w, "Failed to rotate secret", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.repo.UpdateSecret(r.Context(), id, auth.HashSecret(secret)); err != nil {
+		http.Error(w, "Failed to rotate secret", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(model.ClientCredentials{Client: *c, ClientSecret: secret})
+}
+
+// DeleteClient godoc
+// @Summary Delete a service account
+// @Tags clients
+// @Security BearerAuth
+// @Param   id  path  int  true  "Client ID"
+// @Success 204
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Client not found"
+// @Router /admin/clients/{id} [delete]
+func (h *ClientHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	if err := h.repo.Delete(r.Context(), id); err != nil {
+		http.Error(w, "Delete failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// dedupe drops duplicate values. It never returns nil, so the result can be
+// stored in a NOT NULL array column.
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := []string{}
+
+	for _, s := range values {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+
+	return out
+}
+
+func validateClientInput(input model.ClientInput) error {
+	if err := validate.Struct(input); err != nil {
+		return err
+	}
+
+	for _, uri := range input.RedirectURIs {
+		if err := validateRedirectURI(uri); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateRedirectURI enforces the redirect URI rules of RFC 6749 section
+// 3.1.2: absolute, hierarchical and without a fragment. Web apps use https
+// with a host. Native apps use plain http on a loopback address or a
+// reverse domain name private-use scheme such as com.example.app:/callback
+// (RFC 8252 section 7), so schemes like javascript: and data: are refused.
+func validateRedirectURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.Opaque != "" || u.Fragment != "" {
+		return fmt.Errorf("invalid redirect URI %q", uri)
+	}
+
+	switch u.Scheme {
+	case "https":
+	case "http":
+		if u.Hostname() != "localhost" && u.Hostname() != "127.0.0.1" && u.Hostname() != "::1" {
+			return fmt.Errorf("redirect URI %q must use https", uri)
+		}
+	default:
+		if !strings.Contains(u.Scheme, ".") {
+			return fmt.Errorf("redirect URI %q must use https or a reverse domain name scheme", uri)
+		}
+		return nil
+	}
+
+	if u.Host == "" {
+		return fmt.Errorf("redirect URI %q has no host", uri)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/testutils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCookieAuthMode(t *testing.T) {
+	users := &testutils.MockUserRepo{Users: []*model.User{testutils.NewMockUser(7, "jane@example.com", "password1")}}
+	sessions := &testutils.MockSessionRepo{}
+	h := handler.NewAuthRouteHandler(users, handler.WithSessions(sessions), handler.WithCookieAuth())
+
+	r := chi.NewRouter()
+	r.Post("/auth/login", h.Login)
+	r.With(middleware.NewJWTAuth().Middleware).Get("/auth/profile", h.GetUserProfile)
+	r.With(middleware.NewJWTAuth().Middleware).Post("/auth/logout", h.Logout)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email": "jane@example.com", "password": "password1"}`)))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp map[string]string
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Empty(t, resp["token"], "the token must not reach scripts")
+	assert.NotEmpty(t, resp["csrf_token"])
+
+	cookies := map[string]*http.Cookie{}
+	for _, c := range rr.Result().Cookies() {
+		cookies[c.Name] = c
+	}
+	session := cookies[auth.SessionCookie]
+	assert.NotNil(t, session)
+	assert.True(t, session.HttpOnly)
+	assert.True(t, session.Secure)
+	assert.Equal(t, http.SameSiteLaxMode, session.SameSite)
+	assert.Equal(t, resp["csrf_token"], cookies[auth.CSRFCookie].Value)
+
+	send := func(method string, path string, csrfToken string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.AddCookie(&http.Cookie{Name: auth.SessionCookie, Value: session.Value})
+		if csrfToken != "" {
+			req.Header.Set(auth.CSRFHeader, csrfToken)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// safe methods need only the cookie
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/auth/profile", "").Code)
+
+	// unsafe methods need the CSRF token of this session
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, "/auth/logout", "").Code)
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, "/auth/logout", auth.CSRFToken("another session")).Code)
+
+	rr = send(http.MethodPost, "/auth/logout", resp["csrf_token"])
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.NotNil(t, sessions.Sessions[0].RevokedAt)
+	for _, c := range rr.Result().Cookies() {
+		assert.Empty(t, c.Value)
+		assert.Less(t, c.MaxAge, 0)
+	}
+}
+
+func TestBearerTokenSkipsCSRFCheck(t *testing.T) {
+	token, err := auth.GenerateJWT(7)
+	assert.NoError(t, err)
+
+	reached := false
+	h := middleware.NewJWTAuth().Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { reached = true }))
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.True(t, reached)
+}
@@ -0,0 +1,371 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/federation"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/repository"
+	"go-user-api/internal/tenant"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	federationFlowCookie  = "oidc_flow"
+	federationFlowPurpose = "oidc_login"
+	federationFlowTTL     = 10 * time.Minute
+)
+
+type FederationHandler struct {
+	providers  map[string]*federation.Provider
+	identities repository.IdentityRepository
+	users      repository.UserRepository
+	login      *AuthRouteHandler
+}
+
+func NewFederationHandler(providers map[string]*federation.Provider, identities repository.IdentityRepository,
+	users repository.UserRepository, login *AuthRouteHandler) *FederationHandler {
+	return &FederationHandler{providers: providers, identities: identities, users: users, login: login}
+}
+
+type FederationProvider struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	LoginURL    string `json:"login_url"`
+}
+
+// GetProviders godoc
+// @Summary List external identity providers
+// @Description Lists the OpenID Connect providers users can sign in with
+// @Tags federation
+// @Produce  json
+// @Success 200 {array} handler.FederationProvider
+// @Router /auth/oidc/providers [get]
+func (h *FederationHandler) GetProviders(w http.ResponseWriter, r *http.Request) {
+	providers := []FederationProvider{}
+	for name, p := range h.providers {
+		providers = append(providers, FederationProvider{
+			Name:        name,
+			DisplayName: p.Config.DisplayName,
+			LoginURL:    "/auth/oidc/" + name + "/login",
+		})
+	}
+
+	sort.Slice(providers, func(i, j int) bool { return providers[i].Name < providers[j].Name })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(providers)
+}
+
+// Login godoc
+// @Summary Sign in with an external identity provider
+// @Description Redirects to the provider. The flow ends at the callback, which returns the same token as /auth/login.
+// @Tags federation
+// @Param   provider  path  string  true  "Provider name"
+// @Success 302 {string} string "Redirect to the provider"
+// @Failure 404 {string} string "Unknown provider"
+// @Failure 502 {string} string "Provider unavailable"
+// @Router /auth/oidc/{provider}/login [get]
+func (h *FederationHandler) Login(w http.ResponseWriter, r *http.Request) {
+	authURL, ok := h.startFlow(w, r, 0)
+	if !ok {
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Link godoc
+// @Summary Link an external identity to the signed-in user
+// @Description Starts the provider flow for the authenticated user. Send the browser to the returned URL; the callback then links the external account instead of signing in.
+// @Tags federation
+// @Produce  json
+// @Security BearerAuth
+// @Param   provider  path  string  true  "Provider name"
+// @Success 200 {object} map[string]string
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Unknown provider"
+// @Router /auth/oidc/{provider}/link [post]
+func (h *FederationHandler) Link(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	authURL, ok := h.startFlow(w, r, userID)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"authorization_url": authURL})
+}
+
+// startFlow stores the state, nonce and PKCE verifier of a new login in a
+// signed cookie and returns the provider URL to send the user to. linkUserID
+// is set when a signed-in user links an identity.
+func (h *FederationHandler) startFlow(w http.ResponseWriter, r *http.Request, linkUserID int) (string, bool) {
+	name := chi.URLParam(r, "provider")
+	provider, ok := h.providers[name]
+	if !ok {
+		http.Error(w, "Unknown provider", http.StatusNotFound)
+		return "", false
+	}
+
+	state, err1 := auth.GenerateSecret(16)
+	nonce, err2 := auth.GenerateSecret(16)
+	verifier, err3 := auth.GenerateSecret(32)
+	if err1 != nil || err2 != nil || err3 != nil {
+		http.Error(w, "could not start login", http.StatusInternalServerError)
+		return "", false
+	}
+
+	authURL, err := provider.AuthCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		http.Error(w, "Provider unavailable", http.StatusBadGateway)
+		return "", false
+	}
+
+	flow, _, err := auth.GeneratePurposeToken(federationFlowPurpose, name, map[string]string{
+		"state":    state,
+		"nonce":    nonce,
+		"verifier": verifier,
+		"link":     strconv.Itoa(linkUserID),
+		"tenant":   strconv.Itoa(tenant.ID(r.Context())),
+	}, federationFlowTTL)
+	if err != nil {
+		http.Error(w, "could not start login", http.StatusInternalServerError)
+		return "", false
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     federationFlowCookie,
+		Value:    flow,
+		Path:     "/auth/oidc/" + name,
+		MaxAge:   int(federationFlowTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		// Lax, because the provider sends the user back with a top-level
+		// cross-site navigation
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return authURL, true
+}
+
+// Callback godoc
+// @Summary External identity provider callback
+// @Description Completes the provider flow. Known identities sign in; new ones are linked by verified email when the provider allows it, or get a new account in the default organization. Returns the same token as /auth/login.
+// @Tags federation
+// @Produce  json
+// @Param   provider  path   string  true  "Provider name"
+// @Param   code      query  string  true  "Authorization code"
+// @Param   state     query  string  true  "State from the login redirect"
+// @Success 200 {object} map[string]string
+// @Failure 400 {string} string "Invalid login state"
+// @Failure 401 {string} string "Provider login failed"
+// @Failure 403 {string} string "No account in this organization"
+// @Failure 409 {string} string "Account exists, link it explicitly"
+// @Router /auth/oidc/{provider}/callback [get]
+func (h *FederationHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+	provider, ok := h.providers[name]
+	if !ok {
+		http.Error(w, "Unknown provider", http.StatusNotFound)
+		return
+	}
+
+	// the flow cookie is single use, whatever the outcome
+	http.SetCookie(w, &http.Cookie{Name: federationFlowCookie, Path: "/auth/oidc/" + name, MaxAge: -1, HttpOnly: true, Secure: true})
+
+	cookie, err := r.Cookie(federationFlowCookie)
+	if err != nil {
+		http.Error(w, "Invalid login state", http.StatusBadRequest)
+		return
+	}
+
+	flow, err := auth.ParsePurposeToken(federationFlowPurpose, cookie.Value)
+	if err != nil || flow.Subject != name ||
+		subtle.ConstantTimeCompare([]byte(flow.Data["state"]), []byte(r.URL.Query().Get("state"))) != 1 {
+		http.Error(w, "Invalid login state", http.StatusBadRequest)
+		return
+	}
+
+	// the provider redirects to a fixed callback, which may not name the organization
+	if organizationID, _ := strconv.Atoi(flow.Data["tenant"]); organizationID != 0 {
+		ctx, err := tenant.Bind(r.Context(), organizationID)
+		if err != nil {
+			http.Error(w, "Invalid login state", http.StatusBadRequest)
+			return
+		}
+		r = r.WithContext(ctx)
+	}
+
+	if errCode := r.URL.Query().Get("error"); errCode != "" {
+		http.Error(w, "Provider login failed: "+errCode, http.StatusUnauthorized)
+		return
+	}
+
+	identity, err := provider.Exchange(r.Context(), r.URL.Query().Get("code"), flow.Data["verifier"], flow.Data["nonce"])
+	if err != nil {
+		http.Error(w, "Provider login failed", http.StatusUnauthorized)
+		return
+	}
+
+	if linkUserID, _ := strconv.Atoi(flow.Data["link"]); linkUserID != 0 {
+		h.linkIdentity(w, r, name, identity, linkUserID)
+		return
+	}
+
+	user, status, msg := h.resolveUser(r, provider, identity)
+	if user == nil {
+		http.Error(w, msg, status)
+		return
+	}
+
+	h.login.completeLogin(w, r, user)
+}
+
+// resolveUser finds or creates the local user for an external identity.
+func (h *FederationHandler) resolveUser(r *http.Request, provider *federation.Provider, identity *federation.Identity) (*model.User, int, string) {
+	ctx := r.Context()
+	name := provider.Config.Name
+
+	if linked, err := h.identities.GetByProviderSubject(ctx, name, identity.Subject); err == nil {
+		user, err := h.users.Get(ctx, linked.UserID)
+		if err != nil {
+			return nil, http.StatusUnauthorized, "User not found"
+		}
+		return user, 0, ""
+	}
+
+	if identity.Email == "" {
+		return nil, http.StatusUnauthorized, "Provider did not share an email address"
+	}
+
+	user, err := h.users.GetByEmail(ctx, identity.Email)
+	if err == nil && user != nil {
+		if !provider.Config.AutoLinkVerifiedEmail || !identity.EmailVerified {
+			return nil, http.StatusConflict, "An account with this email already exists. Sign in and link this provider from your account."
+		}
+	} else {
+		// like sign-up, a first sign in only creates accounts in the default
+		// organization; the others take members by invitation, and anyone
+		// with an account at the provider could otherwise join them
+		if tenant.ID(ctx) != tenant.DefaultID {
+			return nil, http.StatusForbidden, "Accounts in this organization are created by invitation"
+		}
+
+		// first sign in: create the account with an unusable random password
+		password, err := auth.GenerateSecret(32)
+		if err != nil {
+			return nil, http.StatusInternalServerError, "failed to create user"
+		}
+
+		hashedPassword, err := auth.HashPasswordContext(r.Context(), password)
+		if err != nil {
+			return nil, http.StatusInternalServerError, "failed to create user"
+		}
+
+		user = &model.User{
+			Name:          identity.Name,
+			Email:         identity.Email,
+			Password:      hashedPassword,
+			EmailVerified: identity.EmailVerified,
+		}
+		if user.Name == "" {
+			user.Name = identity.Email
+		}
+
+		if err := h.users.Create(ctx, user); err != nil {
+			return nil, http.StatusInternalServerError, "failed to create user"
+		}
+	}
+
+	link := model.Identity{UserID: user.ID, Provider: name, Subject: identity.Subject, Email: identity.Email}
+	if err := h.identities.Create(ctx, &link); err != nil {
+		return nil, http.StatusInternalServerError, "failed to link identity"
+	}
+
+	return user, 0, ""
+}
+
+func (h *FederationHandler) linkIdentity(w http.ResponseWriter, r *http.Request, provider string, identity *federation.Identity, userID int) {
+	if existing, err := h.identities.GetByProviderSubject(r.Context(), provider, identity.Subject); err == nil {
+		if existing.UserID != userID {
+			http.Error(w, "This identity is linked to another account", http.StatusConflict)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(existing)
+		return
+	}
+
+	link := model.Identity{UserID: userID, Provider: provider, Subject: identity.Subject, Email: identity.Email}
+	if err := h.identities.Create(r.Context(), &link); err != nil {
+		http.Error(w, "failed to link identity", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(link)
+}
+
+// GetIdentities godoc
+// @Summary List linked external identities
+// @Tags federation
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} model.Identity
+// @Failure 401 {string} string "Unauthorized"
+// @Router /auth/identities [get]
+func (h *FederationHandler) GetIdentities(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	identities, err := h.identities.GetAllByUser(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(identities)
+}
+
+// DeleteIdentity godoc
+// @Summary Unlink an external identity
+// @Tags federation
+// @Security BearerAuth
+// @Param   id  path  int  true  "Identity ID"
+// @Success 204
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Identity not found"
+// @Router /auth/identities/{id} [delete]
+func (h *FederationHandler) DeleteIdentity(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	if err := h.identities.Delete(r.Context(), id, userID); err != nil {
+		http.Error(w, "Identity not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,187 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/federation"
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/routes"
+	"go-user-api/internal/testutils"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type federationFixture struct {
+	router     chi.Router
+	stub       *testutils.StubOIDCServer
+	users      *testutils.MockUserRepo
+	identities *testutils.MockIdentityRepo
+}
+
+func newFederationFixture(t *testing.T, autoLink bool) *federationFixture {
+	stub := testutils.NewStubOIDCServer("our-client")
+	t.Cleanup(stub.Close)
+
+	acmeJane := testutils.NewMockUser(2, "jane@acme.test", "password1")
+	acmeJane.OrganizationID = 2
+
+	f := &federationFixture{
+		router:     chi.NewRouter(),
+		stub:       stub,
+		users:      &testutils.MockUserRepo{Users: []*model.User{testutils.NewMockUser(1, "jane@example.com", "password1"), acmeJane}},
+		identities: &testutils.MockIdentityRepo{},
+	}
+	f.router.Use(middleware.ResolveTenant(testutils.NewMockOrganizationRepo(&model.Organization{ID: 2, Slug: "acme", Name: "Acme"}), "example.com"))
+
+	providers := map[string]*federation.Provider{"corp": federation.NewProvider(federation.ProviderConfig{
+		Name:                  "corp",
+		IssuerURL:             stub.URL,
+		ClientID:              "our-client",
+		ClientSecret:          "our-secret",
+		AutoLinkVerifiedEmail: autoLink,
+	})}
+
+	h := handler.NewFederationHandler(providers, f.identities, f.users, handler.NewAuthRouteHandler(f.users))
+	routes.RegisterFederationRoutes(f.router, h, middleware.NewJWTAuth())
+	return f
+}
+
+// signIn runs the login redirect and callback. prepare can adjust the stub
+// and the callback query after the redirect was issued. header is set on the
+// login request as name, value pairs.
+func (f *federationFixture) signIn(t *testing.T, prepare func(query url.Values), header ...string) *httptest.ResponseRecorder {
+	login := httptest.NewRequest(http.MethodGet, "/auth/oidc/corp/login", nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		login.Header.Set(header[i], header[i+1])
+	}
+
+	rr := httptest.NewRecorder()
+	f.router.ServeHTTP(rr, login)
+	assert.Equal(t, http.StatusFound, rr.Code)
+
+	authURL, err := url.Parse(rr.Header().Get("Location"))
+	assert.NoError(t, err)
+	assert.Equal(t, "S256", authURL.Query().Get("code_challenge_method"))
+	f.stub.Nonce = authURL.Query().Get("nonce")
+
+	query := url.Values{"code": {"upstream-code"}, "state": {authURL.Query().Get("state")}}
+	if prepare != nil {
+		prepare(query)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/oidc/corp/callback?"+query.Encode(), nil)
+	for _, c := range rr.Result().Cookies() {
+		req.AddCookie(c)
+	}
+
+	rr = httptest.NewRecorder()
+	f.router.ServeHTTP(rr, req)
+	return rr
+}
+
+func tokenUserID(t *testing.T, rr *httptest.ResponseRecorder) int {
+	var resp map[string]string
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+
+	userID, err := auth.DecodeJWT(resp["token"])
+	assert.NoError(t, err)
+	return userID
+}
+
+func TestFederatedLoginCreatesAndLinksUser(t *testing.T) {
+	f := newFederationFixture(t, false)
+	f.stub.Subject, f.stub.Email, f.stub.EmailVerified, f.stub.Name = "ext-42", "new@example.com", true, "New Person"
+
+	rr := f.signIn(t, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	userID := tokenUserID(t, rr)
+	assert.Equal(t, 3, userID)
+	assert.True(t, f.users.Users[2].EmailVerified)
+
+	// signing in again reuses the linked identity
+	rr = f.signIn(t, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, userID, tokenUserID(t, rr))
+	assert.Len(t, f.identities.Identities, 1)
+}
+
+func TestFederatedLoginLinksByVerifiedEmail(t *testing.T) {
+	f := newFederationFixture(t, true)
+	f.stub.Subject, f.stub.Email = "ext-1", "jane@example.com"
+
+	// an unverified email is never trusted for linking
+	rr := f.signIn(t, nil)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	f.stub.EmailVerified = true
+	rr = f.signIn(t, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, 1, tokenUserID(t, rr))
+}
+
+func TestFederatedLoginRequiresExplicitLinkWithoutAutoLink(t *testing.T) {
+	f := newFederationFixture(t, false)
+	f.stub.Subject, f.stub.Email, f.stub.EmailVerified = "ext-1", "jane@example.com", true
+
+	rr := f.signIn(t, nil)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Empty(t, f.identities.Identities)
+}
+
+func TestFederatedLoginRejectsBadStateAndNonce(t *testing.T) {
+	f := newFederationFixture(t, false)
+	f.stub.Subject, f.stub.Email = "ext-1", "someone@example.com"
+
+	rr := f.signIn(t, func(query url.Values) { query.Set("state", "forged") })
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = f.signIn(t, func(url.Values) { f.stub.Nonce = "replayed" })
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Empty(t, f.identities.Identities)
+}
+
+func TestFederatedLoginOnlyCreatesUsersInDefaultOrganization(t *testing.T) {
+	f := newFederationFixture(t, true)
+	f.stub.Subject, f.stub.Email, f.stub.EmailVerified = "ext-42", "new@example.com", true
+
+	// a stranger at the provider can't pick their way into another organization
+	rr := f.signIn(t, nil, "X-Tenant-ID", "acme")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Len(t, f.users.Users, 2)
+	assert.Empty(t, f.identities.Identities)
+
+	// its members still sign in with the provider
+	f.stub.Subject, f.stub.Email = "ext-7", "jane@acme.test"
+	rr = f.signIn(t, nil, "X-Tenant-ID", "acme")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, 2, tokenUserID(t, rr))
+}
+
+func TestFederatedIdentityLinkedPerOrganization(t *testing.T) {
+	f := newFederationFixture(t, true)
+	f.stub.Subject, f.stub.Email, f.stub.EmailVerified = "ext-1", "jane@example.com", true
+
+	rr := f.signIn(t, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, 1, tokenUserID(t, rr))
+
+	// the same external account is a different member of another
+	// organization, and its link there doesn't clash with the first one
+	f.stub.Email = "jane@acme.test"
+	rr = f.signIn(t, nil, "X-Tenant-ID", "acme")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, 2, tokenUserID(t, rr))
+
+	rr = f.signIn(t, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, 1, tokenUserID(t, rr))
+	assert.Len(t, f.identities.Identities, 2)
+	assert.Equal(t, 2, f.identities.Identities[1].OrganizationID)
+}
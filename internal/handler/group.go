@@ -0,0 +1,371 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"go-user-api/internal/audit"
+	"go-user-api/internal/model"
+	"go-user-api/internal/repository"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type GroupHandler struct {
+	groups   repository.GroupRepository
+	users    repository.UserRepository
+	auditLog audit.Recorder
+}
+
+func NewGroupHandler(groups repository.GroupRepository, users repository.UserRepository, auditLog audit.Recorder) *GroupHandler {
+	return &GroupHandler{groups: groups, users: users, auditLog: auditLog}
+}
+
+// GetGroups godoc
+// @Summary List groups
+// @Tags groups
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} model.Group
+// @Failure 403 {string} string "Forbidden"
+// @Router /admin/groups [get]
+func (h *GroupHandler) GetGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.groups.GetAllGroups(r.Context())
+	if err != nil {
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	writeGroups(w, groups)
+}
+
+// CreateGroup godoc
+// @Summary Create a group
+// @Tags groups
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   group  body  model.GroupInput  true  "Group"
+// @Success 201 {object} model.Group
+// @Failure 400 {string} string "Invalid input"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 409 {string} string "Group name already taken"
+// @Router /admin/groups [post]
+func (h *GroupHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	input, ok := h.decodeGroup(w, r, 0)
+	if !ok {
+		return
+	}
+
+	group := &model.Group{DisplayName: input.DisplayName, Members: []int{}, Subgroups: []int{}}
+	if err := h.groups.Create(r.Context(), group); err != nil {
+		http.Error(w, "failed to create group", http.StatusInternalServerError)
+		return
+	}
+
+	recordChange(r, h.auditLog, newAuditEvent(r, audit.ActionGroupCreate, audit.TargetGroup, strconv.Itoa(group.ID)), nil, group)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(group)
+}
+
+// GetGroup godoc
+// @Summary Get a group
+// @Description Lists the direct members and subgroups of the group.
+// @Tags groups
+// @Produce  json
+// @Security BearerAuth
+// @Param   id  path  int  true  "Group ID"
+// @Success 200 {object} model.Group
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Group not found"
+// @Router /admin/groups/{id} [get]
+func (h *GroupHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.findGroup(w, r, "id")
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group)
+}
+
+// UpdateGroup godoc
+// @Summary Rename a group
+// @Tags groups
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   id     path  int               true  "Group ID"
+// @Param   group  body  model.GroupInput  true  "Group"
+// @Success 200 {object} model.Group
+// @Failure 400 {string} string "Invalid input"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Group not found"
+// @Failure 409 {string} string "Group name already taken"
+// @Router /admin/groups/{id} [put]
+func (h *GroupHandler) UpdateGroup(w http.ResponseWriter, r *http.Request) {
+	before, ok := h.findGroup(w, r, "id")
+	if !ok {
+		return
+	}
+
+	input, ok := h.decodeGroup(w, r, before.ID)
+	if !ok {
+		return
+	}
+
+	after := *before
+	after.DisplayName = input.DisplayName
+	if err := h.groups.Update(r.Context(), &after); err != nil {
+		http.Error(w, "Update failed", http.StatusInternalServerError)
+		return
+	}
+
+	recordChange(r, h.auditLog, newAuditEvent(r, audit.ActionGroupUpdate, audit.TargetGroup, strconv.Itoa(after.ID)), before, after)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(after)
+}
+
+// DeleteGroup godoc
+// @Summary Delete a group
+// @Description Members keep their accounts. Groups it was nested in lose it as a subgroup.
+// @Tags groups
+// @Security BearerAuth
+// @Param   id  path  int  true  "Group ID"
+// @Success 204
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Group not found"
+// @Router /admin/groups/{id} [delete]
+func (h *GroupHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	before, ok := h.findGroup(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.groups.Delete(r.Context(), before.ID); err != nil {
+		http.Error(w, "Delete failed", http.StatusInternalServerError)
+		return
+	}
+
+	recordChange(r, h.auditLog, newAuditEvent(r, audit.ActionGroupDelete, audit.TargetGroup, strconv.Itoa(before.ID)), before, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddGroupMember godoc
+// @Summary Add a user to a group
+// @Tags groups
+// @Security BearerAuth
+// @Param   id      path  int  true  "Group ID"
+// @Param   userID  path  int  true  "User ID"
+// @Success 204
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Group or user not found"
+// @Router /admin/groups/{id}/members/{userID} [put]
+func (h *GroupHandler) AddGroupMember(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.findGroup(w, r, "id")
+	if !ok {
+		return
+	}
+
+	userID, _ := strconv.Atoi(chi.URLParam(r, "userID"))
+	if user, err := h.users.Get(r.Context(), userID); err != nil || user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	before := snapshotGroup(group)
+	if err := h.groups.AddMember(r.Context(), group.ID, userID); err != nil {
+		http.Error(w, "Update failed", http.StatusInternalServerError)
+		return
+	}
+
+	h.groupChanged(w, r, before)
+}
+
+// RemoveGroupMember godoc
+// @Summary Remove a user from a group
+// @Tags groups
+// @Security BearerAuth
+// @Param   id      path  int  true  "Group ID"
+// @Param   userID  path  int  true  "User ID"
+// @Success 204
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not a member"
+// @Router /admin/groups/{id}/members/{userID} [delete]
+func (h *GroupHandler) RemoveGroupMember(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.findGroup(w, r, "id")
+	if !ok {
+		return
+	}
+
+	before := snapshotGroup(group)
+	userID, _ := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err := h.groups.RemoveMember(r.Context(), group.ID, userID); err != nil {
+		http.Error(w, "Not a member", http.StatusNotFound)
+		return
+	}
+
+	h.groupChanged(w, r, before)
+}
+
+// AddSubgroup godoc
+// @Summary Nest a group in another
+// @Description Members of the subgroup become effective members of the group. A group cannot be nested in itself, directly or through its subgroups.
+// @Tags groups
+// @Security BearerAuth
+// @Param   id       path  int  true  "Group ID"
+// @Param   childID  path  int  true  "Subgroup ID"
+// @Success 204
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Group not found"
+// @Failure 409 {string} string "Group would contain itself"
+// @Router /admin/groups/{id}/subgroups/{childID} [put]
+func (h *GroupHandler) AddSubgroup(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.findGroup(w, r, "id")
+	if !ok {
+		return
+	}
+
+	child, ok := h.findGroup(w, r, "childID")
+	if !ok {
+		return
+	}
+
+	before := snapshotGroup(group)
+	err := h.groups.AddSubgroup(r.Context(), group.ID, child.ID)
+	if errors.Is(err, repository.ErrGroupCycle) {
+		http.Error(w, "Group would contain itself", http.StatusConflict)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, "Update failed", http.StatusInternalServerError)
+		return
+	}
+
+	h.groupChanged(w, r, before)
+}
+
+// RemoveSubgroup godoc
+// @Summary Remove a subgroup from a group
+// @Tags groups
+// @Security BearerAuth
+// @Param   id       path  int  true  "Group ID"
+// @Param   childID  path  int  true  "Subgroup ID"
+// @Success 204
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not a subgroup"
+// @Router /admin/groups/{id}/subgroups/{childID} [delete]
+func (h *GroupHandler) RemoveSubgroup(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.findGroup(w, r, "id")
+	if !ok {
+		return
+	}
+
+	before := snapshotGroup(group)
+	childID, _ := strconv.Atoi(chi.URLParam(r, "childID"))
+	if err := h.groups.RemoveSubgroup(r.Context(), group.ID, childID); err != nil {
+		http.Error(w, "Not a subgroup", http.StatusNotFound)
+		return
+	}
+
+	h.groupChanged(w, r, before)
+}
+
+// GetUserGroups godoc
+// @Summary List the effective groups of a user
+// @Description Includes the groups the user belongs to through subgroups.
+// @Tags groups
+// @Produce  json
+// @Security BearerAuth
+// @Param   id  path  int  true  "User ID"
+// @Success 200 {array} model.Group
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "User not found"
+// @Router /admin/users/{id}/groups [get]
+func (h *GroupHandler) GetUserGroups(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	if user, err := h.users.Get(r.Context(), userID); err != nil || user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	groups, err := h.groups.GetEffectiveGroups(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	writeGroups(w, groups)
+}
+
+// decodeGroup reads a GroupInput whose name no group but id has yet.
+func (h *GroupHandler) decodeGroup(w http.ResponseWriter, r *http.Request, id int) (*model.GroupInput, bool) {
+	var input model.GroupInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return nil, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		http.Error(w, "validation error"+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	groups, err := h.groups.GetAllGroups(r.Context())
+	if err != nil {
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	for _, g := range groups {
+		if g.DisplayName == input.DisplayName && g.ID != id {
+			http.Error(w, "Group name already taken", http.StatusConflict)
+			return nil, false
+		}
+	}
+
+	return &input, true
+}
+
+func (h *GroupHandler) findGroup(w http.ResponseWriter, r *http.Request, param string) (*model.Group, bool) {
+	id, _ := strconv.Atoi(chi.URLParam(r, param))
+	group, err := h.groups.Get(r.Context(), id)
+	if err != nil || group == nil {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	return group, true
+}
+
+// snapshotGroup copies g, so a membership change cannot alter the copy.
+func snapshotGroup(g *model.Group) model.Group {
+	snapshot := *g
+	snapshot.Members = append([]int{}, g.Members...)
+	snapshot.Subgroups = append([]int{}, g.Subgroups...)
+	return snapshot
+}
+
+// groupChanged records a membership change of the group and answers it.
+func (h *GroupHandler) groupChanged(w http.ResponseWriter, r *http.Request, before model.Group) {
+	if after, err := h.groups.Get(r.Context(), before.ID); err == nil {
+		recordChange(r, h.auditLog, newAuditEvent(r, audit.ActionGroupUpdate, audit.TargetGroup, strconv.Itoa(before.ID)), before, after)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeGroups(w http.ResponseWriter, groups []*model.Group) {
+	if groups == nil {
+		groups = []*model.Group{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
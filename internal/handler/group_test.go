@@ -0,0 +1,133 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/routes"
+	"go-user-api/internal/testutils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func newGroupRouter() (*chi.Mux, *testutils.MockGroupRepo, *testutils.MockUserRepo) {
+	admin := testutils.NewMockUser(1, "admin@example.com", "password1")
+	admin.Role = model.RoleAdmin
+	users := &testutils.MockUserRepo{Users: []*model.User{admin, testutils.NewMockUser(2, "jane@example.com", "password1")}}
+	groups := &testutils.MockGroupRepo{}
+
+	r := chi.NewRouter()
+	routes.RegisterGroupRoutes(r, handler.NewGroupHandler(groups, users, &testutils.MockAuditRepo{}), middleware.NewJWTAuth(), users)
+	routes.RegisterAuthRoutes(r, handler.NewAuthRouteHandler(users, handler.WithGroupClaims(groups)), middleware.NewJWTAuth())
+	r.With(middleware.NewJWTAuth().Middleware, middleware.RequireGroup(groups, "engineering")).Get("/engineering", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return r, groups, users
+}
+
+func sendAs(r http.Handler, method string, target string, token string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestNestedGroups(t *testing.T) {
+	r, groups, _ := newGroupRouter()
+	admin, _ := auth.GenerateJWT(1)
+	jane, _ := auth.GenerateJWT(2)
+
+	for _, name := range []string{"engineering", "backend", "databases"} {
+		assert.Equal(t, http.StatusCreated, sendAs(r, http.MethodPost, "/admin/groups", admin, `{"display_name": "`+name+`"}`).Code)
+	}
+	assert.Equal(t, http.StatusConflict, sendAs(r, http.MethodPost, "/admin/groups", admin, `{"display_name": "backend"}`).Code)
+	assert.Equal(t, http.StatusForbidden, sendAs(r, http.MethodPost, "/admin/groups", jane, `{"display_name": "mine"}`).Code)
+
+	// engineering > backend > databases > jane
+	assert.Equal(t, http.StatusNoContent, sendAs(r, http.MethodPut, "/admin/groups/1/subgroups/2", admin, "").Code)
+	assert.Equal(t, http.StatusNoContent, sendAs(r, http.MethodPut, "/admin/groups/2/subgroups/3", admin, "").Code)
+	assert.Equal(t, http.StatusNoContent, sendAs(r, http.MethodPut, "/admin/groups/3/members/2", admin, "").Code)
+	assert.Equal(t, http.StatusNotFound, sendAs(r, http.MethodPut, "/admin/groups/3/members/99", admin, "").Code)
+
+	// nesting must not close a cycle
+	assert.Equal(t, http.StatusConflict, sendAs(r, http.MethodPut, "/admin/groups/3/subgroups/1", admin, "").Code)
+	assert.Equal(t, http.StatusConflict, sendAs(r, http.MethodPut, "/admin/groups/2/subgroups/2", admin, "").Code)
+	assert.Equal(t, []int{2}, groups.Groups[0].Subgroups)
+
+	rr := sendAs(r, http.MethodGet, "/admin/users/2/groups", admin, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var effective []model.Group
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&effective))
+	assert.Len(t, effective, 3)
+
+	// membership through subgroups authorizes, and shows in the token
+	assert.Equal(t, http.StatusNoContent, sendAs(r, http.MethodGet, "/engineering", jane, "").Code)
+	assert.Equal(t, http.StatusForbidden, sendAs(r, http.MethodGet, "/engineering", admin, "").Code)
+
+	rr = sendAs(r, http.MethodPost, "/auth/login", "", `{"email": "jane@example.com", "password": "password1"}`)
+	var resp map[string]string
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	claims, err := auth.ParseToken(resp["token"])
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"engineering", "backend", "databases"}, claims.Groups)
+
+	// unnesting takes effect at once
+	assert.Equal(t, http.StatusNoContent, sendAs(r, http.MethodDelete, "/admin/groups/2/subgroups/3", admin, "").Code)
+	assert.Equal(t, http.StatusNotFound, sendAs(r, http.MethodDelete, "/admin/groups/2/subgroups/3", admin, "").Code)
+	assert.Equal(t, http.StatusForbidden, sendAs(r, http.MethodGet, "/engineering", jane, "").Code)
+
+	assert.Equal(t, http.StatusNoContent, sendAs(r, http.MethodDelete, "/admin/groups/3/members/2", admin, "").Code)
+	assert.Equal(t, http.StatusNotFound, sendAs(r, http.MethodDelete, "/admin/groups/3/members/2", admin, "").Code)
+
+	rr = sendAs(r, http.MethodPut, "/admin/groups/3", admin, `{"display_name": "storage"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "storage", groups.Groups[2].DisplayName)
+	assert.Equal(t, http.StatusNoContent, sendAs(r, http.MethodDelete, "/admin/groups/3", admin, "").Code)
+	assert.Equal(t, http.StatusNotFound, sendAs(r, http.MethodGet, "/admin/groups/3", admin, "").Code)
+}
+
+func TestGroupsPerOrganization(t *testing.T) {
+	f := newTenantFixture()
+	groups := &testutils.MockGroupRepo{}
+	routes.RegisterGroupRoutes(f.router, handler.NewGroupHandler(groups, f.users, &testutils.MockAuditRepo{}), middleware.NewJWTAuth(), f.users)
+	platformAdmin, _ := auth.GenerateJWT(1, auth.WithTenant(1))
+	acmeAdmin, _ := auth.GenerateJWT(2, auth.WithTenant(2))
+
+	// each organization has its own namespace of group names
+	assert.Equal(t, http.StatusCreated, f.send(http.MethodPost, "/admin/groups", platformAdmin, `{"display_name": "engineering"}`).Code)
+	assert.Equal(t, http.StatusCreated, f.send(http.MethodPost, "/admin/groups", acmeAdmin, `{"display_name": "engineering"}`).Code)
+	assert.Equal(t, 2, groups.Groups[1].OrganizationID)
+
+	rr := f.send(http.MethodGet, "/admin/groups", acmeAdmin, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var listed []model.Group
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&listed))
+	assert.Len(t, listed, 1)
+	assert.Equal(t, 2, listed[0].ID)
+
+	// the other organization's groups and users are out of reach
+	assert.Equal(t, http.StatusNotFound, f.send(http.MethodGet, "/admin/groups/1", acmeAdmin, "").Code)
+	assert.Equal(t, http.StatusNotFound, f.send(http.MethodPut, "/admin/groups/1/members/3", acmeAdmin, "").Code)
+	assert.Equal(t, http.StatusNotFound, f.send(http.MethodPut, "/admin/groups/2/members/3", acmeAdmin, "").Code)
+	assert.Equal(t, http.StatusNotFound, f.send(http.MethodPut, "/admin/groups/2/subgroups/1", acmeAdmin, "").Code)
+	assert.Equal(t, http.StatusNoContent, f.send(http.MethodPut, "/admin/groups/2/members/4", acmeAdmin, "").Code)
+
+	rr = f.send(http.MethodGet, "/admin/users/4/groups", acmeAdmin, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var effective []model.Group
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&effective))
+	assert.Len(t, effective, 1)
+	assert.Equal(t, http.StatusNotFound, f.send(http.MethodGet, "/admin/users/4/groups", platformAdmin, "").Code)
+}
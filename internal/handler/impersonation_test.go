@@ -0,0 +1,101 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/testutils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImpersonation(t *testing.T) {
+	admin := testutils.NewMockUser(1, "admin@example.com", "password1")
+	admin.Role = model.RoleAdmin
+	otherAdmin := testutils.NewMockUser(2, "root@example.com", "password1")
+	otherAdmin.Role = model.RoleAdmin
+	users := &testutils.MockUserRepo{Users: []*model.User{admin, otherAdmin, testutils.NewMockUser(7, "jane@example.com", "password1")}}
+
+	auditLog := &testutils.MockAuditRepo{}
+	h := handler.NewImpersonationHandler(users, auditLog)
+	jwtAuth := middleware.NewJWTAuth().OnImpersonation(h.AuditRequests)
+	r := chi.NewRouter()
+	r.With(jwtAuth.Middleware, middleware.DenyImpersonation, middleware.RequireAdmin(users)).
+		Post("/admin/users/{id}/impersonate", h.Impersonate)
+	r.With(jwtAuth.Middleware).Get("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"user":  r.Context().Value(middleware.UserIDKey),
+			"actor": r.Context().Value(middleware.ActorIDKey),
+		})
+	})
+	r.With(jwtAuth.Middleware, middleware.DenyImpersonation).Delete("/auth/sessions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	r.With(jwtAuth.Middleware).Put("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	send := func(method string, path string, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	adminToken, _ := auth.GenerateJWT(admin.ID)
+
+	// admins cannot be impersonated, and nobody impersonates themselves
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, "/admin/users/2/impersonate", adminToken).Code)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/admin/users/1/impersonate", adminToken).Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodPost, "/admin/users/99/impersonate", adminToken).Code)
+
+	rr := send(http.MethodPost, "/admin/users/7/impersonate", adminToken)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp struct {
+		Token     string `json:"token"`
+		ExpiresIn int    `json:"expires_in"`
+	}
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, int(auth.ImpersonationTTL.Seconds()), resp.ExpiresIn)
+
+	claims, err := auth.ParseToken(resp.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, "7", claims.Subject)
+	assert.Equal(t, 1, claims.ActorID())
+	assert.Equal(t, "admin.impersonate", auditLog.Events[0].Action)
+	assert.Equal(t, 1, auditLog.Events[0].ActorID)
+	assert.Equal(t, "7", auditLog.Events[0].TargetID)
+
+	// both identities are on the request context
+	var whoami map[string]int
+	assert.NoError(t, json.NewDecoder(send(http.MethodGet, "/whoami", resp.Token).Body).Decode(&whoami))
+	assert.Equal(t, map[string]int{"user": 7, "actor": 1}, whoami)
+
+	// sensitive actions are refused while impersonating
+	assert.Equal(t, http.StatusForbidden, send(http.MethodDelete, "/auth/sessions/1", resp.Token).Code)
+	assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/auth/sessions/1", adminToken).Code)
+
+	// everything done as the user, reads included, is audited under both
+	// identities, the admin's own requests are not
+	assert.Equal(t, http.StatusOK, send(http.MethodPut, "/users/7", resp.Token).Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodPut, "/users/1", adminToken).Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/whoami", adminToken).Code)
+	assert.Len(t, auditLog.Events, 4)
+	for i, want := range []struct {
+		target string
+		status string
+	}{{"GET /whoami", `{"status":200}`}, {"DELETE /auth/sessions/1", `{"status":403}`}, {"PUT /users/7", `{"status":200}`}} {
+		event := auditLog.Events[i+1]
+		assert.Equal(t, "admin.impersonated_request", event.Action)
+		assert.Equal(t, 7, event.ActorID)
+		assert.Equal(t, 1, event.ImpersonatorID)
+		assert.Equal(t, want.target, event.TargetID)
+		assert.JSONEq(t, want.status, string(event.After))
+	}
+}
@@ -0,0 +1,484 @@
+package handler
+
+import (
+	"encoding/json"
+	"go-user-api/internal/audit"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/logging"
+	"go-user-api/internal/mail"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/repository"
+	"go-user-api/internal/tenant"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// InvitationTTL is how long an emailed invitation can be accepted.
+const InvitationTTL = 7 * 24 * time.Hour
+
+const invitationPurpose = "invitation"
+
+type InvitationHandler struct {
+	invitations   repository.InvitationRepository
+	organizations repository.OrganizationRepository
+	users         repository.UserRepository
+	mailer        mail.Mailer
+	login         *AuthRouteHandler
+	auditLog      audit.Recorder
+}
+
+func NewInvitationHandler(invitations repository.InvitationRepository, organizations repository.OrganizationRepository,
+	users repository.UserRepository, mailer mail.Mailer, login *AuthRouteHandler, auditLog audit.Recorder) *InvitationHandler {
+	return &InvitationHandler{
+		invitations:   invitations,
+		organizations: organizations,
+		users:         users,
+		mailer:        mailer,
+		login:         login,
+		auditLog:      auditLog,
+	}
+}
+
+// CreateInvitation godoc
+// @Summary Invite someone to an organization
+// @Description Emails a signed link that lets the invitee join the organization with the given role, as an existing user or by signing up. Admins of the default organization may invite to any organization.
+// @Tags invitations
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   id          path  int                    true  "Organization ID"
+// @Param   invitation  body  model.InvitationInput  true  "Invitee"
+// @Success 201 {object} model.Invitation
+// @Failure 400 {string} string "Invalid input"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Organization not found"
+// @Failure 409 {string} string "Already a member"
+// @Router /orgs/{id}/invitations [post]
+func (h *InvitationHandler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	org, ok := h.organization(w, r)
+	if !ok {
+		return
+	}
+
+	var input model.InvitationInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		http.Error(w, "validation error"+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if input.Role == "" {
+		input.Role = model.RoleUser
+	}
+
+	if !validRole(input.Role) {
+		http.Error(w, "role must be user or admin", http.StatusBadRequest)
+		return
+	}
+
+	scoped := tenant.WithID(r.Context(), org.ID)
+	if existing, err := h.users.GetByEmail(scoped, input.Email); err == nil && existing != nil {
+		http.Error(w, "Already a member", http.StatusConflict)
+		return
+	}
+
+	if open, err := h.invitations.GetOpen(scoped, org.ID, input.Email); err == nil && open != nil {
+		http.Error(w, "An invitation is already open; resend it instead", http.StatusConflict)
+		return
+	}
+
+	callerID, _ := r.Context().Value(middleware.UserIDKey).(int)
+	invitation := &model.Invitation{
+		OrganizationID: org.ID,
+		Email:          input.Email,
+		Role:           input.Role,
+		InvitedBy:      callerID,
+		ExpiresAt:      time.Now().Add(InvitationTTL),
+	}
+	if err := h.invitations.Create(scoped, invitation); err != nil {
+		http.Error(w, "failed to create invitation", http.StatusInternalServerError)
+		return
+	}
+
+	if !h.send(w, r, org, invitation) {
+		return
+	}
+
+	recordAudit(r, h.auditLog, newAuditEvent(r, audit.ActionInvitationCreate, audit.TargetInvitation, strconv.Itoa(invitation.ID)))
+
+	writeInvitation(w, http.StatusCreated, invitation)
+}
+
+// GetInvitations godoc
+// @Summary List the invitations of an organization
+// @Tags invitations
+// @Produce  json
+// @Security BearerAuth
+// @Param   id  path  int  true  "Organization ID"
+// @Success 200 {array} model.Invitation
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Organization not found"
+// @Router /orgs/{id}/invitations [get]
+func (h *InvitationHandler) GetInvitations(w http.ResponseWriter, r *http.Request) {
+	org, ok := h.organization(w, r)
+	if !ok {
+		return
+	}
+
+	invitations, err := h.invitations.GetByOrganization(tenant.WithID(r.Context(), org.ID), org.ID)
+	if err != nil {
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	for _, i := range invitations {
+		i.Status = i.StatusAt(now)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitations)
+}
+
+// ResendInvitation godoc
+// @Summary Resend an invitation
+// @Description Emails a new link and restarts the expiry. Links sent before stop working. Expired invitations can be resent.
+// @Tags invitations
+// @Produce  json
+// @Security BearerAuth
+// @Param   id            path  int  true  "Organization ID"
+// @Param   invitationID  path  int  true  "Invitation ID"
+// @Success 200 {object} model.Invitation
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Invitation not found"
+// @Failure 409 {string} string "Invitation was already accepted or revoked"
+// @Router /orgs/{id}/invitations/{invitationID}/resend [post]
+func (h *InvitationHandler) ResendInvitation(w http.ResponseWriter, r *http.Request) {
+	org, ok := h.organization(w, r)
+	if !ok {
+		return
+	}
+
+	invitation, ok := h.invitation(w, r, org)
+	if !ok {
+		return
+	}
+
+	if invitation.AcceptedAt != nil || invitation.RevokedAt != nil {
+		http.Error(w, "Invitation was already accepted or revoked", http.StatusConflict)
+		return
+	}
+
+	if !h.send(w, r, org, invitation) {
+		return
+	}
+
+	recordAudit(r, h.auditLog, newAuditEvent(r, audit.ActionInvitationResend, audit.TargetInvitation, strconv.Itoa(invitation.ID)))
+
+	writeInvitation(w, http.StatusOK, invitation)
+}
+
+// RevokeInvitation godoc
+// @Summary Revoke an invitation
+// @Tags invitations
+// @Security BearerAuth
+// @Param   id            path  int  true  "Organization ID"
+// @Param   invitationID  path  int  true  "Invitation ID"
+// @Success 204
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Invitation not found"
+// @Failure 409 {string} string "Invitation was already accepted or revoked"
+// @Router /orgs/{id}/invitations/{invitationID} [delete]
+func (h *InvitationHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	org, ok := h.organization(w, r)
+	if !ok {
+		return
+	}
+
+	invitation, ok := h.invitation(w, r, org)
+	if !ok {
+		return
+	}
+
+	revoked, err := h.invitations.Revoke(tenant.WithID(r.Context(), org.ID), invitation.ID)
+	if err != nil {
+		http.Error(w, "Revoke failed", http.StatusInternalServerError)
+		return
+	}
+
+	if !revoked {
+		http.Error(w, "Invitation was already accepted or revoked", http.StatusConflict)
+		return
+	}
+
+	recordAudit(r, h.auditLog, newAuditEvent(r, audit.ActionInvitationRevoke, audit.TargetInvitation, strconv.Itoa(invitation.ID)))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PreviewInvitation godoc
+// @Summary Look at an invitation before accepting it
+// @Tags invitations
+// @Produce  json
+// @Param   token  query  string  true  "Token from the emailed link"
+// @Success 200 {object} model.InvitationPreview
+// @Failure 401 {string} string "Invalid or expired invitation"
+// @Router /invitations [get]
+func (h *InvitationHandler) PreviewInvitation(w http.ResponseWriter, r *http.Request) {
+	invitation, ok := h.redeemable(w, r, r.URL.Query().Get("token"))
+	if !ok {
+		return
+	}
+
+	org, err := h.organizations.Get(r.Context(), invitation.OrganizationID)
+	if err != nil || org == nil {
+		http.Error(w, "Invalid or expired invitation", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(model.InvitationPreview{
+		Organization: *org,
+		Email:        invitation.Email,
+		Role:         invitation.Role,
+		ExpiresAt:    invitation.ExpiresAt,
+	})
+}
+
+// AcceptInvitation godoc
+// @Summary Accept an invitation with your account
+// @Description Adds an account with your name and password to the inviting organization. The invitation must have been sent to your email address. Sign in to the organization afterwards.
+// @Tags invitations
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   request  body  model.InvitationTokenInput  true  "Token from the emailed link"
+// @Success 201 {object} model.User
+// @Failure 401 {string} string "Invalid or expired invitation"
+// @Failure 403 {string} string "This invitation was sent to another address"
+// @Failure 409 {string} string "Already a member"
+// @Router /invitations/accept [post]
+func (h *InvitationHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var input model.InvitationTokenInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Token == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	invitation, ok := h.redeemable(w, r, input.Token)
+	if !ok {
+		return
+	}
+
+	callerID, _ := r.Context().Value(middleware.UserIDKey).(int)
+	caller, err := h.users.Get(r.Context(), callerID)
+	if err != nil || caller == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	if !strings.EqualFold(caller.Email, invitation.Email) {
+		http.Error(w, "This invitation was sent to another address", http.StatusForbidden)
+		return
+	}
+
+	// Get leaves the password out, so look the hash up by email in the
+	// caller's organization
+	credentials, err := h.users.GetByEmail(r.Context(), caller.Email)
+	if err != nil || credentials == nil || credentials.ID != caller.ID {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	// the account in the inviting organization shares the caller's password
+	member := &model.User{Name: caller.Name, Email: invitation.Email, Password: credentials.Password}
+	user, ok := h.join(w, r, invitation, member)
+	if !ok {
+		return
+	}
+
+	resp := *user
+	resp.Password = ""
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// InvitationSignup godoc
+// @Summary Sign up from an invitation
+// @Description Creates an account for the invited email in the inviting organization and signs it in, answering like /auth/login. The invitation proves the address, so no verification email is sent.
+// @Tags invitations
+// @Accept  json
+// @Produce  json
+// @Param   request  body  model.InvitationSignupInput  true  "Token and new account"
+// @Success 200 {object} map[string]string
+// @Failure 400 {string} string "Invalid input"
+// @Failure 401 {string} string "Invalid or expired invitation"
+// @Failure 409 {string} string "Already a member"
+// @Router /invitations/signup [post]
+func (h *InvitationHandler) InvitationSignup(w http.ResponseWriter, r *http.Request) {
+	var input model.InvitationSignupInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		http.Error(w, "validation error"+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	invitation, ok := h.redeemable(w, r, input.Token)
+	if !ok {
+		return
+	}
+
+	ctx, err := tenant.Bind(r.Context(), invitation.OrganizationID)
+	if err != nil {
+		http.Error(w, "Invalid or expired invitation", http.StatusUnauthorized)
+		return
+	}
+	r = r.WithContext(ctx)
+
+	hashedPassword, err := auth.HashPasswordContext(r.Context(), input.Password)
+	if err != nil {
+		http.Error(w, "Could not hash password", http.StatusInternalServerError)
+		return
+	}
+
+	user, ok := h.join(w, r, invitation, &model.User{Name: input.Name, Email: invitation.Email, Password: hashedPassword})
+	if !ok {
+		return
+	}
+
+	h.login.completeLogin(w, r, user)
+}
+
+// organization loads the organization of the id URL parameter.
+func (h *InvitationHandler) organization(w http.ResponseWriter, r *http.Request) (*model.Organization, bool) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	org, err := h.organizations.Get(r.Context(), id)
+	if err != nil || org == nil {
+		http.Error(w, "Organization not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	return org, true
+}
+
+// invitation loads the invitation of the invitationID URL parameter, if it
+// belongs to org.
+func (h *InvitationHandler) invitation(w http.ResponseWriter, r *http.Request, org *model.Organization) (*model.Invitation, bool) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "invitationID"))
+	invitation, err := h.invitations.Get(tenant.WithID(r.Context(), org.ID), id)
+	if err != nil || invitation == nil || invitation.OrganizationID != org.ID {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	return invitation, true
+}
+
+// send emails a new link to the invitation and restarts its expiry.
+func (h *InvitationHandler) send(w http.ResponseWriter, r *http.Request, org *model.Organization, invitation *model.Invitation) bool {
+	// the link names the organization, to look the invitation up in
+	data := map[string]string{"tenant": strconv.Itoa(org.ID)}
+	token, tokenID, err := auth.GeneratePurposeToken(invitationPurpose, strconv.Itoa(invitation.ID), data, InvitationTTL)
+	if err != nil {
+		http.Error(w, "could not create invitation link", http.StatusInternalServerError)
+		return false
+	}
+
+	expiresAt := time.Now().Add(InvitationTTL)
+	if err := h.invitations.SetToken(tenant.WithID(r.Context(), org.ID), invitation.ID, tokenID, expiresAt); err != nil {
+		http.Error(w, "could not create invitation link", http.StatusInternalServerError)
+		return false
+	}
+	invitation.TokenID = tokenID
+	invitation.ExpiresAt = expiresAt
+
+	link := auth.Issuer() + "/invitations?token=" + url.QueryEscape(token)
+	msg := mail.Message{
+		To:      invitation.Email,
+		Subject: "You're invited to join " + org.Name,
+		Body: "Hi,\n\nYou've been invited to join " + org.Name + " as " + invitation.Role + ". " +
+			"Use this link to accept. It expires in " + strconv.Itoa(int(InvitationTTL.Hours()/24)) + " days:\n\n" + link +
+			"\n\nIf you weren't expecting it, you can ignore this email.\n",
+	}
+
+	// the invitation stays open and can be resent, so a failure is only logged
+	if err := h.mailer.Send(r.Context(), msg); err != nil {
+		logging.FromContext(r.Context()).Error("failed to send invitation", "error", err)
+	}
+
+	return true
+}
+
+// redeemable returns the invitation of token if it is the latest link sent
+// for it and the invitation is still pending.
+func (h *InvitationHandler) redeemable(w http.ResponseWriter, r *http.Request, token string) (*model.Invitation, bool) {
+	claims, err := auth.ParsePurposeToken(invitationPurpose, token)
+	if err != nil {
+		http.Error(w, "Invalid or expired invitation", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	id, _ := strconv.Atoi(claims.Subject)
+	organizationID, _ := strconv.Atoi(claims.Data["tenant"])
+	invitation, err := h.invitations.Get(tenant.WithID(r.Context(), organizationID), id)
+	if err != nil || invitation == nil || invitation.TokenID != claims.ID ||
+		invitation.StatusAt(time.Now()) != model.InvitationPending {
+		http.Error(w, "Invalid or expired invitation", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	return invitation, true
+}
+
+// join accepts the invitation by creating user in the inviting organization
+// with the invited role.
+func (h *InvitationHandler) join(w http.ResponseWriter, r *http.Request, invitation *model.Invitation, user *model.User) (*model.User, bool) {
+	r = r.WithContext(tenant.WithID(r.Context(), invitation.OrganizationID))
+
+	if existing, err := h.users.GetByEmail(r.Context(), invitation.Email); err == nil && existing != nil {
+		http.Error(w, "Already a member", http.StatusConflict)
+		return nil, false
+	}
+
+	// the invitation is spent and the account created together, so the
+	// invitation is used at most once and never lost to a failed sign-up
+	user.EmailVerified = true
+	accepted, err := h.invitations.Accept(r.Context(), invitation.ID, user)
+	if err != nil {
+		http.Error(w, "failed to create user", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if !accepted {
+		http.Error(w, "Invalid or expired invitation", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	recordAudit(r, h.auditLog, newAuditEvent(r, audit.ActionInvitationAccept, audit.TargetInvitation, strconv.Itoa(invitation.ID)))
+	recordChange(r, h.auditLog, newAuditEvent(r, audit.ActionUserCreate, audit.TargetUser, strconv.Itoa(user.ID)), nil, user)
+
+	return user, true
+}
+
+func writeInvitation(w http.ResponseWriter, status int, invitation *model.Invitation) {
+	invitation.Status = invitation.StatusAt(time.Now())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(invitation)
+}
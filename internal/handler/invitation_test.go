@@ -0,0 +1,195 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/routes"
+	"go-user-api/internal/testutils"
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var invitationLink = regexp.MustCompile(`/invitations\?token=(\S+)`)
+
+func newInvitationFixture() (*tenantFixture, *testutils.MockInvitationRepo, *testutils.MockMailer) {
+	f := newTenantFixture()
+	invitations := &testutils.MockInvitationRepo{Users: f.users}
+	mailer := &testutils.MockMailer{}
+
+	h := handler.NewInvitationHandler(invitations, f.organizations, f.users, mailer, handler.NewAuthRouteHandler(f.users), &testutils.MockAuditRepo{})
+	routes.RegisterInvitationRoutes(f.router, h, middleware.NewJWTAuth(), f.users)
+
+	return f, invitations, mailer
+}
+
+func lastInvitationToken(t *testing.T, mailer *testutils.MockMailer) string {
+	assert.NotEmpty(t, mailer.Messages)
+	match := invitationLink.FindStringSubmatch(mailer.Messages[len(mailer.Messages)-1].Body)
+	assert.Len(t, match, 2)
+	token, err := url.QueryUnescape(match[1])
+	assert.NoError(t, err)
+	return token
+}
+
+func TestInvitationAdministration(t *testing.T) {
+	f, invitations, mailer := newInvitationFixture()
+	acmeAdmin, _ := auth.GenerateJWT(2, auth.WithTenant(2))
+	platformAdmin, _ := auth.GenerateJWT(1, auth.WithTenant(1))
+	jane, _ := auth.GenerateJWT(3, auth.WithTenant(1))
+
+	rr := f.send(http.MethodPost, "/orgs/2/invitations", acmeAdmin, `{"email": "new@acme.test", "role": "admin"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var invitation model.Invitation
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&invitation))
+	assert.Equal(t, model.InvitationPending, invitation.Status)
+	assert.Equal(t, 2, invitation.InvitedBy)
+	assert.Equal(t, "new@acme.test", mailer.Messages[0].To)
+	first := lastInvitationToken(t, mailer)
+
+	assert.Equal(t, http.StatusConflict, f.send(http.MethodPost, "/orgs/2/invitations", acmeAdmin, `{"email": "NEW@acme.test"}`).Code, "already open")
+	assert.Equal(t, http.StatusConflict, f.send(http.MethodPost, "/orgs/2/invitations", acmeAdmin, `{"email": "jane@example.com"}`).Code, "already a member")
+	assert.Equal(t, http.StatusBadRequest, f.send(http.MethodPost, "/orgs/2/invitations", acmeAdmin, `{"email": "x@acme.test", "role": "owner"}`).Code)
+
+	// tenant admins only invite to their own organization, non-admins not at all
+	assert.Equal(t, http.StatusNotFound, f.send(http.MethodPost, "/orgs/1/invitations", acmeAdmin, `{"email": "x@example.com"}`).Code)
+	assert.Equal(t, http.StatusForbidden, f.send(http.MethodPost, "/orgs/1/invitations", jane, `{"email": "x@example.com"}`).Code)
+	assert.Equal(t, http.StatusCreated, f.send(http.MethodPost, "/orgs/2/invitations", platformAdmin, `{"email": "other@acme.test"}`).Code)
+
+	// an expired invitation can be resent, which replaces the emailed link
+	invitations.Invitations[0].ExpiresAt = time.Now().Add(-time.Minute)
+	assert.Equal(t, http.StatusUnauthorized, f.send(http.MethodGet, "/invitations?token="+url.QueryEscape(first), "", "").Code)
+
+	rr = f.send(http.MethodPost, "/orgs/2/invitations/1/resend", acmeAdmin, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	resent := lastInvitationToken(t, mailer)
+	assert.Equal(t, http.StatusUnauthorized, f.send(http.MethodGet, "/invitations?token="+url.QueryEscape(first), "", "").Code, "superseded link")
+
+	rr = f.send(http.MethodGet, "/invitations?token="+url.QueryEscape(resent), "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var preview model.InvitationPreview
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&preview))
+	assert.Equal(t, "acme", preview.Organization.Slug)
+	assert.Equal(t, model.RoleAdmin, preview.Role)
+
+	// revoked invitations can no longer be used or resent
+	assert.Equal(t, http.StatusNotFound, f.send(http.MethodDelete, "/orgs/1/invitations/1", platformAdmin, "").Code, "wrong organization")
+	assert.Equal(t, http.StatusNoContent, f.send(http.MethodDelete, "/orgs/2/invitations/1", acmeAdmin, "").Code)
+	assert.Equal(t, http.StatusConflict, f.send(http.MethodDelete, "/orgs/2/invitations/1", acmeAdmin, "").Code)
+	assert.Equal(t, http.StatusConflict, f.send(http.MethodPost, "/orgs/2/invitations/1/resend", acmeAdmin, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, f.send(http.MethodGet, "/invitations?token="+url.QueryEscape(resent), "", "").Code)
+
+	rr = f.send(http.MethodGet, "/orgs/2/invitations", acmeAdmin, "")
+	var listed []model.Invitation
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&listed))
+	assert.Len(t, listed, 2)
+	assert.Equal(t, model.InvitationRevoked, listed[0].Status)
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	f, _, mailer := newInvitationFixture()
+	acmeAdmin, _ := auth.GenerateJWT(2, auth.WithTenant(2))
+	root, _ := auth.GenerateJWT(1, auth.WithTenant(1))
+
+	assert.Equal(t, http.StatusCreated, f.send(http.MethodPost, "/orgs/2/invitations", acmeAdmin, `{"email": "root@example.com", "role": "admin"}`).Code)
+	token := lastInvitationToken(t, mailer)
+	body := `{"token": "` + token + `"}`
+
+	jane, _ := auth.GenerateJWT(3, auth.WithTenant(1))
+	assert.Equal(t, http.StatusForbidden, f.send(http.MethodPost, "/invitations/accept", jane, body).Code, "sent to another address")
+	assert.Equal(t, http.StatusUnauthorized, f.send(http.MethodPost, "/invitations/accept", "", body).Code)
+
+	rr := f.send(http.MethodPost, "/invitations/accept", root, body)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var member model.User
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&member))
+	assert.Equal(t, 2, member.OrganizationID)
+	assert.Equal(t, model.RoleAdmin, member.Role)
+	assert.Empty(t, member.Password)
+
+	assert.Equal(t, http.StatusUnauthorized, f.send(http.MethodPost, "/invitations/accept", root, body).Code, "single use")
+
+	// the new account signs in to the organization with the same password
+	rr = f.send(http.MethodPost, "/auth/login", "", `{"email": "root@example.com", "password": "password1"}`, "X-Tenant-ID", "acme")
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestInvitationSignup(t *testing.T) {
+	f, _, mailer := newInvitationFixture()
+	acmeAdmin, _ := auth.GenerateJWT(2, auth.WithTenant(2))
+
+	assert.Equal(t, http.StatusCreated, f.send(http.MethodPost, "/orgs/2/invitations", acmeAdmin, `{"email": "new@acme.test"}`).Code)
+	token := lastInvitationToken(t, mailer)
+
+	assert.Equal(t, http.StatusBadRequest, f.send(http.MethodPost, "/invitations/signup", "", `{"token": "`+token+`", "name": "New Hire"}`).Code)
+	assert.Equal(t, http.StatusUnauthorized, f.send(http.MethodPost, "/invitations/signup", "",
+		`{"token": "`+token+`", "name": "New Hire", "password": "secret1"}`, "X-Tenant-ID", "default").Code, "other organization's host")
+
+	rr := f.send(http.MethodPost, "/invitations/signup", "", `{"token": "`+token+`", "name": "New Hire", "password": "secret1"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp map[string]string
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	claims, err := auth.ParseToken(resp["token"])
+	assert.NoError(t, err)
+	assert.Equal(t, 2, claims.TenantID)
+
+	user := f.users.Users[len(f.users.Users)-1]
+	assert.Equal(t, claims.UserID, user.ID)
+	assert.Equal(t, "new@acme.test", user.Email)
+	assert.Equal(t, model.RoleUser, user.Role)
+	assert.True(t, user.EmailVerified)
+
+	assert.Equal(t, http.StatusUnauthorized, f.send(http.MethodPost, "/invitations/signup", "",
+		`{"token": "`+token+`", "name": "New Hire", "password": "secret1"}`).Code, "single use")
+}
+
+// racingInvitationRepo signs up racer just before the invitation is accepted.
+type racingInvitationRepo struct {
+	*testutils.MockInvitationRepo
+	racer *model.User
+}
+
+func (r racingInvitationRepo) Accept(ctx context.Context, id int, user *model.User) (bool, error) {
+	if r.racer != nil {
+		r.Users.Users = append(r.Users.Users, r.racer)
+	}
+	return r.MockInvitationRepo.Accept(ctx, id, user)
+}
+
+func TestInvitationKeptWhenSignupFails(t *testing.T) {
+	f := newTenantFixture()
+	invitations := &testutils.MockInvitationRepo{Users: f.users}
+	mailer := &testutils.MockMailer{}
+	racer := testutils.NewMockUser(9, "new@acme.test", "password1")
+	racer.OrganizationID = 2
+	racing := &racingInvitationRepo{MockInvitationRepo: invitations, racer: racer}
+
+	h := handler.NewInvitationHandler(racing, f.organizations, f.users, mailer, handler.NewAuthRouteHandler(f.users), &testutils.MockAuditRepo{})
+	routes.RegisterInvitationRoutes(f.router, h, middleware.NewJWTAuth(), f.users)
+	acmeAdmin, _ := auth.GenerateJWT(2, auth.WithTenant(2))
+
+	assert.Equal(t, http.StatusCreated, f.send(http.MethodPost, "/orgs/2/invitations", acmeAdmin, `{"email": "new@acme.test", "role": "admin"}`).Code)
+	body := `{"token": "` + lastInvitationToken(t, mailer) + `", "name": "New Hire", "password": "secret1"}`
+
+	// the address was taken between the check and the sign-up
+	assert.Equal(t, http.StatusInternalServerError, f.send(http.MethodPost, "/invitations/signup", "", body).Code)
+	assert.Equal(t, model.InvitationPending, invitations.Invitations[0].StatusAt(time.Now()))
+	assert.Len(t, f.users.Users, 5)
+
+	// once the address is free again, the same invitation still works
+	f.users.Users = f.users.Users[:4]
+	racing.racer = nil
+	assert.Equal(t, http.StatusOK, f.send(http.MethodPost, "/invitations/signup", "", body).Code)
+	user := f.users.Users[len(f.users.Users)-1]
+	assert.Equal(t, "new@acme.test", user.Email)
+	assert.Equal(t, model.RoleAdmin, user.Role)
+	assert.Equal(t, 2, user.OrganizationID)
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"encoding/json"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/logging"
+	"go-user-api/internal/mail"
+	"go-user-api/internal/metrics"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultLoginHistorySize = 50
+	maxLoginHistorySize     = 200
+)
+
+// deviceFingerprint identifies the device of a login by its user agent and
+// network. Only the /24 (IPv4) or /48 (IPv6) prefix of the address counts,
+// so a new address from the same provider is not a new device.
+func deviceFingerprint(ip string, userAgent string) string {
+	network := ip
+	if addr := net.ParseIP(ip); addr != nil {
+		if v4 := addr.To4(); v4 != nil {
+			network = v4.Mask(net.CIDRMask(24, 32)).String()
+		} else {
+			network = addr.Mask(net.CIDRMask(48, 128)).String()
+		}
+	}
+
+	return auth.HashSecret(network + "\x00" + userAgent)
+}
+
+// recordLoginAttempt adds an attempt to the login history. Successful logins
+// from an unknown device get the user an email, except their very first.
+func (h *AuthRouteHandler) recordLoginAttempt(r *http.Request, user *model.User, email string, outcome string) {
+	result := "failure"
+	if outcome == model.LoginSuccess {
+		result = "success"
+	}
+	metrics.Logins.WithLabelValues(result, outcome).Inc()
+
+	if h.loginHistory == nil {
+		return
+	}
+
+	attempt := &model.LoginAttempt{
+		Email:     truncate(email, 320),
+		Outcome:   outcome,
+		IP:        clientIP(r),
+		UserAgent: truncate(r.UserAgent(), 512),
+	}
+	attempt.Fingerprint = deviceFingerprint(attempt.IP, attempt.UserAgent)
+
+	if user != nil {
+		attempt.UserID = user.ID
+		attempt.Email = user.Email
+	}
+
+	if user != nil && outcome == model.LoginSuccess {
+		fromDevice, total, err := h.loginHistory.CountSuccesses(r.Context(), user.ID, attempt.Fingerprint)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("could not look up login history", "error", err)
+		}
+		attempt.NewDevice = err == nil && fromDevice == 0 && total > 0
+	}
+
+	if err := h.loginHistory.Create(r.Context(), attempt); err != nil {
+		logging.FromContext(r.Context()).Error("could not record login attempt", "error", err)
+	}
+
+	if attempt.NewDevice && h.mailer != nil {
+		h.notifyNewDevice(r, user, attempt)
+	}
+}
+
+func (h *AuthRouteHandler) notifyNewDevice(r *http.Request, user *model.User, attempt *model.LoginAttempt) {
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "New sign-in to your account",
+		Body: "Hi " + user.Name + ",\n\nYour account was just signed in to from a device we haven't seen before:\n\n" +
+			"Time: " + time.Now().UTC().Format(time.RFC1123) + "\n" +
+			"IP address: " + attempt.IP + "\n" +
+			"Browser: " + attempt.UserAgent + "\n\n" +
+			"If this was you, there is nothing to do. If not, sign out the session from your account and change your password.\n",
+	}
+
+	// the login itself succeeded, so a failure is only logged
+	if err := h.mailer.Send(r.Context(), msg); err != nil {
+		logging.FromContext(r.Context()).Error("failed to send new device notification", "error", err)
+	}
+}
+
+// GetLoginHistory godoc
+// @Summary List your recent login attempts
+// @Description Lists successful and failed logins to your account, newest first. Logins from a device not seen before are flagged and announced by email.
+// @Tags auth
+// @Produce  json
+// @Security BearerAuth
+// @Param   limit  query  int  false  "Number of attempts, at most 200"
+// @Success 200 {array} model.LoginAttempt
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Login history is not enabled"
+// @Router /auth/login-history [get]
+func (h *AuthRouteHandler) GetLoginHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if h.loginHistory == nil {
+		http.Error(w, "Login history is not enabled", http.StatusNotFound)
+		return
+	}
+
+	limit := defaultLoginHistorySize
+	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 {
+		limit = min(n, maxLoginHistorySize)
+	}
+
+	attempts, err := h.loginHistory.GetByUser(r.Context(), userID, limit)
+	if err != nil {
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attempts)
+}
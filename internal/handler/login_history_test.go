@@ -0,0 +1,67 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/testutils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginHistoryAndNewDeviceNotification(t *testing.T) {
+	users := &testutils.MockUserRepo{Users: []*model.User{testutils.NewMockUser(7, "jane@example.com", "password1")}}
+	history := &testutils.MockLoginAttemptRepo{}
+	mailer := &testutils.MockMailer{}
+	h := handler.NewAuthRouteHandler(users, handler.WithLoginHistory(history, mailer))
+
+	login := func(email string, password string, ip string, userAgent string) int {
+		req := httptest.NewRequest(http.MethodPost, "/auth/login",
+			strings.NewReader(`{"email": "`+email+`", "password": "`+password+`"}`))
+		req.RemoteAddr = ip + ":51234"
+		req.Header.Set("User-Agent", userAgent)
+		rr := httptest.NewRecorder()
+		h.Login(rr, req)
+		return rr.Code
+	}
+
+	// the first login ever is not news
+	assert.Equal(t, http.StatusOK, login("jane@example.com", "password1", "203.0.113.7", "Firefox"))
+	// a new address on the same network is the same device
+	assert.Equal(t, http.StatusOK, login("jane@example.com", "password1", "203.0.113.99", "Firefox"))
+	assert.Empty(t, mailer.Messages)
+
+	assert.Equal(t, http.StatusUnauthorized, login("jane@example.com", "wrong-password", "198.51.100.1", "curl"))
+	assert.Equal(t, http.StatusUnauthorized, login("nobody@example.com", "password1", "198.51.100.1", "curl"))
+
+	assert.Equal(t, http.StatusOK, login("jane@example.com", "password1", "198.51.100.1", "Mobile Safari"))
+	assert.Len(t, mailer.Messages, 1)
+	assert.Equal(t, "jane@example.com", mailer.Messages[0].To)
+	assert.Contains(t, mailer.Messages[0].Body, "198.51.100.1")
+	assert.Contains(t, mailer.Messages[0].Body, "Mobile Safari")
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/login-history?limit=10", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 7))
+	rr := httptest.NewRecorder()
+	h.GetLoginHistory(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var attempts []model.LoginAttempt
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&attempts))
+	assert.Len(t, attempts, 4, "attempts on unknown emails belong to nobody")
+
+	var outcomes []string
+	for _, a := range attempts {
+		outcomes = append(outcomes, a.Outcome)
+	}
+	assert.Equal(t, []string{"success", "invalid_password", "success", "success"}, outcomes)
+	assert.True(t, attempts[0].NewDevice)
+	assert.Equal(t, "198.51.100.1", attempts[1].IP)
+	assert.False(t, attempts[2].NewDevice)
+}
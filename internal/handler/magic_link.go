@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/logging"
+	"go-user-api/internal/mail"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/ratelimit"
+	"go-user-api/internal/repository"
+	"go-user-api/internal/tenant"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MagicLinkTTL is how long an emailed sign-in link stays valid.
+const MagicLinkTTL = 15 * time.Minute
+
+const (
+	magicLinkPurpose      = "magic_link"
+	magicLinkDeviceCookie = "magic_link_device"
+)
+
+type MagicLinkHandler struct {
+	users  repository.UserRepository
+	grants repository.GrantRepository
+	mailer mail.Mailer
+	login  *AuthRouteHandler
+
+	emailLimiter *ratelimit.Limiter
+	ipLimiter    *ratelimit.Limiter
+}
+
+func NewMagicLinkHandler(users repository.UserRepository, grants repository.GrantRepository, mailer mail.Mailer, login *AuthRouteHandler) *MagicLinkHandler {
+	return &MagicLinkHandler{
+		users:        users,
+		grants:       grants,
+		mailer:       mailer,
+		login:        login,
+		emailLimiter: ratelimit.New(3, 15*time.Minute),
+		ipLimiter:    ratelimit.New(20, 15*time.Minute),
+	}
+}
+
+// RequestMagicLink godoc
+// @Summary Email a sign-in link
+// @Description Sends a single-use link that signs the user in without a password. The response is the same whether or not the email belongs to an account.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param   request  body  model.MagicLinkInput  true  "Email address"
+// @Success 202 {object} map[string]string
+// @Failure 400 {string} string "Invalid input"
+// @Failure 429 {string} string "Too many requests"
+// @Router /auth/magic-link [post]
+func (h *MagicLinkHandler) RequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	var input model.MagicLinkInput
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		http.Error(w, "validation error"+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, check := range []struct {
+		limiter *ratelimit.Limiter
+		key     string
+	}{{h.ipLimiter, clientIP(r)}, {h.emailLimiter, strings.ToLower(input.Email)}} {
+		if ok, retryAfter := check.limiter.Allow(check.key); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+	}
+
+	data := map[string]string{"tenant": strconv.Itoa(tenant.ID(r.Context()))}
+
+	// the device cookie is set before the lookup, so it doesn't reveal
+	// whether the account exists either
+	if input.BindDevice {
+		device, err := auth.GenerateSecret(32)
+		if err != nil {
+			http.Error(w, "could not create link", http.StatusInternalServerError)
+			return
+		}
+
+		data["device"] = auth.HashSecret(device)
+		http.SetCookie(w, &http.Cookie{
+			Name:     magicLinkDeviceCookie,
+			Value:    device,
+			Path:     "/auth/magic-link",
+			MaxAge:   int(MagicLinkTTL.Seconds()),
+			HttpOnly: true,
+			Secure:   true,
+			// Lax, because the link is opened from a mail client
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	// the link is sent in the background, so the response takes as long
+	// whether or not the account exists
+	if user, err := h.users.GetByEmail(r.Context(), input.Email); err == nil && user != nil && !user.Disabled {
+		go h.sendLink(context.WithoutCancel(r.Context()), user, data)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "If the address belongs to an account, a sign-in link is on its way.",
+	})
+}
+
+// sendLink emails user a link carrying data. Failures are only logged, so
+// the response doesn't reveal that the account exists.
+func (h *MagicLinkHandler) sendLink(ctx context.Context, user *model.User, data map[string]string) {
+	token, _, err := auth.GeneratePurposeToken(magicLinkPurpose, strconv.Itoa(user.ID), data, MagicLinkTTL)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to create magic link", "error", err)
+		return
+	}
+
+	link := auth.Issuer() + "/auth/magic-link/callback?token=" + url.QueryEscape(token)
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Your sign-in link",
+		Body: "Hi " + user.Name + ",\n\nUse this link to sign in. It works once and expires in " +
+			strconv.Itoa(int(MagicLinkTTL.Minutes())) + " minutes:\n\n" + link +
+			"\n\nIf you didn't ask for it, you can ignore this email.\n",
+	}
+
+	if err := h.mailer.Send(ctx, msg); err != nil {
+		logging.FromContext(ctx).Error("failed to send magic link", "error", err)
+	}
+}
+
+// MagicLinkCallback godoc
+// @Summary Sign in with an emailed link
+// @Description Exchanges a sign-in link for the same token as /auth/login. Each link works once.
+// @Tags auth
+// @Produce  json
+// @Param   token  query  string  true  "Token from the emailed link"
+// @Success 200 {object} map[string]string
+// @Failure 401 {string} string "Invalid or expired link"
+// @Failure 403 {string} string "Account is disabled"
+// @Router /auth/magic-link/callback [get]
+func (h *MagicLinkHandler) MagicLinkCallback(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.ParsePurposeToken(magicLinkPurpose, r.URL.Query().Get("token"))
+	if err != nil || claims.ExpiresAt == nil {
+		http.Error(w, "Invalid or expired link", http.StatusUnauthorized)
+		return
+	}
+
+	// checked before the link is spent, so opening it in another browser
+	// doesn't burn it
+	if device := claims.Data["device"]; device != "" {
+		cookie, err := r.Cookie(magicLinkDeviceCookie)
+		if err != nil || !auth.CompareSecret(cookie.Value, device) {
+			http.Error(w, "Open the link in the browser you requested it from", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	// the link opens on the issuer's host, which may not name the organization
+	if organizationID, _ := strconv.Atoi(claims.Data["tenant"]); organizationID != 0 {
+		ctx, err := tenant.Bind(r.Context(), organizationID)
+		if err != nil {
+			http.Error(w, "Invalid or expired link", http.StatusUnauthorized)
+			return
+		}
+		r = r.WithContext(ctx)
+	}
+
+	fresh, err := h.grants.ConsumeTokenID(r.Context(), claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	if !fresh {
+		http.Error(w, "This link has already been used", http.StatusUnauthorized)
+		return
+	}
+
+	userID, _ := strconv.Atoi(claims.Subject)
+	user, err := h.users.Get(r.Context(), userID)
+	if err != nil || user == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: magicLinkDeviceCookie, Path: "/auth/magic-link", MaxAge: -1, HttpOnly: true, Secure: true})
+
+	h.login.completeLogin(w, r, user)
+}
+
+// clientIP is the address the request came from.
+func clientIP(r *http.Request) string {
+	return middleware.ClientIP(r)
+}
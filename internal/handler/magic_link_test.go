@@ -0,0 +1,156 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/handler"
+	"go-user-api/internal/mail"
+	"go-user-api/internal/model"
+	"go-user-api/internal/testutils"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestMagicLinkHandler() (*handler.MagicLinkHandler, *testutils.MockMailer) {
+	users := &testutils.MockUserRepo{Users: []*model.User{testutils.NewMockUser(7, "jane@example.com", "password1")}}
+	mailer := &testutils.MockMailer{}
+
+	return handler.NewMagicLinkHandler(users, testutils.NewMockGrantRepo(), mailer, handler.NewAuthRouteHandler(users)), mailer
+}
+
+func requestMagicLink(h *handler.MagicLinkHandler, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/auth/magic-link", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	h.RequestMagicLink(rr, req)
+	return rr
+}
+
+// openMagicLink follows the link in the last email, with optional cookies.
+func openMagicLink(t *testing.T, h *handler.MagicLinkHandler, mailer *testutils.MockMailer, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	messages := mailer.Wait(1)
+	body := messages[len(messages)-1].Body
+	start := strings.Index(body, "http")
+	link, err := url.Parse(strings.Fields(body[start:])[0])
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, link.RequestURI(), nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+
+	rr := httptest.NewRecorder()
+	h.MagicLinkCallback(rr, req)
+	return rr
+}
+
+func TestMagicLinkSignsInOnce(t *testing.T) {
+	h, mailer := newTestMagicLinkHandler()
+
+	rr := requestMagicLink(h, `{"email": "jane@example.com"}`)
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	messages := mailer.Wait(1)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "jane@example.com", messages[0].To)
+
+	rr = openMagicLink(t, h, mailer)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp map[string]string
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	userID, err := auth.DecodeJWT(resp["token"])
+	assert.NoError(t, err)
+	assert.Equal(t, 7, userID)
+
+	rr = openMagicLink(t, h, mailer)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestMagicLinkDoesNotRevealAccounts(t *testing.T) {
+	h, mailer := newTestMagicLinkHandler()
+
+	for _, bindDevice := range []string{"false", "true"} {
+		known := requestMagicLink(h, `{"email": "jane@example.com", "bind_device": `+bindDevice+`}`)
+		unknown := requestMagicLink(h, `{"email": "nobody@example.com", "bind_device": `+bindDevice+`}`)
+
+		assert.Equal(t, known.Code, unknown.Code)
+		assert.Equal(t, known.Body.String(), unknown.Body.String())
+		assert.Equal(t, slices.Sorted(maps.Keys(known.Header())), slices.Sorted(maps.Keys(unknown.Header())))
+
+		// the device cookies only differ in their random value
+		knownCookies, unknownCookies := known.Result().Cookies(), unknown.Result().Cookies()
+		assert.Len(t, unknownCookies, len(knownCookies))
+		for i := range min(len(knownCookies), len(unknownCookies)) {
+			knownCookies[i].Value, unknownCookies[i].Value = "", ""
+			assert.Equal(t, knownCookies[i].String(), unknownCookies[i].String())
+		}
+	}
+	assert.Len(t, mailer.Wait(2), 2)
+}
+
+func TestMagicLinkRateLimitsPerEmail(t *testing.T) {
+	h, mailer := newTestMagicLinkHandler()
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusAccepted, requestMagicLink(h, `{"email": "jane@example.com"}`).Code)
+	}
+
+	rr := requestMagicLink(h, `{"email": "JANE@example.com"}`)
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+	assert.Len(t, mailer.Wait(3), 3)
+}
+
+func TestMagicLinkBoundToDevice(t *testing.T) {
+	h, mailer := newTestMagicLinkHandler()
+
+	rr := requestMagicLink(h, `{"email": "jane@example.com", "bind_device": true}`)
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	cookies := rr.Result().Cookies()
+	assert.Len(t, cookies, 1)
+
+	// another browser is turned away without spending the link
+	assert.Equal(t, http.StatusUnauthorized, openMagicLink(t, h, mailer).Code)
+	assert.Equal(t, http.StatusOK, openMagicLink(t, h, mailer, cookies...).Code)
+}
+
+// blockingMailer holds every message until release is closed, and then
+// drops it if its context was canceled meanwhile.
+type blockingMailer struct {
+	testutils.MockMailer
+	release chan struct{}
+}
+
+func (m *blockingMailer) Send(ctx context.Context, msg mail.Message) error {
+	<-m.release
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.MockMailer.Send(ctx, msg)
+}
+
+func TestMagicLinkSentInBackground(t *testing.T) {
+	users := &testutils.MockUserRepo{Users: []*model.User{testutils.NewMockUser(7, "jane@example.com", "password1")}}
+	mailer := &blockingMailer{release: make(chan struct{})}
+	h := handler.NewMagicLinkHandler(users, testutils.NewMockGrantRepo(), mailer, handler.NewAuthRouteHandler(users))
+
+	// a slow mail server doesn't hold up the response, which would tell
+	// existing accounts apart, and the request ending doesn't cancel sending
+	req := httptest.NewRequest(http.MethodPost, "/auth/magic-link", strings.NewReader(`{"email": "jane@example.com"}`))
+	ctx, cancel := context.WithCancel(req.Context())
+	rr := httptest.NewRecorder()
+	h.RequestMagicLink(rr, req.WithContext(ctx))
+	cancel()
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Empty(t, mailer.Wait(0))
+
+	close(mailer.release)
+	assert.Len(t, mailer.Wait(1), 1)
+}
@@ -0,0 +1,94 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"go-user-api/internal/handler"
+	"go-user-api/internal/metrics"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/testutils"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scrape returns the value of a series of /metrics, or 0 if it is absent.
+func scrape(t *testing.T, series string) float64 {
+	rr := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	for _, line := range strings.Split(rr.Body.String(), "\n") {
+		if value, ok := strings.CutPrefix(line, series+" "); ok {
+			v, err := strconv.ParseFloat(value, 64)
+			require.NoError(t, err)
+			return v
+		}
+	}
+
+	return 0
+}
+
+func TestMetrics(t *testing.T) {
+	users := &testutils.MockUserRepo{Users: []*model.User{testutils.NewMockUser(5, "jane@example.com", "password1")}}
+	authHandler := handler.NewAuthRouteHandler(users)
+
+	r := chi.NewRouter()
+	r.Use(middleware.Metrics)
+	series := map[string]string{
+		"ok":        `http_requests_total{method="GET",route="/users/{id}",status="200"}`,
+		"unmatched": `http_requests_total{method="GET",route="unmatched",status="404"}`,
+		"other":     `http_requests_total{method="OTHER",route="unmatched",status="405"}`,
+		"failure":   `auth_logins_total{reason="invalid_password",result="failure"}`,
+		"success":   `auth_logins_total{reason="success",result="success"}`,
+		"issued":    `auth_tokens_issued_total{type="access"}`,
+		"valid":     `auth_token_validations_total{result="valid"}`,
+		"invalid":   `auth_token_validations_total{result="invalid"}`,
+		"hashes":    `auth_password_hash_duration_seconds_count{operation="compare"}`,
+	}
+	before := map[string]float64{}
+	for name, s := range series {
+		before[name] = scrape(t, s)
+	}
+
+	r.Get("/users/{id}", handler.NewUserHandler(users, &testutils.MockAuditRepo{}).GetUser)
+	r.Post("/auth/login", authHandler.Login)
+	r.With(middleware.NewJWTAuth().Middleware).Get("/auth/profile", authHandler.GetUserProfile)
+
+	login := func(password string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"email": "jane@example.com", "password": password})
+		return sendAs(r, http.MethodPost, "/auth/login", "", string(body))
+	}
+
+	assert.Equal(t, http.StatusOK, sendAs(r, http.MethodGet, "/users/5", "", "").Code)
+	assert.Equal(t, http.StatusNotFound, sendAs(r, http.MethodGet, "/nope/1", "", "").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, sendAs(r, "SCAN1", "/users/5", "", "").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, sendAs(r, "SCAN2", "/users/5", "", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, login("wrong-password").Code)
+	rr := login("password1")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp map[string]string
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, http.StatusOK, sendAs(r, http.MethodGet, "/auth/profile", resp["token"], "").Code)
+	assert.Equal(t, http.StatusUnauthorized, sendAs(r, http.MethodGet, "/auth/profile", "garbage", "").Code)
+
+	// optional parses, e.g. to pick a rate limit key, are not validations
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+resp["token"])
+	assert.NotNil(t, middleware.NewJWTAuth().Claims(req))
+
+	for name, delta := range map[string]float64{
+		"ok": 1, "unmatched": 1, "other": 2, "failure": 1, "success": 1, "issued": 1, "valid": 1, "invalid": 1, "hashes": 2,
+	} {
+		assert.Equal(t, before[name]+delta, scrape(t, series[name]), name)
+	}
+
+	assert.NotZero(t, scrape(t, `http_request_duration_seconds_count{method="POST",route="/auth/login",status="200"}`))
+}
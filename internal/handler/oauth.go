@@ -0,0 +1,303 @@
+package handler
+
+import (
+	"encoding/json"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/repository"
+	"go-user-api/internal/tenant"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// RefreshTokenTTL is how long a refresh token can be exchanged before the
+// user has to sign in again.
+const RefreshTokenTTL = 30 * 24 * time.Hour
+
+type OAuthHandler struct {
+	clients repository.ClientRepository
+	grants  repository.GrantRepository
+	users   repository.UserRepository
+	login   *AuthRouteHandler
+	checks  []middleware.TokenCheck
+}
+
+// NewOAuthHandler returns the authorization server. Its login page checks
+// passwords like login does, through the authenticator of login.
+// Introspection reports access tokens that fail one of checks as inactive,
+// so it should be given the checks of the JWTAuth guarding the API.
+func NewOAuthHandler(clients repository.ClientRepository, grants repository.GrantRepository, users repository.UserRepository,
+	login *AuthRouteHandler, checks ...middleware.TokenCheck) *OAuthHandler {
+	return &OAuthHandler{clients: clients, grants: grants, users: users, login: login, checks: checks}
+}
+
+// Token godoc
+// @Summary Issue an OAuth2 access token
+// @Description Supports the client_credentials, authorization_code and refresh_token grants. Confidential clients authenticate with HTTP Basic or client_id/client_secret form fields, public clients only send client_id.
+// @Tags oauth
+// @Accept  x-www-form-urlencoded
+// @Produce  json
+// @Param   grant_type     formData  string  true   "Grant type (client_credentials, authorization_code or refresh_token)"
+// @Param   scope          formData  string  false  "Space-delimited scopes; defaults to every scope of the client or grant"
+// @Param   code           formData  string  false  "Authorization code (authorization_code grant)"
+// @Param   redirect_uri   formData  string  false  "Redirect URI used in the authorization request (authorization_code grant)"
+// @Param   code_verifier  formData  string  false  "PKCE code verifier (authorization_code grant)"
+// @Param   refresh_token  formData  string  false  "Refresh token (refresh_token grant)"
+// @Param   client_id      formData  string  false  "Client ID when not using HTTP Basic"
+// @Param   client_secret  formData  string  false  "Client secret when not using HTTP Basic"
+// @Success 200 {object} model.TokenResponse
+// @Failure 400 {object} model.OAuthError
+// @Failure 401 {object} model.OAuthError
+// @Router /oauth/token [post]
+func (h *OAuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "malformed form body")
+		return
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case "client_credentials":
+		h.clientCredentials(w, r)
+	case "authorization_code":
+		h.authorizationCode(w, r)
+	case "refresh_token":
+		h.refreshToken(w, r)
+	case "":
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "grant_type is required")
+	default:
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "")
+	}
+}
+
+func (h *OAuthHandler) clientCredentials(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	if client.Public {
+		writeOAuthError(w, http.StatusBadRequest, "unauthorized_client", "public clients cannot use the client_credentials grant")
+		return
+	}
+
+	scopes := client.Scopes
+	if requested := auth.ParseScope(r.PostForm.Get("scope")); len(requested) > 0 {
+		if !auth.ScopesAllowed(requested, client.Scopes) {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_scope", "requested scope exceeds the scopes granted to the client")
+			return
+		}
+		scopes = requested
+	}
+
+	token, err := auth.GenerateClientToken(client.ClientID, scopes)
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "could not generate access token")
+		return
+	}
+
+	writeTokenResponse(w, model.TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(auth.ClientTokenTTL.Seconds()),
+		Scope:       auth.FormatScope(scopes),
+	})
+}
+
+func (h *OAuthHandler) authorizationCode(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	// the code is only spent once the request checks out, so a request
+	// with a wrong redirect_uri or code_verifier doesn't burn it
+	codeHash := auth.HashSecret(r.PostForm.Get("code"))
+	grant, err := h.grants.GetAuthorizationCode(r.Context(), codeHash)
+	if err != nil || grant.ClientID != client.ClientID {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "authorization code is invalid, expired or was issued to another client")
+		return
+	}
+
+	// redirect_uri must match when the authorization request named one
+	// (RFC 6749 section 4.1.3)
+	if grant.RedirectURI != "" && grant.RedirectURI != r.PostForm.Get("redirect_uri") {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "redirect_uri does not match the authorization request")
+		return
+	}
+
+	if !auth.VerifyPKCE(r.PostForm.Get("code_verifier"), grant.CodeChallenge) {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "code_verifier does not match the code challenge")
+		return
+	}
+
+	if grant, err = h.grants.ConsumeAuthorizationCode(r.Context(), codeHash); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "authorization code is invalid, expired or was issued to another client")
+		return
+	}
+
+	h.issueUserTokens(w, r, client, grant.UserID, grant.OrganizationID, auth.ParseScope(grant.Scope), grant.Nonce, grant.AuthTime)
+}
+
+func (h *OAuthHandler) refreshToken(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	tokenHash := auth.HashSecret(r.PostForm.Get("refresh_token"))
+	grant, err := h.grants.ConsumeRefreshToken(r.Context(), tokenHash)
+	if err != nil {
+		// A refresh token that was already rotated is being replayed, so it
+		// may have been stolen. Revoke everything issued to that client for
+		// the user (RFC 6819 section 5.2.2.3).
+		if old, getErr := h.grants.GetRefreshToken(r.Context(), tokenHash); getErr == nil && old.RevokedAt != nil && old.ClientID == client.ClientID {
+			h.grants.RevokeRefreshTokens(tenant.WithID(r.Context(), old.OrganizationID), old.ClientID, old.UserID)
+		}
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "refresh token is invalid, expired or revoked")
+		return
+	}
+
+	if grant.ClientID != client.ClientID {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "refresh token was issued to another client")
+		return
+	}
+
+	scopes := auth.ParseScope(grant.Scope)
+	if requested := auth.ParseScope(r.PostForm.Get("scope")); len(requested) > 0 {
+		if !auth.ScopesAllowed(requested, scopes) {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_scope", "requested scope exceeds the original grant")
+			return
+		}
+		scopes = requested
+	}
+
+	h.issueUserTokens(w, r, client, grant.UserID, grant.OrganizationID, scopes, "", grant.AuthTime)
+}
+
+// issueUserTokens responds with an access token and a fresh refresh token
+// for a user who authorized the client, plus an ID token when the openid
+// scope was granted. authTime is when the user last signed in.
+func (h *OAuthHandler) issueUserTokens(w http.ResponseWriter, r *http.Request, client *model.Client, userID int, organizationID int,
+	scopes []string, nonce string, authTime time.Time) {
+	if organizationID != 0 {
+		ctx, err := tenant.Bind(r.Context(), organizationID)
+		if err != nil {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "grant belongs to another organization")
+			return
+		}
+		r = r.WithContext(ctx)
+	}
+
+	user, err := h.users.Get(r.Context(), userID)
+	if err != nil || user == nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "user no longer exists")
+		return
+	}
+
+	if user.Disabled {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "user is disabled")
+		return
+	}
+
+	var idToken string
+	if slices.Contains(scopes, auth.ScopeOpenID) {
+		if idToken, err = auth.GenerateIDToken(user, client.ClientID, scopes, nonce, authTime); err != nil {
+			writeOAuthError(w, http.StatusInternalServerError, "server_error", "could not generate id token")
+			return
+		}
+	}
+
+	accessToken, err := auth.GenerateJWTContext(r.Context(), userID, auth.WithTenant(user.OrganizationID),
+		auth.WithClientID(client.ClientID), auth.WithScopes(scopes), auth.WithTTL(auth.AccessTokenTTL))
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "could not generate access token")
+		return
+	}
+
+	refreshToken, err := auth.GenerateSecret(32)
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "could not generate refresh token")
+		return
+	}
+
+	grant := model.RefreshToken{
+		TokenHash:      auth.HashSecret(refreshToken),
+		ClientID:       client.ClientID,
+		UserID:         userID,
+		OrganizationID: organizationID,
+		Scope:          auth.FormatScope(scopes),
+		AuthTime:       authTime,
+		ExpiresAt:      time.Now().Add(RefreshTokenTTL),
+	}
+
+	if err := h.grants.CreateRefreshToken(r.Context(), &grant); err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "could not store refresh token")
+		return
+	}
+
+	writeTokenResponse(w, model.TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(auth.AccessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+		IDToken:      idToken,
+		Scope:        auth.FormatScope(scopes),
+	})
+}
+
+// authenticateClient checks the client credentials sent with HTTP Basic
+// (RFC 6749 section 2.3.1) or in the form body. Public clients have no secret
+// and are identified by client_id alone. It writes the error response itself
+// and returns false when authentication fails.
+func (h *OAuthHandler) authenticateClient(w http.ResponseWriter, r *http.Request) (*model.Client, bool) {
+	clientID, secret, usedBasic := r.BasicAuth()
+	if usedBasic {
+		// Basic credentials are form-urlencoded before being base64 encoded
+		var err error
+		if clientID, err = url.QueryUnescape(clientID); err == nil {
+			secret, err = url.QueryUnescape(secret)
+		}
+		if err != nil {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_request", "malformed client credentials")
+			return nil, false
+		}
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+
+	if clientID != "" {
+		client, err := h.clients.GetByClientID(r.Context(), clientID)
+		if err == nil && client != nil {
+			if client.Public && secret == "" {
+				return client, true
+			}
+			if !client.Public && secret != "" && auth.CompareSecret(secret, client.SecretHash) {
+				return client, true
+			}
+		}
+	}
+
+	if usedBasic {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+	return nil, false
+}
+
+func writeTokenResponse(w http.ResponseWriter, resp any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code string, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(model.OAuthError{Error: code, ErrorDescription: description})
+}
@@ -0,0 +1,257 @@
+package handler
+
+import (
+	"embed"
+	"errors"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/authn"
+	"go-user-api/internal/logging"
+	"go-user-api/internal/model"
+	"html/template"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// AuthorizationCodeTTL is how long an issued authorization code stays valid.
+const AuthorizationCodeTTL = 10 * time.Minute
+
+//go:embed templates/authorize.html
+var templateFS embed.FS
+
+var authorizeTemplate = template.Must(template.ParseFS(templateFS, "templates/authorize.html"))
+
+// authorizeRequest is a validated authorization request (RFC 6749 section 4.1.1).
+type authorizeRequest struct {
+	client      *model.Client
+	redirectURI string
+	// requestedRedirectURI is the redirect_uri the client sent, which may
+	// be empty when it has a single one registered
+	requestedRedirectURI string
+	scopes               []string
+	state                string
+	nonce                string
+	codeChallenge        string
+}
+
+// params returns the request parameters to carry through the login form.
+func (a *authorizeRequest) params() map[string]string {
+	return map[string]string{
+		"response_type":         "code",
+		"client_id":             a.client.ClientID,
+		"redirect_uri":          a.requestedRedirectURI,
+		"scope":                 auth.FormatScope(a.scopes),
+		"state":                 a.state,
+		"nonce":                 a.nonce,
+		"code_challenge":        a.codeChallenge,
+		"code_challenge_method": auth.PKCEMethodS256,
+	}
+}
+
+type authorizePage struct {
+	Fatal      bool
+	Error      string
+	ClientName string
+	Scopes     []string
+	Params     map[string]string
+	Email      string
+}
+
+// Authorize godoc
+// @Summary OAuth2 authorization endpoint
+// @Description Starts the authorization code grant and renders the login and consent page. PKCE with S256 is mandatory.
+// @Tags oauth
+// @Produce  html
+// @Param   response_type          query  string  true   "Must be code"
+// @Param   client_id              query  string  true   "Client ID"
+// @Param   redirect_uri           query  string  false  "Registered redirect URI; optional when the client has exactly one"
+// @Param   scope                  query  string  false  "Space-delimited scopes"
+// @Param   state                  query  string  false  "Opaque value returned to the client"
+// @Param   nonce                  query  string  false  "OpenID Connect nonce echoed in the ID token"
+// @Param   code_challenge         query  string  true   "PKCE code challenge"
+// @Param   code_challenge_method  query  string  true   "Must be S256"
+// @Success 200 {string} string "Login and consent page"
+// @Failure 302 {string} string "Redirect to the client with an error"
+// @Failure 400 {string} string "Invalid client or redirect URI"
+// @Router /oauth/authorize [get]
+func (h *OAuthHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.parseAuthorizeRequest(w, r, r.URL.Query())
+	if !ok {
+		return
+	}
+
+	renderAuthorizePage(w, http.StatusOK, authorizePage{
+		ClientName: req.client.Name,
+		Scopes:     req.scopes,
+		Params:     req.params(),
+	})
+}
+
+// AuthorizeSubmit godoc
+// @Summary Submit the OAuth2 login and consent form
+// @Description Authenticates the user and, if they allow access, redirects to the client with an authorization code.
+// @Tags oauth
+// @Accept  x-www-form-urlencoded
+// @Produce  html
+// @Param   email     formData  string  true  "User email"
+// @Param   password  formData  string  true  "User password"
+// @Param   action    formData  string  true  "allow or deny"
+// @Success 302 {string} string "Redirect to the client with a code"
+// @Failure 400 {string} string "Invalid client or redirect URI"
+// @Failure 401 {string} string "Invalid credentials"
+// @Failure 403 {string} string "Account is disabled"
+// @Failure 429 {object} model.Problem "Too many requests"
+// @Failure 503 {string} string "Authentication service unavailable"
+// @Router /oauth/authorize [post]
+func (h *OAuthHandler) AuthorizeSubmit(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		renderAuthorizePage(w, http.StatusBadRequest, authorizePage{Fatal: true, Error: "Malformed request."})
+		return
+	}
+
+	req, ok := h.parseAuthorizeRequest(w, r, r.PostForm)
+	if !ok {
+		return
+	}
+
+	if r.PostForm.Get("action") != "allow" {
+		redirectWithParams(w, r, req.redirectURI, url.Values{"error": {"access_denied"}}, req.state)
+		return
+	}
+
+	email := r.PostForm.Get("email")
+	user, err := h.login.authenticatePassword(r, email, r.PostForm.Get("password"))
+	if err != nil {
+		status, message := http.StatusServiceUnavailable, "Sign-in is unavailable, please try again later."
+		switch {
+		case errors.Is(err, authn.ErrUserNotFound), errors.Is(err, authn.ErrInvalidPassword):
+			status, message = http.StatusUnauthorized, "Invalid email or password."
+		case errors.Is(err, errAccountDisabled):
+			status, message = http.StatusForbidden, "This account is disabled."
+		default:
+			logging.FromContext(r.Context()).Error("login failed", "error", err)
+		}
+
+		renderAuthorizePage(w, status, authorizePage{
+			Error:      message,
+			ClientName: req.client.Name,
+			Scopes:     req.scopes,
+			Params:     req.params(),
+			Email:      email,
+		})
+		return
+	}
+
+	code, err := auth.GenerateSecret(32)
+	if err != nil {
+		redirectWithParams(w, r, req.redirectURI, url.Values{"error": {"server_error"}}, req.state)
+		return
+	}
+
+	grant := model.AuthorizationCode{
+		CodeHash:       auth.HashSecret(code),
+		ClientID:       req.client.ClientID,
+		UserID:         user.ID,
+		OrganizationID: user.OrganizationID,
+		RedirectURI:    req.requestedRedirectURI,
+		Scope:          auth.FormatScope(req.scopes),
+		CodeChallenge:  req.codeChallenge,
+		Nonce:          req.nonce,
+		AuthTime:       time.Now(),
+		ExpiresAt:      time.Now().Add(AuthorizationCodeTTL),
+	}
+
+	if err := h.grants.CreateAuthorizationCode(r.Context(), &grant); err != nil {
+		redirectWithParams(w, r, req.redirectURI, url.Values{"error": {"server_error"}}, req.state)
+		return
+	}
+
+	h.login.recordLoginSuccess(r, user)
+	redirectWithParams(w, r, req.redirectURI, url.Values{"code": {code}}, req.state)
+}
+
+// parseAuthorizeRequest validates an authorization request. Problems with the
+// client or redirect URI are shown to the user, because redirecting to an
+// unverified URI would make this an open redirector. Every other problem is
+// reported back to the client on its redirect URI. It writes the response
+// itself and returns false when the request is invalid.
+func (h *OAuthHandler) parseAuthorizeRequest(w http.ResponseWriter, r *http.Request, params url.Values) (*authorizeRequest, bool) {
+	client, err := h.clients.GetByClientID(r.Context(), params.Get("client_id"))
+	if err != nil || client == nil {
+		renderAuthorizePage(w, http.StatusBadRequest, authorizePage{Fatal: true, Error: "Unknown client."})
+		return nil, false
+	}
+
+	redirectURI := params.Get("redirect_uri")
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+
+	if !slices.Contains(client.RedirectURIs, redirectURI) {
+		renderAuthorizePage(w, http.StatusBadRequest, authorizePage{Fatal: true, Error: "The redirect URI is not registered for this client."})
+		return nil, false
+	}
+
+	req := &authorizeRequest{
+		client:               client,
+		redirectURI:          redirectURI,
+		requestedRedirectURI: params.Get("redirect_uri"),
+		scopes:               client.Scopes,
+		state:                params.Get("state"),
+		nonce:                params.Get("nonce"),
+		codeChallenge:        params.Get("code_challenge"),
+	}
+
+	fail := func(code string, description string) (*authorizeRequest, bool) {
+		redirectWithParams(w, r, redirectURI, url.Values{"error": {code}, "error_description": {description}}, req.state)
+		return nil, false
+	}
+
+	if params.Get("response_type") != "code" {
+		return fail("unsupported_response_type", "only the code response type is supported")
+	}
+
+	if req.codeChallenge == "" || params.Get("code_challenge_method") != auth.PKCEMethodS256 {
+		return fail("invalid_request", "PKCE with the S256 code challenge method is required")
+	}
+
+	if requested := auth.ParseScope(params.Get("scope")); len(requested) > 0 {
+		if !auth.ScopesAllowed(requested, client.Scopes) {
+			return fail("invalid_scope", "requested scope exceeds the scopes granted to the client")
+		}
+		req.scopes = requested
+	}
+
+	return req, true
+}
+
+func redirectWithParams(w http.ResponseWriter, r *http.Request, redirectURI string, params url.Values, state string) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, "Invalid redirect URI", http.StatusBadRequest)
+		return
+	}
+
+	if state != "" {
+		params.Set("state", state)
+	}
+
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+func renderAuthorizePage(w http.ResponseWriter, status int, page authorizePage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	// the consent page must never be framed, or it could be clickjacked
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	w.WriteHeader(status)
+	authorizeTemplate.Execute(w, page)
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"context"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/model"
+	"go-user-api/internal/tenant"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Introspect godoc
+// @Summary OAuth2 token introspection (RFC 7662)
+// @Description Reports whether an access token (JWT) or refresh token (opaque) is active. Tokens that were revoked, whose session ended or whose user is disabled are inactive. Callers authenticate with confidential client credentials.
+// @Tags oauth
+// @Accept  x-www-form-urlencoded
+// @Produce  json
+// @Param   token            formData  string  true   "Token to introspect"
+// @Param   token_type_hint  formData  string  false  "access_token or refresh_token"
+// @Success 200 {object} model.IntrospectionResponse
+// @Failure 401 {object} model.OAuthError
+// @Router /oauth/introspect [post]
+func (h *OAuthHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "malformed form body")
+		return
+	}
+
+	client, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	if client.Public {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "public clients cannot introspect tokens")
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "token is required")
+		return
+	}
+
+	resp := model.IntrospectionResponse{Active: false}
+
+	// the hint only decides which lookup runs first
+	lookups := []func(context.Context, string) (*model.IntrospectionResponse, bool){h.introspectAccessToken, h.introspectRefreshToken}
+	if r.PostForm.Get("token_type_hint") == "refresh_token" {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+
+	for _, lookup := range lookups {
+		if found, ok := lookup(r.Context(), token); ok {
+			resp = *found
+			break
+		}
+	}
+
+	writeTokenResponse(w, resp)
+}
+
+func (h *OAuthHandler) introspectAccessToken(ctx context.Context, token string) (*model.IntrospectionResponse, bool) {
+	claims, err := auth.ParseTokenContext(ctx, token)
+	if err != nil {
+		return nil, false
+	}
+
+	// the token must be as good as it is to the API: not revoked, its
+	// session not ended and its user not disabled
+	for _, check := range h.checks {
+		if err := check(ctx, claims); err != nil {
+			return &model.IntrospectionResponse{Active: false}, true
+		}
+	}
+
+	resp := &model.IntrospectionResponse{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		TokenType: "Bearer",
+		Sub:       claims.Subject,
+		Iss:       auth.Issuer(),
+		Jti:       claims.ID,
+	}
+
+	if claims.ExpiresAt != nil {
+		resp.Exp = claims.ExpiresAt.Unix()
+	}
+
+	if claims.IssuedAt != nil {
+		resp.Iat = claims.IssuedAt.Unix()
+	}
+
+	return resp, true
+}
+
+func (h *OAuthHandler) introspectRefreshToken(ctx context.Context, token string) (*model.IntrospectionResponse, bool) {
+	grant, err := h.grants.GetRefreshToken(ctx, auth.HashSecret(token))
+	if err != nil {
+		return nil, false
+	}
+
+	if grant.RevokedAt != nil || time.Now().After(grant.ExpiresAt) {
+		return &model.IntrospectionResponse{Active: false}, true
+	}
+
+	// the token endpoint refuses to refresh for a disabled or deleted user
+	if grant.OrganizationID != 0 {
+		ctx = tenant.WithID(ctx, grant.OrganizationID)
+	}
+	if user, err := h.users.Get(ctx, grant.UserID); err != nil || user == nil || user.Disabled {
+		return &model.IntrospectionResponse{Active: false}, true
+	}
+
+	return &model.IntrospectionResponse{
+		Active:    true,
+		Scope:     grant.Scope,
+		ClientID:  grant.ClientID,
+		TokenType: "refresh_token",
+		Exp:       grant.ExpiresAt.Unix(),
+		Iat:       grant.CreatedAt.Unix(),
+		Sub:       strconv.Itoa(grant.UserID),
+		Iss:       auth.Issuer(),
+	}, true
+}
+
+// Revoke godoc
+// @Summary OAuth2 token revocation (RFC 7009)
+// @Description Revokes an access or refresh token issued to the calling client. Unknown or already invalid tokens are not an error.
+// @Tags oauth
+// @Accept  x-www-form-urlencoded
+// @Produce  json
+// @Param   token            formData  string  true   "Token to revoke"
+// @Param   token_type_hint  formData  string  false  "access_token or refresh_token"
+// @Success 200
+// @Failure 401 {object} model.OAuthError
+// @Router /oauth/revoke [post]
+func (h *OAuthHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "malformed form body")
+		return
+	}
+
+	client, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "token is required")
+		return
+	}
+
+	// clients may only revoke their own tokens; anything else is silently ignored
+	if claims, err := auth.ParseTokenContext(r.Context(), token); err == nil {
+		if claims.ClientID == client.ClientID && claims.ID != "" && claims.ExpiresAt != nil {
+			if err := h.grants.RevokeAccessToken(r.Context(), claims.ID, claims.ExpiresAt.Time); err != nil {
+				writeOAuthError(w, http.StatusServiceUnavailable, "temporarily_unavailable", "could not revoke token")
+				return
+			}
+		}
+	} else if grant, err := h.grants.GetRefreshToken(r.Context(), auth.HashSecret(token)); err == nil && grant.ClientID == client.ClientID {
+		if err := h.grants.RevokeRefreshToken(r.Context(), grant.TokenHash); err != nil {
+			writeOAuthError(w, http.StatusServiceUnavailable, "temporarily_unavailable", "could not revoke token")
+			return
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+}
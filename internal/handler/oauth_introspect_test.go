@@ -0,0 +1,130 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/testutils"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func postOAuthForm(endpoint http.HandlerFunc, form url.Values, basicUser, basicPass string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if basicUser != "" {
+		req.SetBasicAuth(basicUser, basicPass)
+	}
+
+	rr := httptest.NewRecorder()
+	endpoint(rr, req)
+	return rr
+}
+
+func introspect(t *testing.T, h *handler.OAuthHandler, token string) model.IntrospectionResponse {
+	rr := postOAuthForm(h.Introspect, url.Values{"token": {token}}, "billing", "s3cret")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp model.IntrospectionResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	return resp
+}
+
+func TestIntrospectAndRevokeAccessToken(t *testing.T) {
+	h, _ := newTestOAuthHandler()
+
+	rr := postTokenForm(h, url.Values{"grant_type": {"client_credentials"}}, "billing", "s3cret")
+	var token model.TokenResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&token))
+
+	resp := introspect(t, h, token.AccessToken)
+	assert.True(t, resp.Active)
+	assert.Equal(t, "billing", resp.ClientID)
+	assert.Equal(t, "users:read users:write", resp.Scope)
+	assert.NotZero(t, resp.Exp)
+
+	rr = postOAuthForm(h.Revoke, url.Values{"token": {token.AccessToken}}, "billing", "s3cret")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	resp = introspect(t, h, token.AccessToken)
+	assert.False(t, resp.Active)
+	assert.Empty(t, resp.ClientID)
+}
+
+func TestIntrospectAndRevokeRefreshToken(t *testing.T) {
+	h, grants := newTestOAuthHandler()
+	grants.CreateRefreshToken(context.Background(), &model.RefreshToken{
+		TokenHash: auth.HashSecret("refresh-1"),
+		ClientID:  "spa",
+		UserID:    7,
+		Scope:     "profile",
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+
+	resp := introspect(t, h, "refresh-1")
+	assert.True(t, resp.Active)
+	assert.Equal(t, "7", resp.Sub)
+	assert.Equal(t, "refresh_token", resp.TokenType)
+
+	// another client cannot revoke it
+	postOAuthForm(h.Revoke, url.Values{"token": {"refresh-1"}}, "billing", "s3cret")
+	assert.True(t, introspect(t, h, "refresh-1").Active)
+
+	rr := postOAuthForm(h.Revoke, url.Values{"token": {"refresh-1"}, "client_id": {"spa"}}, "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.False(t, introspect(t, h, "refresh-1").Active)
+
+	// unknown tokens are inactive, not an error
+	assert.False(t, introspect(t, h, "garbage").Active)
+}
+
+func TestIntrospectRequiresConfidentialClient(t *testing.T) {
+	h, _ := newTestOAuthHandler()
+
+	rr := postOAuthForm(h.Introspect, url.Values{"token": {"x"}, "client_id": {"spa"}}, "", "")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	rr = postOAuthForm(h.Introspect, url.Values{"token": {"x"}}, "billing", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestIntrospectAppliesTokenChecks(t *testing.T) {
+	users := &testutils.MockUserRepo{Users: []*model.User{testutils.NewMockUser(7, "jane@example.com", "password1")}}
+	sessions := &testutils.MockSessionRepo{}
+	grants := testutils.NewMockGrantRepo()
+	h := handler.NewOAuthHandler(newTestClientRepo(), grants, users, handler.NewAuthRouteHandler(users),
+		middleware.SessionCheck(sessions), middleware.RevocationCheck(grants), middleware.ActiveUserCheck(users))
+
+	session := &model.Session{UserID: 7, ExpiresAt: time.Now().Add(time.Hour)}
+	assert.NoError(t, sessions.Create(context.Background(), session))
+	access, _ := auth.GenerateJWT(7, auth.WithSessionID(session.ID))
+	grants.CreateRefreshToken(context.Background(), &model.RefreshToken{
+		TokenHash: auth.HashSecret("refresh-1"),
+		ClientID:  "spa",
+		UserID:    7,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+
+	assert.True(t, introspect(t, h, access).Active)
+	assert.True(t, introspect(t, h, "refresh-1").Active)
+
+	// a token whose session was signed out is as dead as it is to the API
+	assert.NoError(t, sessions.Revoke(context.Background(), session.ID, 7))
+	assert.False(t, introspect(t, h, access).Active)
+
+	// so are the tokens of a disabled user
+	other, _ := auth.GenerateJWT(7)
+	assert.True(t, introspect(t, h, other).Active)
+	users.Users[0].Disabled = true
+	assert.False(t, introspect(t, h, other).Active)
+	assert.False(t, introspect(t, h, "refresh-1").Active)
+}
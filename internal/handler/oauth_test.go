@@ -0,0 +1,425 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/authn"
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/ratelimit"
+	"go-user-api/internal/routes"
+	"go-user-api/internal/testutils"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClientRepo() *testutils.MockClientRepo {
+	return testutils.NewMockClientRepo(&model.Client{
+		ID:         1,
+		ClientID:   "billing",
+		Name:       "Billing Service",
+		SecretHash: auth.HashSecret("s3cret"),
+		Scopes:     []string{"users:read", "users:write"},
+	}, &model.Client{
+		ID:           2,
+		ClientID:     "spa",
+		Name:         "Web App",
+		Scopes:       []string{"openid", "profile", "email", "users:read"},
+		RedirectURIs: []string{"https://app.example.com/callback"},
+		Public:       true,
+	})
+}
+
+func newTestOAuthHandler() (*handler.OAuthHandler, *testutils.MockGrantRepo) {
+	grants := testutils.NewMockGrantRepo()
+	users := &testutils.MockUserRepo{Users: []*model.User{testutils.NewMockUser(7, "jane@example.com", "password1")}}
+
+	return handler.NewOAuthHandler(newTestClientRepo(), grants, users, handler.NewAuthRouteHandler(users),
+		middleware.RevocationCheck(grants), middleware.ActiveUserCheck(users)), grants
+}
+
+func postTokenForm(h *handler.OAuthHandler, form url.Values, basicUser, basicPass string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if basicUser != "" {
+		req.SetBasicAuth(basicUser, basicPass)
+	}
+
+	rr := httptest.NewRecorder()
+	h.Token(rr, req)
+	return rr
+}
+
+func TestClientCredentialsGrant(t *testing.T) {
+	h, _ := newTestOAuthHandler()
+
+	rr := postTokenForm(h, url.Values{"grant_type": {"client_credentials"}, "scope": {"users:read"}}, "billing", "s3cret")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+
+	var resp model.TokenResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, "Bearer", resp.TokenType)
+	assert.Equal(t, "users:read", resp.Scope)
+
+	claims, err := auth.ParseToken(resp.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "billing", claims.ClientID)
+	assert.True(t, claims.HasScope("users:read"))
+	assert.False(t, claims.HasScope("users:write"))
+
+	// client tokens must not be accepted where a user is expected
+	_, err = auth.DecodeJWT(resp.AccessToken)
+	assert.Error(t, err)
+}
+
+func TestClientCredentialsGrantErrors(t *testing.T) {
+	h, _ := newTestOAuthHandler()
+
+	tests := []struct {
+		name   string
+		form   url.Values
+		user   string
+		pass   string
+		status int
+		code   string
+	}{
+		{"wrong secret", url.Values{"grant_type": {"client_credentials"}}, "billing", "nope", http.StatusUnauthorized, "invalid_client"},
+		{"unknown client in body", url.Values{"grant_type": {"client_credentials"}, "client_id": {"x"}, "client_secret": {"y"}}, "", "", http.StatusUnauthorized, "invalid_client"},
+		{"scope not granted", url.Values{"grant_type": {"client_credentials"}, "scope": {"admin"}}, "billing", "s3cret", http.StatusBadRequest, "invalid_scope"},
+		{"unsupported grant", url.Values{"grant_type": {"password"}}, "billing", "s3cret", http.StatusBadRequest, "unsupported_grant_type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := postTokenForm(h, tt.form, tt.user, tt.pass)
+			assert.Equal(t, tt.status, rr.Code)
+
+			var resp model.OAuthError
+			assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+			assert.Equal(t, tt.code, resp.Error)
+		})
+	}
+}
+
+const testCodeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+const testCodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+func authorizeParams() url.Values {
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {"spa"},
+		"redirect_uri":          {"https://app.example.com/callback"},
+		"scope":                 {"profile"},
+		"state":                 {"xyz"},
+		"code_challenge":        {testCodeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+}
+
+func TestAuthorizationCodeWithPKCE(t *testing.T) {
+	h, _ := newTestOAuthHandler()
+
+	// the login and consent page is rendered for a valid request
+	req := httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+authorizeParams().Encode(), nil)
+	rr := httptest.NewRecorder()
+	h.Authorize(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Web App")
+	assert.Equal(t, "DENY", rr.Header().Get("X-Frame-Options"))
+
+	// submitting valid credentials redirects back with a code
+	form := authorizeParams()
+	form.Set("email", "jane@example.com")
+	form.Set("password", "password1")
+	form.Set("action", "allow")
+	req = httptest.NewRequest(http.MethodPost, "/oauth/authorize", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = httptest.NewRecorder()
+	h.AuthorizeSubmit(rr, req)
+	assert.Equal(t, http.StatusFound, rr.Code)
+
+	location, err := url.Parse(rr.Header().Get("Location"))
+	assert.NoError(t, err)
+	assert.Equal(t, "app.example.com", location.Host)
+	assert.Equal(t, "xyz", location.Query().Get("state"))
+	code := location.Query().Get("code")
+	assert.NotEmpty(t, code)
+
+	exchange := url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {"spa"},
+		"code":          {code},
+		"redirect_uri":  {"https://app.example.com/callback"},
+		"code_verifier": {testCodeVerifier},
+	}
+
+	rr = postTokenForm(h, exchange, "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp model.TokenResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, "profile", resp.Scope)
+	assert.NotEmpty(t, resp.RefreshToken)
+
+	claims, err := auth.ParseToken(resp.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, claims.UserID)
+	assert.Equal(t, "spa", claims.ClientID)
+
+	// codes are single use
+	rr = postTokenForm(h, exchange, "", "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestAuthorizeRejectsUnregisteredRedirectURI(t *testing.T) {
+	h, _ := newTestOAuthHandler()
+
+	params := authorizeParams()
+	params.Set("redirect_uri", "https://evil.example.com/callback")
+	req := httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+params.Encode(), nil)
+	rr := httptest.NewRecorder()
+	h.Authorize(rr, req)
+
+	// never redirect to an unregistered URI
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Empty(t, rr.Header().Get("Location"))
+
+	params = authorizeParams()
+	params.Del("code_challenge")
+	req = httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+params.Encode(), nil)
+	rr = httptest.NewRecorder()
+	h.Authorize(rr, req)
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	location, _ := url.Parse(rr.Header().Get("Location"))
+	assert.Equal(t, "invalid_request", location.Query().Get("error"))
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	h, grants := newTestOAuthHandler()
+	grants.CreateRefreshToken(context.Background(), &model.RefreshToken{
+		TokenHash: auth.HashSecret("refresh-1"),
+		ClientID:  "spa",
+		UserID:    7,
+		Scope:     "profile users:read",
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+
+	form := url.Values{"grant_type": {"refresh_token"}, "client_id": {"spa"}, "refresh_token": {"refresh-1"}, "scope": {"profile"}}
+	rr := postTokenForm(h, form, "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp model.TokenResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, "profile", resp.Scope)
+	assert.NotEqual(t, "refresh-1", resp.RefreshToken)
+
+	// replaying the rotated token fails and revokes the new one too
+	rr = postTokenForm(h, form, "", "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.NotNil(t, grants.RefreshTokens[auth.HashSecret(resp.RefreshToken)].RevokedAt)
+}
+
+// authorizeCode signs jane in on the authorization page and returns the code.
+func authorizeCode(t *testing.T, h *handler.OAuthHandler, params url.Values) string {
+	params.Set("email", "jane@example.com")
+	params.Set("password", "password1")
+	params.Set("action", "allow")
+	req := httptest.NewRequest(http.MethodPost, "/oauth/authorize", strings.NewReader(params.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	h.AuthorizeSubmit(rr, req)
+	require.Equal(t, http.StatusFound, rr.Code)
+
+	location, err := url.Parse(rr.Header().Get("Location"))
+	require.NoError(t, err)
+	require.NotEmpty(t, location.Query().Get("code"))
+	return location.Query().Get("code")
+}
+
+func TestAuthorizationCodeRedirectURI(t *testing.T) {
+	h, _ := newTestOAuthHandler()
+
+	// a client with one redirect URI may leave it out of both requests
+	params := authorizeParams()
+	params.Del("redirect_uri")
+	code := authorizeCode(t, h, params)
+
+	exchange := url.Values{"grant_type": {"authorization_code"}, "client_id": {"spa"}, "code": {code}, "code_verifier": {testCodeVerifier}}
+	assert.Equal(t, http.StatusOK, postTokenForm(h, exchange, "", "").Code)
+
+	// one that named it must repeat it, and a bad request doesn't burn the code
+	code = authorizeCode(t, h, authorizeParams())
+	exchange.Set("code", code)
+	assert.Equal(t, http.StatusBadRequest, postTokenForm(h, exchange, "", "").Code)
+
+	exchange.Set("redirect_uri", "https://app.example.com/callback")
+	exchange.Set("code_verifier", "wrong-verifier-wrong-verifier-wrong-verifier")
+	assert.Equal(t, http.StatusBadRequest, postTokenForm(h, exchange, "", "").Code)
+
+	exchange.Set("code_verifier", testCodeVerifier)
+	assert.Equal(t, http.StatusOK, postTokenForm(h, exchange, "", "").Code)
+}
+
+func TestOAuthAccessTokenIsNotASession(t *testing.T) {
+	h, _ := newTestOAuthHandler()
+	admin := testutils.NewMockUser(7, "jane@example.com", "password1")
+	admin.Role = model.RoleAdmin
+	users := &testutils.MockUserRepo{Users: []*model.User{admin}}
+
+	params := authorizeParams()
+	params.Set("scope", "openid profile")
+	code := authorizeCode(t, h, params)
+	rr := postTokenForm(h, url.Values{
+		"grant_type": {"authorization_code"}, "client_id": {"spa"}, "code": {code},
+		"redirect_uri": {"https://app.example.com/callback"}, "code_verifier": {testCodeVerifier},
+	}, "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp model.TokenResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+
+	r := chi.NewRouter()
+	routes.RegisterClientRoutes(r, handler.NewClientHandler(newTestClientRepo()), middleware.NewJWTAuth(), users)
+	routes.RegisterOIDCRoutes(r, handler.NewOIDCHandler(users), middleware.NewJWTAuth())
+
+	rr = sendAs(r, http.MethodGet, "/admin/clients", resp.AccessToken, "")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Contains(t, rr.Header().Get("WWW-Authenticate"), "insufficient_scope")
+
+	// userinfo is what the client was granted
+	assert.Equal(t, http.StatusOK, sendAs(r, http.MethodGet, "/userinfo", resp.AccessToken, "").Code)
+
+	session, _ := auth.GenerateJWT(7)
+	assert.Equal(t, http.StatusOK, sendAs(r, http.MethodGet, "/admin/clients", session, "").Code)
+}
+
+// directoryAuthenticator stands in for a directory that knows users the
+// local password check doesn't.
+type directoryAuthenticator struct{ user *model.User }
+
+func (d directoryAuthenticator) Authenticate(_ context.Context, email string, password string) (*model.User, error) {
+	if email != d.user.Email {
+		return nil, authn.ErrUserNotFound
+	}
+	if password != "directory-password" {
+		return nil, authn.ErrInvalidPassword
+	}
+	return d.user, nil
+}
+
+func TestAuthorizeUsesAuthenticator(t *testing.T) {
+	john := testutils.NewMockUser(8, "john@example.com", "")
+	users := &testutils.MockUserRepo{Users: []*model.User{john}}
+	history := &testutils.MockLoginAttemptRepo{}
+
+	submit := func(login *handler.AuthRouteHandler, password string) *httptest.ResponseRecorder {
+		form := authorizeParams()
+		form.Set("email", "john@example.com")
+		form.Set("password", password)
+		form.Set("action", "allow")
+		req := httptest.NewRequest(http.MethodPost, "/oauth/authorize", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		handler.NewOAuthHandler(newTestClientRepo(), testutils.NewMockGrantRepo(), users, login).AuthorizeSubmit(rr, req)
+		return rr
+	}
+
+	login := handler.NewAuthRouteHandler(users, handler.WithAuthenticator(directoryAuthenticator{john}), handler.WithLoginHistory(history, nil))
+	assert.Equal(t, http.StatusUnauthorized, submit(login, "wrong").Code)
+	rr := submit(login, "directory-password")
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Contains(t, rr.Header().Get("Location"), "code=")
+
+	require.Len(t, history.Attempts, 2)
+	assert.Equal(t, model.LoginInvalidPassword, history.Attempts[0].Outcome)
+	assert.Equal(t, model.LoginSuccess, history.Attempts[1].Outcome)
+	assert.Equal(t, 8, history.Attempts[1].UserID)
+
+	unavailable := handler.NewAuthRouteHandler(users, handler.WithAuthenticator(failingAuthenticator{errors.New("ldap: dial: connection refused")}))
+	assert.Equal(t, http.StatusServiceUnavailable, submit(unavailable, "directory-password").Code)
+
+	john.Disabled = true
+	assert.Equal(t, http.StatusForbidden, submit(login, "directory-password").Code)
+}
+
+func TestAuthorizeSubmitRateLimited(t *testing.T) {
+	h, _ := newTestOAuthHandler()
+	limit := middleware.RateLimit(ratelimit.NewMemoryStore(), middleware.RateLimitPolicy{
+		Name: "auth",
+		Rate: ratelimit.Rate{Limit: 1, Period: time.Minute},
+		Key:  middleware.KeyByIP,
+	})
+	r := chi.NewRouter()
+	routes.RegisterOAuthRoutes(r, h, limit)
+
+	submit := func() int {
+		form := authorizeParams()
+		form.Set("email", "jane@example.com")
+		form.Set("password", "wrong")
+		form.Set("action", "allow")
+		req := httptest.NewRequest(http.MethodPost, "/oauth/authorize", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// the login form guesses passwords like login does, so it is limited too
+	assert.Equal(t, http.StatusUnauthorized, submit())
+	assert.Equal(t, http.StatusTooManyRequests, submit())
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+authorizeParams().Encode(), nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestClientRedirectURIs(t *testing.T) {
+	admin := testutils.NewMockUser(7, "jane@example.com", "password1")
+	admin.Role = model.RoleAdmin
+	users := &testutils.MockUserRepo{Users: []*model.User{admin}}
+	r := chi.NewRouter()
+	routes.RegisterClientRoutes(r, handler.NewClientHandler(newTestClientRepo()), middleware.NewJWTAuth(), users)
+	session, _ := auth.GenerateJWT(7)
+
+	create := func(uri string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(model.ClientInput{Name: "Native App", Public: true, RedirectURIs: []string{uri}})
+		return sendAs(r, http.MethodPost, "/admin/clients", session, string(body))
+	}
+
+	for _, uri := range []string{
+		"https://app.example.com/callback",
+		"http://127.0.0.1:8400/callback",
+		"http://localhost/callback",
+		"com.example.app:/oauth/callback",
+	} {
+		assert.Equal(t, http.StatusCreated, create(uri).Code, uri)
+	}
+
+	for _, uri := range []string{
+		"javascript:alert(document.cookie)",
+		"data:text/html,<script>alert(1)</script>",
+		"com.example.app:callback",
+		"myapp:/callback",
+		"https:///callback",
+		"http://app.example.com/callback",
+		"https://app.example.com/callback#fragment",
+		"/callback",
+	} {
+		rr := create(uri)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, uri)
+		assert.True(t, strings.HasPrefix(rr.Body.String(), "validation error: "), uri)
+	}
+}
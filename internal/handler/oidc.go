@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"encoding/json"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/repository"
+	"net/http"
+)
+
+type OIDCHandler struct {
+	users repository.UserRepository
+}
+
+func NewOIDCHandler(users repository.UserRepository) *OIDCHandler {
+	return &OIDCHandler{users: users}
+}
+
+// DiscoveryDocument is the OpenID Provider metadata (OpenID Connect Discovery 1.0 section 3).
+type DiscoveryDocument struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JwksURI                           string   `json:"jwks_uri"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// Discovery godoc
+// @Summary OpenID Connect discovery document
+// @Tags oidc
+// @Produce  json
+// @Success 200 {object} handler.DiscoveryDocument
+// @Router /.well-known/openid-configuration [get]
+func (h *OIDCHandler) Discovery(w http.ResponseWriter, r *http.Request) {
+	issuer := auth.Issuer()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DiscoveryDocument{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		UserinfoEndpoint:                  issuer + "/userinfo",
+		JwksURI:                           issuer + "/.well-known/jwks.json",
+		IntrospectionEndpoint:             issuer + "/oauth/introspect",
+		RevocationEndpoint:                issuer + "/oauth/revoke",
+		ScopesSupported:                   []string{auth.ScopeOpenID, auth.ScopeProfile, auth.ScopeEmail},
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code", "refresh_token", "client_credentials"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{"RS256"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{auth.PKCEMethodS256},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "name", "email", "email_verified"},
+	})
+}
+
+// JWKS godoc
+// @Summary JSON Web Key Set used to verify ID tokens
+// @Tags oidc
+// @Produce  json
+// @Success 200 {object} auth.JSONWebKeySet
+// @Failure 500 {string} string "Signing key unavailable"
+// @Router /.well-known/jwks.json [get]
+func (h *OIDCHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	keys, err := auth.JWKS()
+	if err != nil {
+		http.Error(w, "Signing key unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// UserInfo godoc
+// @Summary OpenID Connect userinfo endpoint
+// @Description Returns the claims about the user that the access token's scopes allow. Requires the openid scope.
+// @Tags oidc
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "insufficient_scope"
+// @Router /userinfo [get]
+func (h *OIDCHandler) UserInfo(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok || !claims.HasScope(auth.ScopeOpenID) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+		http.Error(w, "insufficient_scope", http.StatusForbidden)
+		return
+	}
+
+	user, err := h.users.Get(r.Context(), claims.UserID)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(auth.UserInfoClaims(user, auth.ParseScope(claims.Scope)))
+}
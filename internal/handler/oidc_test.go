@@ -0,0 +1,100 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/testutils"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// authorizeAndExchange runs the authorization code flow for jane@example.com
+// and returns the token response.
+func authorizeAndExchange(t *testing.T, h *handler.OAuthHandler, params url.Values) model.TokenResponse {
+	form := params
+	form.Set("email", "jane@example.com")
+	form.Set("password", "password1")
+	form.Set("action", "allow")
+	req := httptest.NewRequest(http.MethodPost, "/oauth/authorize", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	h.AuthorizeSubmit(rr, req)
+	assert.Equal(t, http.StatusFound, rr.Code)
+
+	location, _ := url.Parse(rr.Header().Get("Location"))
+	rr = postTokenForm(h, url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {"spa"},
+		"code":          {location.Query().Get("code")},
+		"redirect_uri":  {"https://app.example.com/callback"},
+		"code_verifier": {testCodeVerifier},
+	}, "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp model.TokenResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	return resp
+}
+
+func TestIDTokenIssuedForOpenIDScope(t *testing.T) {
+	h, _ := newTestOAuthHandler()
+
+	params := authorizeParams()
+	params.Set("scope", "openid email")
+	params.Set("nonce", "n-0S6_WzA2Mj")
+	resp := authorizeAndExchange(t, h, params)
+	assert.NotEmpty(t, resp.IDToken)
+
+	claims, err := auth.ParseIDToken(resp.IDToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "7", claims.Subject)
+	assert.Equal(t, []string{"spa"}, []string(claims.Audience))
+	assert.Equal(t, "n-0S6_WzA2Mj", claims.Nonce)
+	assert.Equal(t, "jane@example.com", claims.Email)
+	assert.NotNil(t, claims.EmailVerified)
+	assert.NotZero(t, claims.AuthTime)
+	// the name claim needs the profile scope
+	assert.Empty(t, claims.Name)
+
+	// no ID token without openid
+	resp = authorizeAndExchange(t, h, authorizeParams())
+	assert.Empty(t, resp.IDToken)
+}
+
+func TestUserInfo(t *testing.T) {
+	h, _ := newTestOAuthHandler()
+	users := &testutils.MockUserRepo{Users: []*model.User{testutils.NewMockUser(7, "jane@example.com", "password1")}}
+	userInfo := middleware.NewJWTAuth().OAuthMiddleware(http.HandlerFunc(handler.NewOIDCHandler(users).UserInfo))
+
+	params := authorizeParams()
+	params.Set("scope", "openid profile")
+	resp := authorizeAndExchange(t, h, params)
+
+	req := httptest.NewRequest(http.MethodGet, "/userinfo", nil)
+	req.Header.Set("Authorization", "Bearer "+resp.AccessToken)
+	rr := httptest.NewRecorder()
+	userInfo.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var claims map[string]any
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&claims))
+	assert.Equal(t, "7", claims["sub"])
+	assert.Equal(t, "User jane@example.com", claims["name"])
+	assert.NotContains(t, claims, "email")
+
+	// tokens without the openid scope are refused
+	resp = authorizeAndExchange(t, h, authorizeParams())
+	req = httptest.NewRequest(http.MethodGet, "/userinfo", nil)
+	req.Header.Set("Authorization", "Bearer "+resp.AccessToken)
+	rr = httptest.NewRecorder()
+	userInfo.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
@@ -0,0 +1,406 @@
+package handler
+
+import (
+	"encoding/json"
+	"go-user-api/internal/audit"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/repository"
+	"go-user-api/internal/tenant"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// organizationSlug is a DNS label, so that slugs work as subdomains.
+var organizationSlug = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+type OrganizationHandler struct {
+	organizations repository.OrganizationRepository
+	users         repository.UserRepository
+	auditLog      audit.Recorder
+}
+
+func NewOrganizationHandler(organizations repository.OrganizationRepository, users repository.UserRepository,
+	auditLog audit.Recorder) *OrganizationHandler {
+	return &OrganizationHandler{organizations: organizations, users: users, auditLog: auditLog}
+}
+
+// GetOrganizations godoc
+// @Summary List organizations
+// @Description Only admins of the default organization manage organizations.
+// @Tags organizations
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} model.Organization
+// @Failure 403 {string} string "Forbidden"
+// @Router /admin/organizations [get]
+func (h *OrganizationHandler) GetOrganizations(w http.ResponseWriter, r *http.Request) {
+	organizations, err := h.organizations.GetAllOrganizations(r.Context())
+	if err != nil {
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(organizations)
+}
+
+// CreateOrganization godoc
+// @Summary Create an organization
+// @Description Creates an organization and, optionally, its first admin.
+// @Tags organizations
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   organization  body  model.OrganizationInput  true  "Organization"
+// @Success 201 {object} model.Organization
+// @Failure 400 {string} string "Invalid input"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 409 {string} string "Slug already taken"
+// @Router /admin/organizations [post]
+func (h *OrganizationHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	var input model.OrganizationInput
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		http.Error(w, "validation error"+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !organizationSlug.MatchString(input.Slug) {
+		http.Error(w, "slug must be lowercase letters, digits and hyphens", http.StatusBadRequest)
+		return
+	}
+
+	if existing, err := h.organizations.GetBySlug(r.Context(), input.Slug); err == nil && existing != nil {
+		http.Error(w, "Slug already taken", http.StatusConflict)
+		return
+	}
+
+	org := model.Organization{Slug: input.Slug, Name: input.Name}
+	if err := h.organizations.Create(r.Context(), &org); err != nil {
+		http.Error(w, "failed to create organization", http.StatusInternalServerError)
+		return
+	}
+
+	recordChange(r, h.auditLog, newAuditEvent(r, audit.ActionOrganizationCreate, audit.TargetOrganization, strconv.Itoa(org.ID)), nil, org)
+
+	if input.Admin != nil {
+		input.Admin.Role = model.RoleAdmin
+		// the admin is created inside the new organization
+		scoped := r.WithContext(tenant.WithID(r.Context(), org.ID))
+		if _, status, msg := h.createMember(scoped, *input.Admin); status != 0 {
+			http.Error(w, "organization created, but not its admin: "+msg, status)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(org)
+}
+
+// GetOrganization godoc
+// @Summary Get an organization
+// @Tags organizations
+// @Produce  json
+// @Security BearerAuth
+// @Param   id  path  int  true  "Organization ID"
+// @Success 200 {object} model.Organization
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Organization not found"
+// @Router /admin/organizations/{id} [get]
+func (h *OrganizationHandler) GetOrganization(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	h.writeOrganization(w, r, id)
+}
+
+// UpdateOrganization godoc
+// @Summary Rename an organization
+// @Description The slug cannot change, since it is part of the organization's URLs.
+// @Tags organizations
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   id            path  int                 true  "Organization ID"
+// @Param   organization  body  model.Organization  true  "Organization"
+// @Success 200 {object} model.Organization
+// @Failure 400 {string} string "Invalid input"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Organization not found"
+// @Router /admin/organizations/{id} [put]
+func (h *OrganizationHandler) UpdateOrganization(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var input model.Organization
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Name == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	before, err := h.organizations.Get(r.Context(), id)
+	if err != nil || before == nil {
+		http.Error(w, "Organization not found", http.StatusNotFound)
+		return
+	}
+
+	after := *before
+	after.Name = input.Name
+	if err := h.organizations.Update(r.Context(), &after); err != nil {
+		http.Error(w, "Update failed", http.StatusInternalServerError)
+		return
+	}
+
+	recordChange(r, h.auditLog, newAuditEvent(r, audit.ActionOrganizationUpdate, audit.TargetOrganization, strconv.Itoa(id)), before, after)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(after)
+}
+
+// DeleteOrganization godoc
+// @Summary Delete an organization
+// @Description Deletes the organization together with all of its users. The default organization cannot be deleted.
+// @Tags organizations
+// @Security BearerAuth
+// @Param   id  path  int  true  "Organization ID"
+// @Success 204
+// @Failure 400 {string} string "The default organization cannot be deleted"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Organization not found"
+// @Router /admin/organizations/{id} [delete]
+func (h *OrganizationHandler) DeleteOrganization(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	if id == tenant.DefaultID {
+		http.Error(w, "The default organization cannot be deleted", http.StatusBadRequest)
+		return
+	}
+
+	before, err := h.organizations.Get(r.Context(), id)
+	if err != nil || before == nil {
+		http.Error(w, "Organization not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.organizations.Delete(r.Context(), id); err != nil {
+		http.Error(w, "Delete failed", http.StatusInternalServerError)
+		return
+	}
+
+	recordChange(r, h.auditLog, newAuditEvent(r, audit.ActionOrganizationDelete, audit.TargetOrganization, strconv.Itoa(id)), before, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetCurrentOrganization godoc
+// @Summary Get your organization
+// @Tags organizations
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} model.Organization
+// @Failure 401 {string} string "Unauthorized"
+// @Router /tenant [get]
+func (h *OrganizationHandler) GetCurrentOrganization(w http.ResponseWriter, r *http.Request) {
+	h.writeOrganization(w, r, tenant.ID(r.Context()))
+}
+
+// GetMembers godoc
+// @Summary List the users of your organization
+// @Tags organizations
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} model.User
+// @Failure 403 {string} string "Forbidden"
+// @Router /tenant/members [get]
+func (h *OrganizationHandler) GetMembers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.users.GetAllUsers(r.Context())
+	if err != nil {
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	members := []*model.User{}
+	for _, u := range users {
+		u.Password = ""
+		members = append(members, u)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(members)
+}
+
+// CreateMember godoc
+// @Summary Add a user to your organization
+// @Tags organizations
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   member  body  model.OrganizationMemberInput  true  "New user"
+// @Success 201 {object} model.User
+// @Failure 400 {string} string "Invalid input"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 409 {string} string "Email already in use"
+// @Router /tenant/members [post]
+func (h *OrganizationHandler) CreateMember(w http.ResponseWriter, r *http.Request) {
+	var input model.OrganizationMemberInput
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	user, status, msg := h.createMember(r, input)
+	if status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
+}
+
+// createMember adds a user to the organization of r.
+func (h *OrganizationHandler) createMember(r *http.Request, input model.OrganizationMemberInput) (*model.User, int, string) {
+	if err := validate.Struct(input); err != nil {
+		return nil, http.StatusBadRequest, "validation error" + err.Error()
+	}
+
+	if input.Role == "" {
+		input.Role = model.RoleUser
+	}
+
+	if !validRole(input.Role) {
+		return nil, http.StatusBadRequest, "role must be user or admin"
+	}
+
+	if existing, err := h.users.GetByEmail(r.Context(), input.Email); err == nil && existing != nil {
+		return nil, http.StatusConflict, "Email already in use"
+	}
+
+	hashedPassword, err := auth.HashPasswordContext(r.Context(), input.Password)
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Could not hash password"
+	}
+
+	user := &model.User{Name: input.Name, Email: input.Email, Password: hashedPassword}
+	if err := h.users.Create(r.Context(), user); err != nil {
+		return nil, http.StatusInternalServerError, "failed to create user"
+	}
+
+	if input.Role != model.RoleUser {
+		if err := h.users.UpdateRole(r.Context(), user.ID, input.Role); err != nil {
+			return nil, http.StatusInternalServerError, "failed to set role"
+		}
+	}
+	user.Role = input.Role
+
+	recordChange(r, h.auditLog, newAuditEvent(r, audit.ActionUserCreate, audit.TargetUser, strconv.Itoa(user.ID)), nil, user)
+
+	user.Password = ""
+	return user, 0, ""
+}
+
+// UpdateMemberRole godoc
+// @Summary Change the role of a user in your organization
+// @Tags organizations
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   id    path  int              true  "User ID"
+// @Param   role  body  model.RoleInput  true  "New role"
+// @Success 200 {object} model.User
+// @Failure 400 {string} string "Invalid input"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "User not found"
+// @Router /tenant/members/{id}/role [put]
+func (h *OrganizationHandler) UpdateMemberRole(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var input model.RoleInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || !validRole(input.Role) {
+		http.Error(w, "role must be user or admin", http.StatusBadRequest)
+		return
+	}
+
+	// an organization must not lose its last admin by accident
+	if callerID, _ := r.Context().Value(middleware.UserIDKey).(int); callerID == id {
+		http.Error(w, "You cannot change your own role", http.StatusBadRequest)
+		return
+	}
+
+	before, err := h.users.Get(r.Context(), id)
+	if err != nil || before == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.users.UpdateRole(r.Context(), id, input.Role); err != nil {
+		http.Error(w, "Update failed", http.StatusInternalServerError)
+		return
+	}
+
+	after := *before
+	after.Role = input.Role
+	recordChange(r, h.auditLog, newAuditEvent(r, audit.ActionUserUpdate, audit.TargetUser, strconv.Itoa(id)), before, after)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(after)
+}
+
+// DeleteMember godoc
+// @Summary Remove a user from your organization
+// @Description Deletes the user's account.
+// @Tags organizations
+// @Security BearerAuth
+// @Param   id  path  int  true  "User ID"
+// @Success 204
+// @Failure 400 {string} string "You cannot remove yourself"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "User not found"
+// @Router /tenant/members/{id} [delete]
+func (h *OrganizationHandler) DeleteMember(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if callerID, _ := r.Context().Value(middleware.UserIDKey).(int); callerID == id {
+		http.Error(w, "You cannot remove yourself", http.StatusBadRequest)
+		return
+	}
+
+	before, err := h.users.Get(r.Context(), id)
+	if err != nil || before == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.users.Delete(r.Context(), id); err != nil {
+		http.Error(w, "Delete failed", http.StatusInternalServerError)
+		return
+	}
+
+	recordChange(r, h.auditLog, newAuditEvent(r, audit.ActionUserDelete, audit.TargetUser, strconv.Itoa(id)), before, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *OrganizationHandler) writeOrganization(w http.ResponseWriter, r *http.Request, id int) {
+	org, err := h.organizations.Get(r.Context(), id)
+	if err != nil || org == nil {
+		http.Error(w, "Organization not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(org)
+}
+
+func validRole(role string) bool {
+	return role == model.RoleUser || role == model.RoleAdmin
+}
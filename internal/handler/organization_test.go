@@ -0,0 +1,167 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/routes"
+	"go-user-api/internal/tenant"
+	"go-user-api/internal/testutils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type tenantFixture struct {
+	router        *chi.Mux
+	users         *testutils.MockUserRepo
+	organizations *testutils.MockOrganizationRepo
+}
+
+func newTenantFixture() *tenantFixture {
+	platformAdmin := testutils.NewMockUser(1, "root@example.com", "password1")
+	platformAdmin.Role = model.RoleAdmin
+
+	acmeAdmin := testutils.NewMockUser(2, "admin@acme.test", "password1")
+	acmeAdmin.Role = model.RoleAdmin
+	acmeAdmin.OrganizationID = 2
+
+	// the same email exists in both organizations
+	jane := testutils.NewMockUser(3, "jane@example.com", "password1")
+	acmeJane := testutils.NewMockUser(4, "jane@example.com", "password2")
+	acmeJane.OrganizationID = 2
+
+	f := &tenantFixture{
+		users:         &testutils.MockUserRepo{Users: []*model.User{platformAdmin, acmeAdmin, jane, acmeJane}},
+		organizations: testutils.NewMockOrganizationRepo(&model.Organization{ID: 2, Slug: "acme", Name: "Acme"}),
+	}
+
+	f.router = chi.NewRouter()
+	f.router.Use(middleware.ResolveTenant(f.organizations, "example.com"))
+	routes.RegisterAuthRoutes(f.router, handler.NewAuthRouteHandler(f.users), middleware.NewJWTAuth())
+	routes.RegisterUserRoutes(f.router, handler.NewUserHandler(f.users, &testutils.MockAuditRepo{}), middleware.NewJWTAuth(), nil)
+	routes.RegisterOrganizationRoutes(f.router, handler.NewOrganizationHandler(f.organizations, f.users, &testutils.MockAuditRepo{}), middleware.NewJWTAuth(), f.users)
+
+	return f
+}
+
+func (f *tenantFixture) send(method string, target string, token string, body string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+
+	rr := httptest.NewRecorder()
+	f.router.ServeHTTP(rr, req)
+	return rr
+}
+
+func (f *tenantFixture) login(t *testing.T, target string, password string, header ...string) *auth.Claims {
+	rr := f.send(http.MethodPost, target, "", `{"email": "jane@example.com", "password": "`+password+`"}`, header...)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp map[string]string
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	claims, err := auth.ParseToken(resp["token"])
+	assert.NoError(t, err)
+	return claims
+}
+
+func TestTenantResolution(t *testing.T) {
+	f := newTenantFixture()
+
+	// the same email signs in to a different account per organization
+	assert.Equal(t, 3, f.login(t, "/auth/login", "password1").UserID)
+	acme := f.login(t, "/auth/login", "password2", "X-Tenant-ID", "acme")
+	assert.Equal(t, 4, acme.UserID)
+	assert.Equal(t, 2, acme.TenantID)
+	assert.Equal(t, 4, f.login(t, "http://acme.example.com/auth/login", "password2").UserID)
+
+	rr := f.send(http.MethodPost, "/auth/login", "", `{"email": "jane@example.com", "password": "password2"}`)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "password of the other organization's account")
+
+	assert.Equal(t, http.StatusNotFound, f.send(http.MethodGet, "/tenant", "", "", "X-Tenant-ID", "nope").Code)
+
+	// a token is only accepted for its organization
+	token, _ := auth.GenerateJWT(4, auth.WithTenant(2))
+	rr = f.send(http.MethodGet, "/tenant", token, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var org model.Organization
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&org))
+	assert.Equal(t, "acme", org.Slug)
+
+	assert.Equal(t, http.StatusUnauthorized, f.send(http.MethodGet, "/tenant", token, "", "X-Tenant-ID", "default").Code)
+}
+
+func TestSignupOnlyToDefaultOrganization(t *testing.T) {
+	f := newTenantFixture()
+	body := `{"name": "Mallory", "email": "mallory@example.com", "password": "password1"}`
+
+	assert.Equal(t, http.StatusForbidden, f.send(http.MethodPost, "/auth/signup", "", body, "X-Tenant-ID", "acme").Code)
+	assert.Equal(t, http.StatusForbidden, f.send(http.MethodPost, "http://acme.example.com/auth/signup", "", body).Code)
+	assert.Equal(t, http.StatusForbidden, f.send(http.MethodPost, "/users", "", body, "X-Tenant-ID", "acme").Code)
+	for _, u := range f.users.Users {
+		assert.NotEqual(t, "mallory@example.com", u.Email)
+	}
+
+	assert.Equal(t, http.StatusOK, f.send(http.MethodPost, "/auth/signup", "", body).Code)
+	mallory, err := f.users.GetByEmail(context.Background(), "mallory@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, tenant.DefaultID, mallory.OrganizationID)
+}
+
+func TestTenantAdminAPIs(t *testing.T) {
+	f := newTenantFixture()
+	acmeAdmin, _ := auth.GenerateJWT(2, auth.WithTenant(2))
+	platformAdmin, _ := auth.GenerateJWT(1, auth.WithTenant(1))
+
+	// tenant admins only see their own organization
+	rr := f.send(http.MethodGet, "/tenant/members", acmeAdmin, "")
+	var members []model.User
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&members))
+	assert.Len(t, members, 2)
+	for _, m := range members {
+		assert.Equal(t, 2, m.OrganizationID)
+		assert.Empty(t, m.Password)
+	}
+
+	rr = f.send(http.MethodPost, "/tenant/members", acmeAdmin, `{"name": "Bob Builder", "email": "bob@acme.test", "password": "secret1"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, http.StatusConflict, f.send(http.MethodPost, "/tenant/members", acmeAdmin, `{"name": "Jane Again", "email": "jane@example.com", "password": "secret1"}`).Code)
+
+	assert.Equal(t, http.StatusOK, f.send(http.MethodPut, "/tenant/members/4/role", acmeAdmin, `{"role": "admin"}`).Code)
+	assert.Equal(t, model.RoleAdmin, f.users.Users[3].Role)
+
+	// users of other organizations are out of reach
+	assert.Equal(t, http.StatusNotFound, f.send(http.MethodPut, "/tenant/members/3/role", acmeAdmin, `{"role": "admin"}`).Code)
+	assert.Equal(t, http.StatusNotFound, f.send(http.MethodDelete, "/tenant/members/1", acmeAdmin, "").Code)
+	assert.Equal(t, http.StatusBadRequest, f.send(http.MethodDelete, "/tenant/members/2", acmeAdmin, "").Code)
+
+	// only admins of the default organization manage organizations
+	assert.Equal(t, http.StatusForbidden, f.send(http.MethodGet, "/admin/organizations", acmeAdmin, "").Code)
+
+	rr = f.send(http.MethodPost, "/admin/organizations", platformAdmin,
+		`{"slug": "globex", "name": "Globex", "admin": {"name": "Hank Scorpio", "email": "hank@globex.test", "password": "secret1"}}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var globex model.Organization
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&globex))
+
+	hank := f.users.Users[len(f.users.Users)-1]
+	assert.Equal(t, globex.ID, hank.OrganizationID)
+	assert.Equal(t, model.RoleAdmin, hank.Role)
+
+	assert.Equal(t, http.StatusConflict, f.send(http.MethodPost, "/admin/organizations", platformAdmin, `{"slug": "globex", "name": "Again"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, f.send(http.MethodPost, "/admin/organizations", platformAdmin, `{"slug": "Not A Label", "name": "Bad"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, f.send(http.MethodDelete, "/admin/organizations/1", platformAdmin, "").Code)
+	assert.Equal(t, http.StatusNoContent, f.send(http.MethodDelete, "/admin/organizations/2", platformAdmin, "").Code)
+}
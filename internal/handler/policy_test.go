@@ -0,0 +1,80 @@
+package handler_test
+
+import (
+	"fmt"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/policy"
+	"go-user-api/internal/routes"
+	"go-user-api/internal/testutils"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPolicyRouter(t *testing.T, src string) http.Handler {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, os.WriteFile(path, []byte(src), 0o600))
+	engine, err := policy.NewEngine(path)
+	require.NoError(t, err)
+
+	admin := testutils.NewMockUser(1, "admin@example.com", "password1")
+	admin.Role = model.RoleAdmin
+	users := &testutils.MockUserRepo{Users: []*model.User{
+		admin,
+		testutils.NewMockUser(5, "jane@example.com", "password1"),
+	}}
+
+	r := chi.NewRouter()
+	r.Use(middleware.Policy(engine, middleware.NewJWTAuth(), users))
+	routes.RegisterUserRoutes(r, handler.NewUserHandler(users, &testutils.MockAuditRepo{}), middleware.NewJWTAuth(), nil)
+	return r
+}
+
+// the rules use the route pattern, the subject's token and the role of
+// the user the route names
+const ownProfilePolicy = `{
+	"version": "2026-10-01",
+	"mode": "%s",
+	"rules": [
+		{"name": "own-profile", "effect": "deny", "methods": ["PUT", "DELETE"], "routes": ["/users/{id}"],
+		 "when": "resource.user.id != subject.user_id && !('admins' in subject.groups)"},
+		{"name": "protect-admins", "effect": "deny", "methods": ["DELETE"], "routes": ["/users/{id}"],
+		 "when": "resource.user.role == 'admin'"}
+	]
+}`
+
+func TestPolicyEnforced(t *testing.T) {
+	r := newPolicyRouter(t, policyMode(policy.ModeEnforce))
+
+	jane, _ := auth.GenerateJWT(5)
+	admin, _ := auth.GenerateJWT(1, auth.WithGroups([]string{"admins"}))
+	update := `{"name": "Jane Doe", "email": "jane@example.com"}`
+
+	assert.Equal(t, http.StatusOK, sendAs(r, http.MethodGet, "/users/1", "", "").Code, "no rule applies")
+	assert.Equal(t, http.StatusForbidden, sendAs(r, http.MethodPut, "/users/5", "", update).Code)
+	assert.Equal(t, http.StatusOK, sendAs(r, http.MethodPut, "/users/5", jane, update).Code)
+	assert.Equal(t, http.StatusForbidden, sendAs(r, http.MethodPut, "/users/1", jane, update).Code)
+	assert.Equal(t, http.StatusOK, sendAs(r, http.MethodPut, "/users/5", admin, update).Code)
+	assert.Equal(t, http.StatusForbidden, sendAs(r, http.MethodDelete, "/users/1", admin, "").Code)
+	assert.Equal(t, http.StatusNoContent, sendAs(r, http.MethodDelete, "/users/5", admin, "").Code)
+}
+
+func TestPolicyShadowMode(t *testing.T) {
+	r := newPolicyRouter(t, policyMode(policy.ModeShadow))
+
+	jane, _ := auth.GenerateJWT(5)
+	rr := sendAs(r, http.MethodPut, "/users/1", jane, `{"name": "Jane Doe", "email": "jane@example.com"}`)
+	assert.Equal(t, http.StatusOK, rr.Code, "decisions are only logged")
+}
+
+func policyMode(mode string) string {
+	return fmt.Sprintf(ownProfilePolicy, mode)
+}
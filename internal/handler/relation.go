@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"go-user-api/internal/model"
+	"go-user-api/internal/rebac"
+	"net/http"
+)
+
+type RelationHandler struct {
+	engine *rebac.Engine
+}
+
+func NewRelationHandler(engine *rebac.Engine) *RelationHandler {
+	return &RelationHandler{engine: engine}
+}
+
+// WriteRelation godoc
+// @Summary Store a relation tuple
+// @Description E.g. object "team:1", relation "manager", subject "user:2". Subjects can be usersets such as "team:3#member".
+// @Tags relations
+// @Accept  json
+// @Security BearerAuth
+// @Param   tuple  body  model.RelationTupleInput  true  "Relation tuple"
+// @Success 204
+// @Failure 400 {string} string "Invalid tuple"
+// @Failure 403 {string} string "Forbidden"
+// @Router /admin/relations [post]
+func (h *RelationHandler) WriteRelation(w http.ResponseWriter, r *http.Request) {
+	tuple, ok := decodeTuple(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.engine.Write(r.Context(), tuple); err != nil {
+		writeRelationError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteRelation godoc
+// @Summary Delete a relation tuple
+// @Tags relations
+// @Accept  json
+// @Security BearerAuth
+// @Param   tuple  body  model.RelationTupleInput  true  "Relation tuple"
+// @Success 204
+// @Failure 400 {string} string "Invalid tuple"
+// @Failure 403 {string} string "Forbidden"
+// @Router /admin/relations [delete]
+func (h *RelationHandler) DeleteRelation(w http.ResponseWriter, r *http.Request) {
+	tuple, ok := decodeTuple(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.engine.Delete(r.Context(), tuple); err != nil {
+		writeRelationError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CheckRelation godoc
+// @Summary Check a permission
+// @Description Reports whether the subject holds the relation or permission on the object, directly or through other relations.
+// @Tags relations
+// @Produce  json
+// @Security BearerAuth
+// @Param   object    query  string  true  "Object, e.g. user:5"
+// @Param   relation  query  string  true  "Relation or permission, e.g. edit"
+// @Param   subject   query  string  true  "Subject, e.g. user:2"
+// @Success 200 {object} model.RelationCheck
+// @Failure 400 {string} string "Invalid input"
+// @Failure 403 {string} string "Forbidden"
+// @Router /admin/relations/check [get]
+func (h *RelationHandler) CheckRelation(w http.ResponseWriter, r *http.Request) {
+	object, err := rebac.ParseObject(r.URL.Query().Get("object"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	subject, err := rebac.ParseSubject(r.URL.Query().Get("subject"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	allowed, err := h.engine.Check(r.Context(), object, r.URL.Query().Get("relation"), subject)
+	if err != nil {
+		writeRelationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(model.RelationCheck{Allowed: allowed})
+}
+
+// ExpandRelation godoc
+// @Summary Expand a relation
+// @Description Returns the tree of subjects holding the relation or permission on the object.
+// @Tags relations
+// @Produce  json
+// @Security BearerAuth
+// @Param   object    query  string  true  "Object, e.g. team:1"
+// @Param   relation  query  string  true  "Relation or permission"
+// @Success 200 {object} rebac.Node
+// @Failure 400 {string} string "Invalid input"
+// @Failure 403 {string} string "Forbidden"
+// @Router /admin/relations/expand [get]
+func (h *RelationHandler) ExpandRelation(w http.ResponseWriter, r *http.Request) {
+	object, err := rebac.ParseObject(r.URL.Query().Get("object"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tree, err := h.engine.Expand(r.Context(), object, r.URL.Query().Get("relation"))
+	if err != nil {
+		writeRelationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tree)
+}
+
+// ListRelationObjects godoc
+// @Summary List the objects a subject holds a relation on
+// @Tags relations
+// @Produce  json
+// @Security BearerAuth
+// @Param   type      query  string  true  "Object type, e.g. user"
+// @Param   relation  query  string  true  "Relation or permission"
+// @Param   subject   query  string  true  "Subject, e.g. user:2"
+// @Success 200 {object} model.RelationObjects
+// @Failure 400 {string} string "Invalid input"
+// @Failure 403 {string} string "Forbidden"
+// @Router /admin/relations/objects [get]
+func (h *RelationHandler) ListRelationObjects(w http.ResponseWriter, r *http.Request) {
+	subject, err := rebac.ParseSubject(r.URL.Query().Get("subject"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	objects, err := h.engine.ListObjects(r.Context(), r.URL.Query().Get("type"), r.URL.Query().Get("relation"), subject)
+	if err != nil {
+		writeRelationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(model.RelationObjects{Objects: objects})
+}
+
+func decodeTuple(w http.ResponseWriter, r *http.Request) (rebac.Tuple, bool) {
+	var input model.RelationTupleInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return rebac.Tuple{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		http.Error(w, "validation error"+err.Error(), http.StatusBadRequest)
+		return rebac.Tuple{}, false
+	}
+
+	tuple, err := rebac.ParseTuple(input.Object + "#" + input.Relation + "@" + input.Subject)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return rebac.Tuple{}, false
+	}
+
+	return tuple, true
+}
+
+// writeRelationError answers schema violations with 400 and anything else,
+// such as store failures, with 500.
+func writeRelationError(w http.ResponseWriter, err error) {
+	if errors.Is(err, rebac.ErrUnknownRelation) || errors.Is(err, rebac.ErrInvalidTuple) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	http.Error(w, "something went wrong", http.StatusInternalServerError)
+}
@@ -0,0 +1,78 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/rebac"
+	"go-user-api/internal/routes"
+	"go-user-api/internal/testutils"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelationAuthorizedUserRoutes(t *testing.T) {
+	admin := testutils.NewMockUser(1, "admin@example.com", "password1")
+	admin.Role = model.RoleAdmin
+	users := &testutils.MockUserRepo{Users: []*model.User{
+		admin,
+		testutils.NewMockUser(2, "manager@example.com", "password1"),
+		testutils.NewMockUser(3, "peer@example.com", "password1"),
+		testutils.NewMockUser(5, "jane@example.com", "password1"),
+	}}
+
+	schema, _ := rebac.ParseSchema(rebac.DefaultSchema)
+	store := &testutils.MockTupleStore{}
+	engine := rebac.NewEngine(schema, store)
+
+	r := chi.NewRouter()
+	routes.RegisterUserRoutes(r, handler.NewUserHandler(users, &testutils.MockAuditRepo{}), middleware.NewJWTAuth(), engine)
+	routes.RegisterRelationRoutes(r, handler.NewRelationHandler(engine), middleware.NewJWTAuth(), users)
+
+	adminToken, _ := auth.GenerateJWT(1)
+	manager, _ := auth.GenerateJWT(2)
+	peer, _ := auth.GenerateJWT(3)
+	jane, _ := auth.GenerateJWT(5)
+
+	for _, tuple := range []string{
+		`{"object": "user:5", "relation": "self", "subject": "user:5"}`,
+		`{"object": "user:5", "relation": "team", "subject": "team:1"}`,
+		`{"object": "team:1", "relation": "manager", "subject": "user:2"}`,
+		`{"object": "team:1", "relation": "member", "subject": "user:3"}`,
+	} {
+		assert.Equal(t, http.StatusNoContent, sendAs(r, http.MethodPost, "/admin/relations", adminToken, tuple).Code)
+	}
+	assert.Equal(t, http.StatusBadRequest, sendAs(r, http.MethodPost, "/admin/relations", adminToken,
+		`{"object": "team:1", "relation": "owner", "subject": "user:3"}`).Code)
+	assert.Equal(t, http.StatusForbidden, sendAs(r, http.MethodPost, "/admin/relations", jane,
+		`{"object": "team:1", "relation": "manager", "subject": "user:5"}`).Code)
+
+	update := `{"name": "Jane Doe", "email": "jane@example.com"}`
+	assert.Equal(t, http.StatusUnauthorized, sendAs(r, http.MethodPut, "/users/5", "", update).Code)
+	assert.Equal(t, http.StatusOK, sendAs(r, http.MethodPut, "/users/5", jane, update).Code)
+	assert.Equal(t, http.StatusOK, sendAs(r, http.MethodPut, "/users/5", manager, update).Code)
+	assert.Equal(t, http.StatusForbidden, sendAs(r, http.MethodPut, "/users/5", peer, update).Code)
+
+	// users can edit but not delete themselves; their team's manager can
+	assert.Equal(t, http.StatusForbidden, sendAs(r, http.MethodDelete, "/users/5", jane, "").Code)
+
+	rr := sendAs(r, http.MethodGet, "/admin/relations/check?object=user:5&relation=manage&subject=user:2", adminToken, "")
+	var check model.RelationCheck
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&check))
+	assert.True(t, check.Allowed)
+
+	rr = sendAs(r, http.MethodGet, "/admin/relations/objects?type=user&relation=view&subject=user:3", adminToken, "")
+	var objects model.RelationObjects
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&objects))
+	assert.Equal(t, []string{"5"}, objects.Objects)
+
+	assert.Equal(t, http.StatusOK, sendAs(r, http.MethodGet, "/admin/relations/expand?object=user:5&relation=edit", adminToken, "").Code)
+	assert.Equal(t, http.StatusBadRequest, sendAs(r, http.MethodGet, "/admin/relations/expand?object=user5&relation=edit", adminToken, "").Code)
+
+	assert.Equal(t, http.StatusNoContent, sendAs(r, http.MethodDelete, "/users/5", manager, "").Code)
+}
@@ -0,0 +1,502 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/model"
+	"go-user-api/internal/repository"
+	"go-user-api/internal/scim"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// SCIMHandler serves the SCIM 2.0 provisioning API (RFC 7644) on top of the
+// user and group stores. Lists are filtered and paged in memory, which is
+// fine for the directory sizes we provision; the common userName eq lookup
+// uses the email index and, like logins, matches the email exactly.
+type SCIMHandler struct {
+	users  repository.UserRepository
+	groups repository.GroupRepository
+}
+
+func NewSCIMHandler(users repository.UserRepository, groups repository.GroupRepository) *SCIMHandler {
+	return &SCIMHandler{users: users, groups: groups}
+}
+
+func scimBaseURL() string {
+	return auth.Issuer() + "/scim/v2"
+}
+
+// GetServiceProviderConfig godoc
+// @Summary SCIM service provider configuration
+// @Description Lists the SCIM features this server supports
+// @Tags scim
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} scim.ServiceProviderConfig
+// @Failure 401 {object} scim.Error
+// @Router /scim/v2/ServiceProviderConfig [get]
+func (h *SCIMHandler) GetServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	writeSCIM(w, http.StatusOK, scim.NewServiceProviderConfig(scimBaseURL()))
+}
+
+// GetResourceTypes godoc
+// @Summary SCIM resource types
+// @Tags scim
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} scim.ListResponse
+// @Failure 401 {object} scim.Error
+// @Router /scim/v2/ResourceTypes [get]
+func (h *SCIMHandler) GetResourceTypes(w http.ResponseWriter, r *http.Request) {
+	var resources []any
+	for _, t := range scim.ResourceTypes(scimBaseURL()) {
+		resources = append(resources, t)
+	}
+
+	writeSCIM(w, http.StatusOK, scim.NewListResponse(resources, 1, len(resources)))
+}
+
+// GetResourceType godoc
+// @Summary SCIM resource type
+// @Tags scim
+// @Produce  json
+// @Security BearerAuth
+// @Param   id  path  string  true  "User or Group"
+// @Success 200 {object} scim.ResourceType
+// @Failure 404 {object} scim.Error
+// @Router /scim/v2/ResourceTypes/{id} [get]
+func (h *SCIMHandler) GetResourceType(w http.ResponseWriter, r *http.Request) {
+	for _, t := range scim.ResourceTypes(scimBaseURL()) {
+		if t.ID == chi.URLParam(r, "id") {
+			writeSCIM(w, http.StatusOK, t)
+			return
+		}
+	}
+
+	writeSCIMError(w, scim.NewError(http.StatusNotFound, "", "Resource type not found"))
+}
+
+// GetSchemas godoc
+// @Summary SCIM schemas
+// @Tags scim
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} scim.ListResponse
+// @Failure 401 {object} scim.Error
+// @Router /scim/v2/Schemas [get]
+func (h *SCIMHandler) GetSchemas(w http.ResponseWriter, r *http.Request) {
+	var resources []any
+	for _, s := range scim.Schemas(scimBaseURL()) {
+		resources = append(resources, s)
+	}
+
+	writeSCIM(w, http.StatusOK, scim.NewListResponse(resources, 1, len(resources)))
+}
+
+// GetSchema godoc
+// @Summary SCIM schema
+// @Tags scim
+// @Produce  json
+// @Security BearerAuth
+// @Param   id  path  string  true  "Schema URN"
+// @Success 200 {object} scim.Schema
+// @Failure 404 {object} scim.Error
+// @Router /scim/v2/Schemas/{id} [get]
+func (h *SCIMHandler) GetSchema(w http.ResponseWriter, r *http.Request) {
+	for _, s := range scim.Schemas(scimBaseURL()) {
+		if s.ID == chi.URLParam(r, "id") {
+			writeSCIM(w, http.StatusOK, s)
+			return
+		}
+	}
+
+	writeSCIMError(w, scim.NewError(http.StatusNotFound, "", "Schema not found"))
+}
+
+// GetUsers godoc
+// @Summary List or search users
+// @Description Supports filter expressions such as userName eq "jane@example.com", and pagination with startIndex and count.
+// @Tags scim
+// @Produce  json
+// @Security BearerAuth
+// @Param   filter      query  string  false  "SCIM filter expression"
+// @Param   startIndex  query  int     false  "1-based index of the first result"
+// @Param   count       query  int     false  "Maximum number of results"
+// @Success 200 {object} scim.ListResponse
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Router /scim/v2/Users [get]
+func (h *SCIMHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
+	filter, startIndex, count, err := scimListParams(r)
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	var users []*model.User
+	if email, ok := scim.EqualityValue(filter, "userName"); ok {
+		if u, err := h.users.GetByEmail(r.Context(), email); err == nil && u != nil {
+			users = append(users, u)
+		}
+	} else if users, err = h.users.GetAllUsers(r.Context()); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	var resources []any
+	for _, u := range users {
+		resource := scimUser(u)
+		if scimMatches(filter, resource) {
+			resources = append(resources, resource)
+		}
+	}
+
+	writeSCIM(w, http.StatusOK, scim.NewListResponse(resources, startIndex, count))
+}
+
+// GetUser godoc
+// @Summary Get a provisioned user
+// @Tags scim
+// @Produce  json
+// @Security BearerAuth
+// @Param   id  path  string  true  "User ID"
+// @Success 200 {object} scim.User
+// @Failure 404 {object} scim.Error
+// @Router /scim/v2/Users/{id} [get]
+func (h *SCIMHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.findUser(w, r)
+	if !ok {
+		return
+	}
+
+	writeSCIM(w, http.StatusOK, scimUser(user))
+}
+
+// CreateUser godoc
+// @Summary Provision a user
+// @Description userName must be the user's email address. Without a password the account can only sign in through federation or a password reset.
+// @Tags scim
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   user  body  scim.User  true  "SCIM user"
+// @Success 201 {object} scim.User
+// @Failure 400 {object} scim.Error
+// @Failure 409 {object} scim.Error
+// @Router /scim/v2/Users [post]
+func (h *SCIMHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var resource scim.User
+	if err := json.NewDecoder(r.Body).Decode(&resource); err != nil {
+		writeSCIMError(w, scim.NewError(http.StatusBadRequest, "invalidSyntax", "Invalid input"))
+		return
+	}
+
+	user := &model.User{}
+	if err := h.applyUser(r.Context(), user, &resource); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	password := resource.Password
+	if password == "" {
+		random, err := auth.GenerateSecret(32)
+		if err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+		password = random
+	} else if len(password) < 6 {
+		writeSCIMError(w, scim.NewError(http.StatusBadRequest, "invalidValue", "password must be at least 6 characters"))
+		return
+	}
+
+	hashedPassword, err := auth.HashPasswordContext(r.Context(), password)
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	user.Password = hashedPassword
+
+	if err := h.users.Create(r.Context(), user); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	created := scimUser(user)
+	w.Header().Set("Location", created.Meta.Location)
+	writeSCIM(w, http.StatusCreated, created)
+}
+
+// ReplaceUser godoc
+// @Summary Replace a provisioned user
+// @Tags scim
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   id    path  string     true  "User ID"
+// @Param   user  body  scim.User  true  "SCIM user"
+// @Success 200 {object} scim.User
+// @Failure 400 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Failure 409 {object} scim.Error
+// @Router /scim/v2/Users/{id} [put]
+func (h *SCIMHandler) ReplaceUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.findUser(w, r)
+	if !ok {
+		return
+	}
+
+	var resource scim.User
+	if err := json.NewDecoder(r.Body).Decode(&resource); err != nil {
+		writeSCIMError(w, scim.NewError(http.StatusBadRequest, "invalidSyntax", "Invalid input"))
+		return
+	}
+
+	h.saveUser(w, r, user, &resource)
+}
+
+// PatchUser godoc
+// @Summary Modify a provisioned user
+// @Description Applies add, replace and remove operations. Setting active to false disables the account.
+// @Tags scim
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   id     path  string             true  "User ID"
+// @Param   patch  body  scim.PatchRequest  true  "Patch operations"
+// @Success 200 {object} scim.User
+// @Failure 400 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Router /scim/v2/Users/{id} [patch]
+func (h *SCIMHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.findUser(w, r)
+	if !ok {
+		return
+	}
+
+	var patched scim.User
+	if err := patchResource(r, scimUser(user), &patched); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	h.saveUser(w, r, user, &patched)
+}
+
+func (h *SCIMHandler) saveUser(w http.ResponseWriter, r *http.Request, user *model.User, resource *scim.User) {
+	if err := h.applyUser(r.Context(), user, resource); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	if err := h.users.UpdateProvisioned(r.Context(), user); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	writeSCIM(w, http.StatusOK, scimUser(user))
+}
+
+// DeleteUser godoc
+// @Summary Deprovision a user
+// @Tags scim
+// @Security BearerAuth
+// @Param   id  path  string  true  "User ID"
+// @Success 204
+// @Failure 404 {object} scim.Error
+// @Router /scim/v2/Users/{id} [delete]
+func (h *SCIMHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.findUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.users.Delete(r.Context(), user.ID); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SCIMHandler) findUser(w http.ResponseWriter, r *http.Request) (*model.User, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err == nil {
+		if user, err := h.users.Get(r.Context(), id); err == nil && user != nil {
+			return user, true
+		}
+	}
+
+	writeSCIMError(w, scim.NewError(http.StatusNotFound, "", "User not found"))
+	return nil, false
+}
+
+// applyUser copies the attributes of a SCIM user onto user. Logins are by
+// email, so userName has to be the email address.
+func (h *SCIMHandler) applyUser(ctx context.Context, user *model.User, resource *scim.User) error {
+	email := strings.TrimSpace(resource.UserName)
+	if err := validate.Var(email, "required,email"); err != nil {
+		return scim.NewError(http.StatusBadRequest, "invalidValue", "userName must be the user's email address")
+	}
+
+	if existing, err := h.users.GetByEmail(ctx, email); err == nil && existing != nil && existing.ID != user.ID {
+		return scim.NewError(http.StatusConflict, "uniqueness", "a user with this userName already exists")
+	}
+
+	user.Name = scimUserName(user.Name, resource)
+	user.Email = email
+	user.ExternalID = resource.ExternalID
+	if resource.Active != nil {
+		user.Disabled = !*resource.Active
+	}
+
+	return nil
+}
+
+// scimUserName picks the name to store. SCIM has several name attributes and
+// we keep one, so the first attribute that differs from the current name wins.
+func scimUserName(current string, resource *scim.User) string {
+	var candidates []string
+	if resource.Name != nil {
+		candidates = append(candidates, resource.Name.Formatted,
+			strings.TrimSpace(resource.Name.GivenName+" "+resource.Name.FamilyName))
+	}
+	candidates = append(candidates, resource.DisplayName)
+
+	for _, name := range candidates {
+		if name != "" && name != current {
+			return name
+		}
+	}
+
+	if current != "" {
+		return current
+	}
+
+	return resource.UserName
+}
+
+func scimUser(u *model.User) scim.User {
+	active := !u.Disabled
+	id := strconv.Itoa(u.ID)
+
+	return scim.User{
+		Schemas:     []string{scim.UserSchema},
+		ID:          id,
+		ExternalID:  u.ExternalID,
+		UserName:    u.Email,
+		Name:        &scim.Name{Formatted: u.Name},
+		DisplayName: u.Name,
+		Emails:      []scim.MultiValued{{Value: u.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta:        scimMeta("User", "/Users/"+id, u.CreatedAt, u.UpdatedAt),
+	}
+}
+
+func scimMeta(resourceType string, path string, created time.Time, lastModified time.Time) *scim.Meta {
+	meta := &scim.Meta{ResourceType: resourceType, Location: scimBaseURL() + path}
+	if !created.IsZero() {
+		meta.Created = &created
+	}
+	if !lastModified.IsZero() {
+		meta.LastModified = &lastModified
+	}
+
+	return meta
+}
+
+// scimListParams reads the filter and pagination parameters of a list
+// request. count defaults to, and is capped at, scim.MaxResults.
+func scimListParams(r *http.Request) (scim.Filter, int, int, error) {
+	query := r.URL.Query()
+
+	filter, err := scim.ParseFilter(query.Get("filter"))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	startIndex, err := strconv.Atoi(query.Get("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+
+	count, err := strconv.Atoi(query.Get("count"))
+	if err != nil || count > scim.MaxResults {
+		count = scim.MaxResults
+	}
+
+	return filter, startIndex, max(count, 0), nil
+}
+
+// scimMatches evaluates filter against the JSON form of resource.
+func scimMatches(filter scim.Filter, resource any) bool {
+	if filter == nil {
+		return true
+	}
+
+	m, err := scimResourceMap(resource)
+	return err == nil && filter.Matches(m)
+}
+
+func scimResourceMap(resource any) (map[string]any, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]any
+	return m, json.Unmarshal(data, &m)
+}
+
+// patchResource applies the PATCH request in the body to current and decodes
+// the outcome into patched.
+func patchResource(r *http.Request, current any, patched any) error {
+	var req scim.PatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return scim.NewError(http.StatusBadRequest, "invalidSyntax", "Invalid input")
+	}
+
+	m, err := scimResourceMap(current)
+	if err != nil {
+		return err
+	}
+
+	if err := scim.ApplyPatch(m, req.Operations); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, patched); err != nil {
+		return scim.NewError(http.StatusBadRequest, "invalidValue", "patched resource is invalid")
+	}
+
+	return nil
+}
+
+func writeSCIM(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", scim.ContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeSCIMError sends err as a SCIM error. Anything that isn't a *scim.Error
+// is an internal failure and its details are not exposed.
+func writeSCIMError(w http.ResponseWriter, err error) {
+	var scimErr *scim.Error
+	if !errors.As(err, &scimErr) {
+		scimErr = scim.NewError(http.StatusInternalServerError, "", "something went wrong")
+	}
+
+	writeSCIM(w, scimErr.StatusCode(), scimErr)
+}
@@ -0,0 +1,267 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"go-user-api/internal/model"
+	"go-user-api/internal/scim"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// GetGroups godoc
+// @Summary List or search groups
+// @Description Supports filter expressions such as displayName eq "Engineering", and pagination with startIndex and count.
+// @Tags scim
+// @Produce  json
+// @Security BearerAuth
+// @Param   filter      query  string  false  "SCIM filter expression"
+// @Param   startIndex  query  int     false  "1-based index of the first result"
+// @Param   count       query  int     false  "Maximum number of results"
+// @Success 200 {object} scim.ListResponse
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Router /scim/v2/Groups [get]
+func (h *SCIMHandler) GetGroups(w http.ResponseWriter, r *http.Request) {
+	filter, startIndex, count, err := scimListParams(r)
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	groups, err := h.groups.GetAllGroups(r.Context())
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	var resources []any
+	for _, g := range groups {
+		resource := scimGroup(g)
+		if scimMatches(filter, resource) {
+			resources = append(resources, resource)
+		}
+	}
+
+	writeSCIM(w, http.StatusOK, scim.NewListResponse(resources, startIndex, count))
+}
+
+// GetGroup godoc
+// @Summary Get a provisioned group
+// @Tags scim
+// @Produce  json
+// @Security BearerAuth
+// @Param   id  path  string  true  "Group ID"
+// @Success 200 {object} scim.Group
+// @Failure 404 {object} scim.Error
+// @Router /scim/v2/Groups/{id} [get]
+func (h *SCIMHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.findGroup(w, r)
+	if !ok {
+		return
+	}
+
+	writeSCIM(w, http.StatusOK, scimGroup(group))
+}
+
+// CreateGroup godoc
+// @Summary Provision a group
+// @Tags scim
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   group  body  scim.Group  true  "SCIM group"
+// @Success 201 {object} scim.Group
+// @Failure 400 {object} scim.Error
+// @Failure 409 {object} scim.Error
+// @Router /scim/v2/Groups [post]
+func (h *SCIMHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	var resource scim.Group
+	if err := json.NewDecoder(r.Body).Decode(&resource); err != nil {
+		writeSCIMError(w, scim.NewError(http.StatusBadRequest, "invalidSyntax", "Invalid input"))
+		return
+	}
+
+	group := &model.Group{}
+	if err := h.applyGroup(r.Context(), group, &resource); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	if err := h.groups.Create(r.Context(), group); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	created := scimGroup(group)
+	w.Header().Set("Location", created.Meta.Location)
+	writeSCIM(w, http.StatusCreated, created)
+}
+
+// ReplaceGroup godoc
+// @Summary Replace a provisioned group
+// @Tags scim
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   id     path  string      true  "Group ID"
+// @Param   group  body  scim.Group  true  "SCIM group"
+// @Success 200 {object} scim.Group
+// @Failure 400 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Router /scim/v2/Groups/{id} [put]
+func (h *SCIMHandler) ReplaceGroup(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.findGroup(w, r)
+	if !ok {
+		return
+	}
+
+	var resource scim.Group
+	if err := json.NewDecoder(r.Body).Decode(&resource); err != nil {
+		writeSCIMError(w, scim.NewError(http.StatusBadRequest, "invalidSyntax", "Invalid input"))
+		return
+	}
+
+	h.saveGroup(w, r, group, &resource)
+}
+
+// PatchGroup godoc
+// @Summary Modify a provisioned group
+// @Description Applies add, replace and remove operations, e.g. removing members[value eq "42"].
+// @Tags scim
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   id     path  string             true  "Group ID"
+// @Param   patch  body  scim.PatchRequest  true  "Patch operations"
+// @Success 200 {object} scim.Group
+// @Failure 400 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Router /scim/v2/Groups/{id} [patch]
+func (h *SCIMHandler) PatchGroup(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.findGroup(w, r)
+	if !ok {
+		return
+	}
+
+	var patched scim.Group
+	if err := patchResource(r, scimGroup(group), &patched); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	h.saveGroup(w, r, group, &patched)
+}
+
+func (h *SCIMHandler) saveGroup(w http.ResponseWriter, r *http.Request, group *model.Group, resource *scim.Group) {
+	if err := h.applyGroup(r.Context(), group, resource); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	if err := h.groups.Update(r.Context(), group); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	writeSCIM(w, http.StatusOK, scimGroup(group))
+}
+
+// DeleteGroup godoc
+// @Summary Delete a provisioned group
+// @Tags scim
+// @Security BearerAuth
+// @Param   id  path  string  true  "Group ID"
+// @Success 204
+// @Failure 404 {object} scim.Error
+// @Router /scim/v2/Groups/{id} [delete]
+func (h *SCIMHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.findGroup(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.groups.Delete(r.Context(), group.ID); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SCIMHandler) findGroup(w http.ResponseWriter, r *http.Request) (*model.Group, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err == nil {
+		if group, err := h.groups.Get(r.Context(), id); err == nil && group != nil {
+			return group, true
+		}
+	}
+
+	writeSCIMError(w, scim.NewError(http.StatusNotFound, "", "Group not found"))
+	return nil, false
+}
+
+// applyGroup copies the attributes of a SCIM group onto group. Members must
+// be existing users.
+func (h *SCIMHandler) applyGroup(ctx context.Context, group *model.Group, resource *scim.Group) error {
+	if resource.DisplayName == "" {
+		return scim.NewError(http.StatusBadRequest, "invalidValue", "displayName is required")
+	}
+
+	groups, err := h.groups.GetAllGroups(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, g := range groups {
+		if g.DisplayName == resource.DisplayName && g.ID != group.ID {
+			return scim.NewError(http.StatusConflict, "uniqueness", "a group with this displayName already exists")
+		}
+	}
+
+	members := []int{}
+	for _, member := range resource.Members {
+		id, err := strconv.Atoi(member.Value)
+		if err != nil {
+			return scim.NewError(http.StatusBadRequest, "invalidValue", "unknown member "+member.Value)
+		}
+
+		if slices.Contains(members, id) {
+			continue
+		}
+
+		if user, err := h.users.Get(ctx, id); err != nil || user == nil {
+			return scim.NewError(http.StatusBadRequest, "invalidValue", "unknown member "+member.Value)
+		}
+
+		members = append(members, id)
+	}
+
+	group.DisplayName = resource.DisplayName
+	group.ExternalID = resource.ExternalID
+	group.Members = members
+
+	return nil
+}
+
+func scimGroup(g *model.Group) scim.Group {
+	id := strconv.Itoa(g.ID)
+
+	members := []scim.MultiValued{}
+	for _, userID := range g.Members {
+		memberID := strconv.Itoa(userID)
+		members = append(members, scim.MultiValued{Value: memberID, Ref: scimBaseURL() + "/Users/" + memberID})
+	}
+
+	return scim.Group{
+		Schemas:     []string{scim.GroupSchema},
+		ID:          id,
+		ExternalID:  g.ExternalID,
+		DisplayName: g.DisplayName,
+		Members:     members,
+		Meta:        scimMeta("Group", "/Groups/"+id, g.CreatedAt, g.UpdatedAt),
+	}
+}
@@ -0,0 +1,148 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"go-user-api/internal/handler"
+	"go-user-api/internal/model"
+	"go-user-api/internal/routes"
+	"go-user-api/internal/scim"
+	"go-user-api/internal/testutils"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSCIMToken = "scim-token"
+
+func newSCIMRouter() (chi.Router, *testutils.MockUserRepo, *testutils.MockGroupRepo) {
+	users := &testutils.MockUserRepo{Users: []*model.User{
+		testutils.NewMockUser(1, "jane@example.com", "password1"),
+		testutils.NewMockUser(2, "john@example.com", "password1"),
+		testutils.NewMockUser(3, "ann@corp.example.com", "password1"),
+	}}
+	groups := &testutils.MockGroupRepo{}
+
+	r := chi.NewRouter()
+	routes.RegisterSCIMRoutes(r, handler.NewSCIMHandler(users, groups), testSCIMToken)
+	return r, users, groups
+}
+
+func scimRequest(r chi.Router, method string, path string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", scim.ContentType)
+	req.Header.Set("Authorization", "Bearer "+testSCIMToken)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestSCIMRequiresProvisioningToken(t *testing.T) {
+	r, _, _ := newSCIMRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/scim/v2/Users", nil)
+	req.Header.Set("Authorization", "Bearer not-the-token")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, scim.ContentType, rr.Header().Get("Content-Type"))
+}
+
+func TestSCIMListUsersWithFilterAndPagination(t *testing.T) {
+	r, _, _ := newSCIMRouter()
+
+	rr := scimRequest(r, http.MethodGet, "/scim/v2/Users?filter="+url.QueryEscape(`userName eq "jane@example.com"`), "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var list struct {
+		TotalResults int         `json:"totalResults"`
+		ItemsPerPage int         `json:"itemsPerPage"`
+		Resources    []scim.User `json:"Resources"`
+	}
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&list))
+	assert.Equal(t, 1, list.TotalResults)
+	assert.Equal(t, "1", list.Resources[0].ID)
+
+	rr = scimRequest(r, http.MethodGet, "/scim/v2/Users?startIndex=2&count=1&filter="+url.QueryEscape(`emails.value ew "example.com"`), "")
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&list))
+	assert.Equal(t, 3, list.TotalResults)
+	assert.Equal(t, 1, list.ItemsPerPage)
+	assert.Equal(t, "2", list.Resources[0].ID)
+
+	rr = scimRequest(r, http.MethodGet, "/scim/v2/Users?filter="+url.QueryEscape(`userName eq`), "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "invalidFilter")
+}
+
+func TestSCIMCreateAndDeactivateUser(t *testing.T) {
+	r, users, _ := newSCIMRouter()
+
+	rr := scimRequest(r, http.MethodPost, "/scim/v2/Users", `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"userName": "new@example.com",
+		"externalId": "hr-17",
+		"name": {"givenName": "New", "familyName": "Hire"}
+	}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Contains(t, rr.Header().Get("Location"), "/scim/v2/Users/4")
+	assert.Equal(t, "New Hire", users.Users[3].Name)
+	assert.Equal(t, "hr-17", users.Users[3].ExternalID)
+
+	// provisioning the same userName again conflicts
+	rr = scimRequest(r, http.MethodPost, "/scim/v2/Users", `{"userName": "new@example.com"}`)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "uniqueness")
+
+	rr = scimRequest(r, http.MethodPatch, "/scim/v2/Users/4", `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [{"op": "Replace", "value": {"active": false, "name.formatted": "Renamed Hire"}}]
+	}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.True(t, users.Users[3].Disabled)
+	assert.Equal(t, "Renamed Hire", users.Users[3].Name)
+
+	// disabled accounts cannot sign in
+	login := httptest.NewRecorder()
+	handler.NewAuthRouteHandler(users).Login(login, httptest.NewRequest(http.MethodPost, "/auth/login",
+		strings.NewReader(`{"email": "jane@example.com", "password": "password1"}`)))
+	assert.Equal(t, http.StatusOK, login.Code)
+
+	users.Users[0].Disabled = true
+	login = httptest.NewRecorder()
+	handler.NewAuthRouteHandler(users).Login(login, httptest.NewRequest(http.MethodPost, "/auth/login",
+		strings.NewReader(`{"email": "jane@example.com", "password": "password1"}`)))
+	assert.Equal(t, http.StatusForbidden, login.Code)
+
+	rr = scimRequest(r, http.MethodDelete, "/scim/v2/Users/4", "")
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = scimRequest(r, http.MethodGet, "/scim/v2/Users/4", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestSCIMGroupMembership(t *testing.T) {
+	r, _, groups := newSCIMRouter()
+
+	rr := scimRequest(r, http.MethodPost, "/scim/v2/Groups", `{"displayName": "Engineering", "members": [{"value": "1"}, {"value": "2"}]}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, []int{1, 2}, groups.Groups[0].Members)
+
+	rr = scimRequest(r, http.MethodPatch, "/scim/v2/Groups/1", `{"Operations": [
+		{"op": "add", "path": "members", "value": [{"value": "3"}, {"value": "1"}]},
+		{"op": "remove", "path": "members[value eq \"2\"]"}
+	]}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, []int{1, 3}, groups.Groups[0].Members)
+
+	// unknown users cannot become members
+	rr = scimRequest(r, http.MethodPatch, "/scim/v2/Groups/1", `{"Operations": [{"op": "add", "path": "members", "value": [{"value": "99"}]}]}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = scimRequest(r, http.MethodGet, "/scim/v2/Groups?filter="+url.QueryEscape(`members[value eq "3"] and displayName sw "eng"`), "")
+	assert.Contains(t, rr.Body.String(), `"totalResults":1`)
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"encoding/json"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/repository"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type SessionHandler struct {
+	sessions repository.SessionRepository
+}
+
+func NewSessionHandler(sessions repository.SessionRepository) *SessionHandler {
+	return &SessionHandler{sessions: sessions}
+}
+
+// GetSessions godoc
+// @Summary List your active sessions
+// @Description Lists the devices you are signed in on. The session of the calling token is marked as current.
+// @Tags sessions
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} model.Session
+// @Failure 401 {string} string "Unauthorized"
+// @Router /auth/sessions [get]
+func (h *SessionHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	h.writeSessions(w, r, userID)
+}
+
+// DeleteSession godoc
+// @Summary Revoke one of your sessions
+// @Description Signs the device out; tokens of the session stop working immediately.
+// @Tags sessions
+// @Security BearerAuth
+// @Param   id  path  int  true  "Session ID"
+// @Success 204
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Session not found"
+// @Router /auth/sessions/{id} [delete]
+func (h *SessionHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	h.revoke(w, r, id, userID)
+}
+
+// GetUserSessions godoc
+// @Summary List a user's active sessions
+// @Tags admin
+// @Produce  json
+// @Security BearerAuth
+// @Param   id  path  int  true  "User ID"
+// @Success 200 {array} model.Session
+// @Failure 403 {string} string "Forbidden"
+// @Router /admin/users/{id}/sessions [get]
+func (h *SessionHandler) GetUserSessions(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	h.writeSessions(w, r, userID)
+}
+
+// DeleteUserSession godoc
+// @Summary Revoke a user's session
+// @Tags admin
+// @Security BearerAuth
+// @Param   id         path  int  true  "User ID"
+// @Param   sessionId  path  int  true  "Session ID"
+// @Success 204
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Session not found"
+// @Router /admin/users/{id}/sessions/{sessionId} [delete]
+func (h *SessionHandler) DeleteUserSession(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	id, _ := strconv.Atoi(chi.URLParam(r, "sessionId"))
+
+	h.revoke(w, r, id, userID)
+}
+
+func (h *SessionHandler) writeSessions(w http.ResponseWriter, r *http.Request, userID int) {
+	sessions, err := h.sessions.GetActiveByUser(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	if sessions == nil {
+		sessions = []*model.Session{}
+	}
+
+	if claims, ok := r.Context().Value(middleware.ClaimsKey).(*auth.Claims); ok {
+		for _, s := range sessions {
+			s.Current = s.ID == claims.SessionID
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+func (h *SessionHandler) revoke(w http.ResponseWriter, r *http.Request, id int, userID int) {
+	if err := h.sessions.Revoke(r.Context(), id, userID); err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,89 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/testutils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func loginWithSession(t *testing.T, h *handler.AuthRouteHandler, userAgent string) *auth.Claims {
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email": "jane@example.com", "password": "password1"}`))
+	req.RemoteAddr = "203.0.113.7:51234"
+	req.Header.Set("User-Agent", userAgent)
+
+	rr := httptest.NewRecorder()
+	h.Login(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp map[string]string
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+
+	claims, err := auth.ParseToken(resp["token"])
+	assert.NoError(t, err)
+	return claims
+}
+
+func TestLoginCreatesSession(t *testing.T) {
+	users := &testutils.MockUserRepo{Users: []*model.User{testutils.NewMockUser(7, "jane@example.com", "password1")}}
+	sessions := &testutils.MockSessionRepo{}
+	h := handler.NewAuthRouteHandler(users, handler.WithSessions(sessions))
+
+	claims := loginWithSession(t, h, "Firefox")
+	assert.Equal(t, 1, claims.SessionID)
+	assert.Equal(t, "203.0.113.7", sessions.Sessions[0].IP)
+	assert.Equal(t, "Firefox", sessions.Sessions[0].UserAgent)
+	assert.NoError(t, middleware.SessionCheck(sessions)(context.Background(), claims))
+}
+
+func TestRevokedSessionRejectsToken(t *testing.T) {
+	users := &testutils.MockUserRepo{Users: []*model.User{testutils.NewMockUser(7, "jane@example.com", "password1")}}
+	sessions := &testutils.MockSessionRepo{}
+	h := handler.NewAuthRouteHandler(users, handler.WithSessions(sessions))
+
+	laptop := loginWithSession(t, h, "Firefox")
+	phone := loginWithSession(t, h, "Mobile Safari")
+
+	sessionHandler := handler.NewSessionHandler(sessions)
+	r := chi.NewRouter()
+	r.Get("/auth/sessions", sessionHandler.GetSessions)
+	r.Delete("/auth/sessions/{id}", sessionHandler.DeleteSession)
+
+	asUser := func(req *http.Request, claims *auth.Claims) *http.Request {
+		ctx := context.WithValue(req.Context(), middleware.UserIDKey, claims.UserID)
+		return req.WithContext(context.WithValue(ctx, middleware.ClaimsKey, claims))
+	}
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, asUser(httptest.NewRequest(http.MethodGet, "/auth/sessions", nil), laptop))
+	var listed []model.Session
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&listed))
+	assert.Len(t, listed, 2)
+	for _, s := range listed {
+		assert.Equal(t, s.ID == laptop.SessionID, s.Current)
+	}
+
+	// another user's session is not found
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, asUser(httptest.NewRequest(http.MethodDelete, "/auth/sessions/2", nil), &auth.Claims{UserID: 8}))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	// the laptop signs out the phone
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, asUser(httptest.NewRequest(http.MethodDelete, "/auth/sessions/2", nil), laptop))
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	check := middleware.SessionCheck(sessions)
+	assert.Error(t, check(context.Background(), phone))
+	assert.NoError(t, check(context.Background(), laptop))
+}
@@ -0,0 +1,77 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/testutils"
+	"go-user-api/internal/tracing"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	_, err := tracing.Setup(context.Background(), "none")
+	require.NoError(t, err)
+
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(tracing.NewProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	users := &testutils.MockUserRepo{Users: []*model.User{testutils.NewMockUser(5, "jane@example.com", "password1")}}
+	authHandler := handler.NewAuthRouteHandler(users)
+
+	r := chi.NewRouter()
+	r.Use(middleware.Tracing)
+	r.Post("/auth/login", authHandler.Login)
+	r.With(middleware.NewJWTAuth().Middleware).Get("/auth/profile", authHandler.GetUserProfile)
+
+	body, _ := json.Marshal(map[string]string{"email": "jane@example.com", "password": "password1"})
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(string(body)))
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+
+	server, ok := spans["POST /auth/login"]
+	require.True(t, ok, "server span named after the route")
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID().String(), "continues the incoming trace")
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID().String())
+	assert.Contains(t, server.Attributes, attribute.String("http.route", "/auth/login"))
+	assert.Contains(t, server.Attributes, attribute.Int("http.response.status_code", http.StatusOK))
+
+	for _, name := range []string{"bcrypt.compare", "jwt.sign"} {
+		require.Contains(t, spans, name)
+		assert.Equal(t, server.SpanContext.SpanID(), spans[name].Parent.SpanID(), name)
+	}
+	assert.Contains(t, spans["bcrypt.compare"].Attributes, attribute.Bool("auth.password.match", true))
+
+	exporter.Reset()
+	assert.Equal(t, http.StatusUnauthorized, sendAs(r, http.MethodGet, "/auth/profile", "garbage", "").Code)
+	spans = map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	assert.Contains(t, spans["jwt.parse"].Attributes, attribute.Bool("auth.token.valid", false))
+	assert.Contains(t, spans, "GET /auth/profile")
+}
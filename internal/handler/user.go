@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"go-user-api/internal/audit"
 	"go-user-api/internal/model"
 	"go-user-api/internal/repository"
 	"net/http"
@@ -11,11 +12,12 @@ import (
 )
 
 type UserHandler struct {
-	repo repository.UserRepository
+	repo     repository.UserRepository
+	auditLog audit.Recorder
 }
 
-func NewUserHandler(repo repository.UserRepository) *UserHandler {
-	return &UserHandler{repo: repo}
+func NewUserHandler(repo repository.UserRepository, auditLog audit.Recorder) *UserHandler {
+	return &UserHandler{repo: repo, auditLog: auditLog}
 }
 
 // CreateUser godoc
@@ -27,6 +29,7 @@ func NewUserHandler(repo repository.UserRepository) *UserHandler {
 // @Param   user  body  model.User  true  "User Data"
 // @Success 200 {object} model.User
 // @Failure 400 {string} string "Invalid input"
+// @Failure 403 {string} string "Only the default organization takes new users"
 // @Failure 500 {string} string "Failed to create user"
 // @Router /users [post]
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
@@ -37,11 +40,15 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	u.EmailVerified = false
+
 	if err := h.repo.Create(r.Context(), &u); err != nil {
 		http.Error(w, "Failed to create user", http.StatusInternalServerError)
 		return
 	}
 
+	recordChange(r, h.auditLog, newAuditEvent(r, audit.ActionUserCreate, audit.TargetUser, strconv.Itoa(u.ID)), nil, u)
+
 	json.NewEncoder(w).Encode(u)
 }
 
@@ -107,12 +114,23 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, err := h.repo.Get(r.Context(), id)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
 	u.ID = id
 	if err := h.repo.Update(r.Context(), &u); err != nil {
 		http.Error(w, "Update failed", http.StatusInternalServerError)
 		return
 	}
 
+	// Update only writes the name and email
+	after := *before
+	after.Name, after.Email = u.Name, u.Email
+	recordChange(r, h.auditLog, newAuditEvent(r, audit.ActionUserUpdate, audit.TargetUser, strconv.Itoa(id)), before, after)
+
 	json.NewEncoder(w).Encode(u)
 }
 
@@ -128,10 +146,13 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 // @Router /users/{id} [delete]
 func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	before, _ := h.repo.Get(r.Context(), id)
 	if err := h.repo.Delete(r.Context(), id); err != nil {
 		http.Error(w, "Delete failed", http.StatusInternalServerError)
 		return
 	}
 
+	recordChange(r, h.auditLog, newAuditEvent(r, audit.ActionUserDelete, audit.TargetUser, strconv.Itoa(id)), before, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 }
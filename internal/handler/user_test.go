@@ -17,7 +17,7 @@ import (
 // ---- ✅ Test CreateUser ----
 
 func TestCreateUser(t *testing.T) {
-	handler := handler.NewUserHandler(&testutils.MockUserRepo{})
+	handler := handler.NewUserHandler(&testutils.MockUserRepo{}, &testutils.MockAuditRepo{})
 
 	// Prepare input user JSON
 	inputUser := model.User{
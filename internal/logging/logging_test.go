@@ -0,0 +1,73 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"go-user-api/internal/logging"
+	"go-user-api/internal/model"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	_, err := logging.New(&bytes.Buffer{}, "verbose", "")
+	assert.Error(t, err)
+	_, err = logging.New(&bytes.Buffer{}, "", "xml")
+	assert.Error(t, err)
+
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "warn", "text")
+	require.NoError(t, err)
+	logger.Info("hidden")
+	logger.Warn("shown")
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.Contains(t, buf.String(), "level=WARN msg=shown")
+}
+
+func TestRedact(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "", "json")
+	require.NoError(t, err)
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer abc")
+	header.Set("Cookie", "session=abc")
+	header.Set("Accept", "application/json")
+
+	logger.Info("request",
+		"password", "hunter22",
+		slog.Group("oauth", "refresh_token", "abc", "client_id", "web"),
+		"ClientSecret", "abc",
+		"header", header,
+		"user", model.User{ID: 5, Email: "jane@example.com", Password: "$2a$hash"},
+	)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, logging.Redacted, record["password"])
+	assert.Equal(t, map[string]any{"refresh_token": logging.Redacted, "client_id": "web"}, record["oauth"])
+	assert.Equal(t, logging.Redacted, record["ClientSecret"])
+	assert.Equal(t, map[string]any{
+		"Authorization": []any{logging.Redacted},
+		"Cookie":        []any{logging.Redacted},
+		"Accept":        []any{"application/json"},
+	}, record["header"])
+	assert.NotContains(t, buf.String(), "$2a$hash")
+	assert.NotContains(t, buf.String(), "jane@example.com")
+}
+
+func TestContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := logging.New(&buf, "", "json")
+
+	assert.Equal(t, slog.Default(), logging.FromContext(context.Background()))
+
+	ctx := logging.With(logging.WithLogger(context.Background(), logger), "user_id", 5)
+	logging.FromContext(ctx).Info("hello")
+	assert.Contains(t, buf.String(), `"user_id":5`)
+}
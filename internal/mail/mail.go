@@ -0,0 +1,83 @@
+// Package mail sends the emails of login flows. Delivery is behind Mailer so
+// deployments can plug in their provider.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"go-user-api/internal/logging"
+	"log/slog"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// FromEnv returns an SMTP mailer when SMTP_ADDR is set, and otherwise one
+// that only logs messages, which is enough for development.
+func FromEnv() Mailer {
+	addr := os.Getenv("SMTP_ADDR")
+	if addr == "" {
+		slog.Warn("SMTP_ADDR not set, emails are logged instead of sent")
+		return LogMailer{}
+	}
+
+	return &SMTPMailer{
+		Addr:     addr,
+		From:     os.Getenv("SMTP_FROM"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+	}
+}
+
+// LogMailer writes messages to the log. Links in them are credentials, so
+// it must not be used in production.
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	logging.FromContext(ctx).Info("mail", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+// SMTPMailer sends plain text mail through an SMTP relay, authenticating
+// with PLAIN auth when a username is set.
+type SMTPMailer struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+func (m *SMTPMailer) Send(_ context.Context, msg Message) error {
+	if strings.ContainsAny(msg.To+msg.Subject, "\r\n") {
+		return fmt.Errorf("mail: header values must not contain line breaks")
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+
+	body := "From: " + m.From + "\r\n" +
+		"To: " + msg.To + "\r\n" +
+		"Subject: " + msg.Subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" + msg.Body
+
+	return smtp.SendMail(m.Addr, auth, m.From, []string{msg.To}, []byte(body))
+}
@@ -0,0 +1,77 @@
+// Package metrics defines the Prometheus metrics of the service and serves
+// them in the text exposition format.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every metric of the service, plus the Go runtime and
+// process metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route pattern and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route pattern and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// Logins counts sign-ins by result, success or failure, and reason, the
+	// outcome recorded in the login history.
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_logins_total",
+		Help: "Sign-ins by result and reason.",
+	}, []string{"result", "reason"})
+
+	TokensIssued = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_tokens_issued_total",
+		Help: "Issued tokens by type.",
+	}, []string{"type"})
+
+	TokenValidations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_token_validations_total",
+		Help: "Access tokens checked by the authentication middleware, by result.",
+	}, []string{"result"})
+
+	// PasswordHashDuration has buckets around the cost of bcrypt, which
+	// takes about a second at cost 14.
+	PasswordHashDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "auth_password_hash_duration_seconds",
+		Help:    "Duration of bcrypt hashing and comparison.",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 4},
+	}, []string{"operation"})
+
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_rate_limited_total",
+		Help: "Requests rejected by a rate limit, by policy.",
+	}, []string{"policy"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		Logins,
+		TokensIssued,
+		TokenValidations,
+		PasswordHashDuration,
+		RateLimited,
+	)
+}
+
+// Handler serves the metrics of Registry.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
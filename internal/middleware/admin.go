@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"go-user-api/internal/model"
+	"go-user-api/internal/repository"
+	"net/http"
+)
+
+// RequireAdmin only lets through users with the admin role. It must run
+// after JWTAuth.Middleware. The role is looked up on every request so that
+// demoting an admin takes effect immediately.
+func RequireAdmin(users repository.UserRepository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := r.Context().Value(UserIDKey).(int)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			user, err := users.Get(r.Context(), userID)
+			if err != nil || user == nil || user.Role != model.RoleAdmin {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// BodyLimit caps request bodies at n bytes. Requests declaring a larger body
+// are refused with a 413 problem response up front. For the others the
+// reader fails once the cap is passed, and the error response the handler
+// writes for that is replaced by the same 413.
+func BodyLimit(n int64) func(http.Handler) http.Handler {
+	detail := fmt.Sprintf("The request body exceeds the limit of %d bytes.", n)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				w.Header().Set("Connection", "close")
+				writeProblem(w, r, http.StatusRequestEntityTooLarge, detail)
+				return
+			}
+
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body := &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, n)}
+			r.Body = body
+			next.ServeHTTP(&bodyLimitWriter{ResponseWriter: w, r: r, body: body, detail: detail}, r)
+		})
+	}
+}
+
+// limitedBody notes when the body passed the limit.
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		b.exceeded = true
+	}
+
+	return n, err
+}
+
+// bodyLimitWriter turns an error response into a 413 if the body passed the
+// limit, and drops what the handler writes after it.
+type bodyLimitWriter struct {
+	http.ResponseWriter
+	r      *http.Request
+	body   *limitedBody
+	detail string
+
+	wroteHeader bool
+	replaced    bool
+}
+
+func (w *bodyLimitWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if w.body.exceeded && status >= http.StatusBadRequest {
+		w.replaced = true
+		writeProblem(w.ResponseWriter, w.r, http.StatusRequestEntityTooLarge, w.detail)
+		return
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *bodyLimitWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.replaced {
+		return len(p), nil
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *bodyLimitWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,62 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyLimit(t *testing.T) {
+	var created []map[string]string
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(middleware.BodyLimit(64))
+	r.Post("/users", func(w http.ResponseWriter, r *http.Request) {
+		var input map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		created = append(created, input)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	send := func(body io.Reader, contentLength int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/users", body)
+		if contentLength != 0 {
+			req.ContentLength = contentLength
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assertTooLarge := func(rr *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+
+		var problem model.Problem
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, problem.Status)
+		assert.NotEmpty(t, problem.RequestID)
+	}
+
+	body := `{"name": "Jane", "email": "jane@example.com", "password": "` + strings.Repeat("x", 100) + `"}`
+	assertTooLarge(send(strings.NewReader(body), 0))
+
+	// without a Content-Length the limit is only noticed while decoding
+	assertTooLarge(send(io.NopCloser(strings.NewReader(body)), -1))
+
+	assert.Empty(t, created)
+	assert.Equal(t, http.StatusCreated, send(strings.NewReader(`{"name": "Jane"}`), 0).Code)
+}
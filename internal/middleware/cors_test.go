@@ -0,0 +1,79 @@
+package middleware_test
+
+import (
+	"go-user-api/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORS(t *testing.T) {
+	opts := middleware.DefaultCORSOptions()
+	opts.AllowedOrigins = []string{"https://app.example.com", "https://*.example.org"}
+	opts.AllowCredentials = true
+
+	r := chi.NewRouter()
+	r.Use(middleware.CORS(opts))
+	r.Post("/users", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) })
+
+	send := func(method string, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/users", nil)
+		req.Header.Set("Origin", origin)
+		if method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := send(http.MethodOptions, "https://app.example.com")
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Equal(t, "https://app.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", rr.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Contains(t, rr.Header().Get("Access-Control-Allow-Methods"), http.MethodDelete)
+	assert.Contains(t, rr.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+	assert.Equal(t, "600", rr.Header().Get("Access-Control-Max-Age"))
+
+	rr = send(http.MethodPost, "https://eu.example.org")
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "https://eu.example.org", rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, rr.Header().Get("Access-Control-Expose-Headers"), "RateLimit-Remaining")
+	assert.Contains(t, rr.Header().Values("Vary"), "Origin")
+
+	for _, origin := range []string{"https://evil.com", "https://example.org.evil.com", "http://eu.example.org"} {
+		rr = send(http.MethodOptions, origin)
+		assert.Equal(t, http.StatusNoContent, rr.Code, origin)
+		assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"), origin)
+		assert.Empty(t, rr.Header().Get("Access-Control-Allow-Methods"), origin)
+	}
+}
+
+func TestCORSOptionsValidate(t *testing.T) {
+	opts := middleware.DefaultCORSOptions()
+	opts.AllowedOrigins = []string{"*"}
+	assert.NoError(t, opts.Validate())
+
+	// every site could make requests with the user's cookies
+	opts.AllowCredentials = true
+	assert.Error(t, opts.Validate())
+
+	opts.AllowedOrigins = []string{"https://app.example.com"}
+	assert.NoError(t, opts.Validate())
+}
+
+func TestCORSWildcardOrigin(t *testing.T) {
+	opts := middleware.DefaultCORSOptions()
+	opts.AllowedOrigins = []string{"*"}
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("Origin", "https://any.example.net")
+	rr := httptest.NewRecorder()
+	middleware.CORS(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rr, req)
+
+	assert.Equal(t, "*", rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, rr.Header().Get("Access-Control-Allow-Credentials"))
+}
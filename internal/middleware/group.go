@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"go-user-api/internal/repository"
+	"net/http"
+)
+
+// RequireGroup only lets through users who are effective members of one of
+// the named groups, directly or through a subgroup. It must run after
+// JWTAuth.Middleware. Like RequireAdmin it looks membership up on every
+// request rather than trusting the groups claim, which may be stale.
+func RequireGroup(groups repository.GroupRepository, names ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := r.Context().Value(UserIDKey).(int)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			effective, err := groups.GetEffectiveGroups(r.Context(), userID)
+			if err != nil {
+				http.Error(w, "something went wrong", http.StatusInternalServerError)
+				return
+			}
+
+			for _, g := range effective {
+				for _, name := range names {
+					if g.DisplayName == name {
+						next.ServeHTTP(w, r)
+						return
+					}
+				}
+			}
+
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		})
+	}
+}
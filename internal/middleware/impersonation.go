@@ -0,0 +1,17 @@
+package middleware
+
+import "net/http"
+
+// DenyImpersonation guards sensitive actions, such as changing credentials or
+// revoking sessions, that an admin must not take on a user's behalf. It must
+// run after JWTAuth.Middleware.
+func DenyImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, impersonated := r.Context().Value(ActorIDKey).(int); impersonated {
+			http.Error(w, "Not allowed while impersonating a user", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
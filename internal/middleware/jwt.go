@@ -2,32 +2,187 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"go-user-api/internal/auth"
+	"go-user-api/internal/logging"
+	"go-user-api/internal/metrics"
+	"go-user-api/internal/tenant"
 	"net/http"
 	"strings"
 )
 
 type contextKey string
 
+// UserIDKey holds the ID of the user the request acts as. With an
+// impersonation token that is the impersonated user, not the admin making
+// the request, so permission checks apply to the user.
 const UserIDKey = contextKey("userID")
 
-func JWTAuthMiddleware(next http.Handler) http.Handler {
+// ActorIDKey holds the ID of the admin behind UserIDKey when the request is
+// made with an impersonation token, and is unset otherwise.
+const ActorIDKey = contextKey("actorID")
+
+// ClaimsKey holds the *auth.Claims of the authenticated token.
+const ClaimsKey = contextKey("claims")
+
+// TokenCheck can reject a token that is otherwise valid, e.g. because it was revoked.
+type TokenCheck func(ctx context.Context, claims *auth.Claims) error
+
+// JWTAuth authenticates requests by their token. Besides verifying the
+// signature it runs checks that can reject a token, e.g. SessionCheck and
+// RevocationCheck.
+type JWTAuth struct {
+	checks       []TokenCheck
+	impersonated func(http.Handler) http.Handler
+}
+
+func NewJWTAuth(checks ...TokenCheck) *JWTAuth {
+	return &JWTAuth{checks: checks}
+}
+
+// OnImpersonation wraps the handling of requests made with an impersonation
+// token in mw, once they are authenticated, e.g. to audit what admins do as
+// users.
+func (a *JWTAuth) OnImpersonation(mw func(http.Handler) http.Handler) *JWTAuth {
+	a.impersonated = mw
+	return a
+}
+
+// Middleware accepts a Bearer token, or else the session cookie set in
+// cookie auth mode. Browsers attach cookies to cross-site requests, so
+// cookie-authenticated unsafe requests must also carry the CSRF token.
+// Access tokens issued to OAuth clients are refused: they carry only what
+// the user consented to, so they are good for OAuthMiddleware routes only.
+func (a *JWTAuth) Middleware(next http.Handler) http.Handler {
+	return a.middleware(next, false)
+}
+
+// OAuthMiddleware is Middleware for the routes OAuth clients may call with
+// the access token a user granted them, such as userinfo.
+func (a *JWTAuth) OAuthMiddleware(next http.Handler) http.Handler {
+	return a.middleware(next, true)
+}
+
+func (a *JWTAuth) middleware(next http.Handler, allowClients bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+		tokenStr, fromCookie := requestToken(r)
+		if tokenStr == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 
-		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-		userID, err := auth.DecodeJWT(tokenStr)
+		if fromCookie && !isSafeMethod(r.Method) && !auth.CheckCSRFToken(tokenStr, r.Header.Get(auth.CSRFHeader)) {
+			http.Error(w, "CSRF token missing or invalid", http.StatusForbidden)
+			return
+		}
+
+		claims, err := a.verify(r.Context(), tokenStr)
 		if err != nil {
+			metrics.TokenValidations.WithLabelValues("invalid").Inc()
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
 			http.Error(w, "Invalid Token", http.StatusUnauthorized)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), UserIDKey, userID)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		metrics.TokenValidations.WithLabelValues("valid").Inc()
+
+		if claims.ClientID != "" && !allowClients {
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+			http.Error(w, "Token was issued to an OAuth client", http.StatusForbidden)
+			return
+		}
+
+		ctx := r.Context()
+		if claims.TenantID != 0 {
+			if ctx, err = tenant.Bind(ctx, claims.TenantID); err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, "Invalid Token", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		ctx = tenant.WithUser(ctx, claims.UserID)
+		ctx = context.WithValue(ctx, UserIDKey, claims.UserID)
+		ctx = context.WithValue(ctx, ClaimsKey, claims)
+		ctx = logging.With(ctx, "user_id", claims.UserID, "tenant_id", tenant.ID(ctx))
+
+		h := next
+		if actorID := claims.ActorID(); actorID != 0 {
+			ctx = context.WithValue(ctx, ActorIDKey, actorID)
+			ctx = logging.With(ctx, "actor_id", actorID)
+			logging.FromContext(ctx).Info("admin impersonating user")
+
+			if a.impersonated != nil {
+				h = a.impersonated(next)
+			}
+		}
+
+		h.ServeHTTP(w, r.WithContext(ctx))
 
 	})
 }
+
+// Claims returns the claims of a valid token on the request, or nil. It is
+// for middleware that runs before Middleware, or on routes that also serve
+// anonymous requests, and so is left out of the validation metrics.
+func (a *JWTAuth) Claims(r *http.Request) *auth.Claims {
+	tokenStr, _ := requestToken(r)
+	if tokenStr == "" {
+		return nil
+	}
+
+	claims, err := a.verify(r.Context(), tokenStr)
+	if err != nil {
+		return nil
+	}
+
+	return claims
+}
+
+// verify parses the token and runs the checks on it.
+func (a *JWTAuth) verify(ctx context.Context, tokenStr string) (*auth.Claims, error) {
+	claims, err := auth.ParseTokenContext(ctx, tokenStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.UserID == 0 {
+		return nil, errors.New("token has no user")
+	}
+
+	for _, check := range a.checks {
+		if err := check(ctx, claims); err != nil {
+			return nil, err
+		}
+	}
+
+	return claims, nil
+}
+
+// requestToken returns the token of the request and whether it came from the
+// session cookie. An Authorization header takes precedence.
+func requestToken(r *http.Request) (string, bool) {
+	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+		token, ok := strings.CutPrefix(authHeader, "Bearer ")
+		if !ok {
+			return "", false
+		}
+		return token, false
+	}
+
+	if cookie, err := r.Cookie(auth.SessionCookie); err == nil && cookie.Value != "" {
+		return cookie.Value, true
+	}
+
+	return "", false
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,102 @@
+package middleware_test
+
+import (
+	"context"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/tenant"
+	"go-user-api/internal/testutils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTAuthChecks(t *testing.T) {
+	grants := testutils.NewMockGrantRepo()
+	sessions := &testutils.MockSessionRepo{}
+	session := &model.Session{UserID: 5, ExpiresAt: time.Now().Add(time.Hour)}
+	require.NoError(t, sessions.Create(context.Background(), session))
+
+	checked := middleware.NewJWTAuth(middleware.SessionCheck(sessions), middleware.RevocationCheck(grants))
+	unchecked := middleware.NewJWTAuth()
+
+	send := func(jwtAuth *middleware.JWTAuth, token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		jwtAuth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	token, _ := auth.GenerateJWT(5, auth.WithSessionID(session.ID))
+	assert.Equal(t, http.StatusOK, send(checked, token))
+
+	claims, err := auth.ParseToken(token)
+	require.NoError(t, err)
+	require.NoError(t, grants.RevokeAccessToken(context.Background(), claims.ID, claims.ExpiresAt.Time))
+	assert.Equal(t, http.StatusUnauthorized, send(checked, token))
+	assert.Equal(t, http.StatusOK, send(unchecked, token))
+
+	ended, _ := auth.GenerateJWT(5, auth.WithSessionID(session.ID))
+	assert.Equal(t, http.StatusOK, send(checked, ended))
+	require.NoError(t, sessions.Revoke(context.Background(), session.ID, 5))
+	assert.Equal(t, http.StatusUnauthorized, send(checked, ended))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+ended)
+	assert.Nil(t, checked.Claims(req))
+	assert.NotNil(t, unchecked.Claims(req))
+}
+
+func TestActiveUserCheck(t *testing.T) {
+	jane := testutils.NewMockUser(5, "jane@example.com", "password1")
+	acmeJane := testutils.NewMockUser(6, "jane@acme.test", "password1")
+	acmeJane.OrganizationID = 2
+	users := &testutils.MockUserRepo{Users: []*model.User{jane, acmeJane}}
+	jwtAuth := middleware.NewJWTAuth(middleware.ActiveUserCheck(users))
+
+	send := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		jwtAuth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	token, _ := auth.GenerateJWT(5)
+	acmeToken, _ := auth.GenerateJWT(6, auth.WithTenant(2))
+	assert.Equal(t, http.StatusOK, send(token))
+	assert.Equal(t, http.StatusOK, send(acmeToken), "looked up in the token's organization")
+
+	// disabling a user, e.g. with SCIM active:false, ends their tokens
+	jane.Disabled = true
+	assert.Equal(t, http.StatusUnauthorized, send(token))
+	jane.Disabled = false
+	assert.Equal(t, http.StatusOK, send(token))
+
+	require.NoError(t, users.Delete(context.Background(), 5))
+	assert.Equal(t, http.StatusUnauthorized, send(token))
+}
+
+func TestSessionCheckUsesTokenOrganization(t *testing.T) {
+	sessions := &testutils.MockSessionRepo{}
+	session := &model.Session{UserID: 6, ExpiresAt: time.Now().Add(time.Hour)}
+	require.NoError(t, sessions.Create(tenant.WithID(context.Background(), 2), session))
+	check := middleware.SessionCheck(sessions)
+
+	acmeToken, _ := auth.GenerateJWT(6, auth.WithTenant(2), auth.WithSessionID(session.ID))
+	claims, err := auth.ParseToken(acmeToken)
+	require.NoError(t, err)
+	assert.NoError(t, check(context.Background(), claims), "looked up in the token's organization")
+
+	// the same session ID means nothing in another organization
+	token, _ := auth.GenerateJWT(6, auth.WithSessionID(session.ID))
+	claims, err = auth.ParseToken(token)
+	require.NoError(t, err)
+	assert.Error(t, check(context.Background(), claims))
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"go-user-api/internal/logging"
+	"log/slog"
+	"net/http"
+)
+
+// RequestLogger gives every request a logger, available through
+// logging.FromContext, that records its request ID, method, path and route
+// pattern. ResolveTenant and JWTAuth add the tenant and user.
+func RequestLogger(base *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, _ := matchRoute(r)
+
+			requestID := GetRequestID(r.Context())
+			if requestID == "" {
+				requestID = r.Header.Get(RequestIDHeader)
+			}
+
+			logger := base.With(
+				slog.String("request_id", requestID),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("route", route),
+			)
+
+			next.ServeHTTP(w, r.WithContext(logging.WithLogger(r.Context(), logger)))
+		})
+	}
+}
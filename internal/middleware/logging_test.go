@@ -0,0 +1,44 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/logging"
+	"go-user-api/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "", "json")
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestLogger(logger))
+	r.With(middleware.NewJWTAuth().Middleware).Get("/things/{id}", func(w http.ResponseWriter, r *http.Request) {
+		logging.FromContext(r.Context()).Info("handled", "token", "abc")
+	})
+
+	token, _ := auth.GenerateJWT(5, auth.WithTenant(3))
+	req := httptest.NewRequest(http.MethodGet, "/things/42", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Request-ID", "req-1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "handled", record["msg"])
+	assert.Equal(t, "req-1", record["request_id"])
+	assert.Equal(t, "/things/{id}", record["route"])
+	assert.Equal(t, "/things/42", record["path"])
+	assert.Equal(t, float64(5), record["user_id"])
+	assert.Equal(t, float64(3), record["tenant_id"])
+	assert.Equal(t, logging.Redacted, record["token"])
+}
@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"context"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/logging"
+	"go-user-api/internal/policy"
+	"go-user-api/internal/repository"
+	"go-user-api/internal/tenant"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Policy decides every request with the policy engine. It runs before
+// routing, so it matches the route pattern itself, and reads the subject
+// from the request's token without requiring one; routes still authenticate
+// with JWTAuth. Denied requests get 403, or, in shadow mode, only
+// a log line.
+func Policy(engine *policy.Engine, jwtAuth *JWTAuth, users repository.UserRepository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, params := matchRoute(r)
+			claims := jwtAuth.Claims(r)
+
+			input := policy.Input{
+				Subject:  subjectAttributes(claims),
+				Request:  requestAttributes(r, route, params),
+				Resource: resourceAttributes(r.Context(), users, claims, route, params),
+			}
+
+			decision := engine.Evaluate(r.Method, route, input)
+			logger := logging.FromContext(r.Context()).With(
+				slog.String("policy_version", decision.Version),
+				slog.Any("user_id", input.Subject["user_id"]),
+				slog.Bool("allowed", decision.Allowed),
+				slog.String("rule", decision.Rule),
+			)
+
+			if !decision.Enforced {
+				logger.Info("policy decision (shadow)")
+			}
+
+			if decision.Enforced && !decision.Allowed {
+				logger.Warn("policy denied request")
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// matchRoute returns the route pattern the router will pick for the request
+// and its URL parameters, or an empty pattern if none matches.
+func matchRoute(r *http.Request) (string, map[string]string) {
+	params := map[string]string{}
+
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil {
+		return "", params
+	}
+
+	match := chi.NewRouteContext()
+	if !rctx.Routes.Match(match, r.Method, r.URL.Path) {
+		return "", params
+	}
+
+	for i, key := range match.URLParams.Keys {
+		if key != "*" {
+			params[key] = match.URLParams.Values[i]
+		}
+	}
+
+	return match.RoutePattern(), params
+}
+
+// subjectAttributes always sets every key, so policies need no has() checks
+// for anonymous requests.
+func subjectAttributes(claims *auth.Claims) map[string]any {
+	if claims == nil {
+		return map[string]any{
+			"authenticated": false,
+			"user_id":       0,
+			"tenant_id":     0,
+			"client_id":     "",
+			"scopes":        []string{},
+			"groups":        []string{},
+			"session_id":    0,
+			"actor_id":      0,
+		}
+	}
+
+	groups := claims.Groups
+	if groups == nil {
+		groups = []string{}
+	}
+
+	return map[string]any{
+		"authenticated": true,
+		"user_id":       claims.UserID,
+		"tenant_id":     claims.TenantID,
+		"client_id":     claims.ClientID,
+		"scopes":        strings.Fields(claims.Scope),
+		"groups":        groups,
+		"session_id":    claims.SessionID,
+		"actor_id":      claims.ActorID(),
+	}
+}
+
+func requestAttributes(r *http.Request, route string, params map[string]string) map[string]any {
+	return map[string]any{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"route":  route,
+		"params": params,
+		"ip":     ClientIP(r),
+	}
+}
+
+// resourceAttributes loads the user a route names, e.g. {id} in
+// /users/{id} or /admin/users/{id}/sessions, as resource.user.
+func resourceAttributes(ctx context.Context, users repository.UserRepository, claims *auth.Claims, route string, params map[string]string) map[string]any {
+	param := userParam(route)
+	if param == "" {
+		return map[string]any{}
+	}
+
+	id, err := strconv.Atoi(params[param])
+	if err != nil {
+		return map[string]any{}
+	}
+
+	if claims != nil && claims.TenantID != 0 {
+		if ctx, err = tenant.Bind(ctx, claims.TenantID); err != nil {
+			return map[string]any{}
+		}
+		ctx = tenant.WithUser(ctx, claims.UserID)
+	}
+
+	user, err := users.Get(ctx, id)
+	if err != nil || user == nil {
+		return map[string]any{}
+	}
+
+	return map[string]any{
+		"type": "user",
+		"user": map[string]any{
+			"id":              user.ID,
+			"organization_id": user.OrganizationID,
+			"email":           user.Email,
+			"role":            user.Role,
+			"email_verified":  user.EmailVerified,
+			"disabled":        user.Disabled,
+			"external_id":     user.ExternalID,
+		},
+	}
+}
+
+// userParam returns the URL parameter that follows a users or members
+// segment of the route pattern.
+func userParam(route string) string {
+	segments := strings.Split(route, "/")
+	for i := 1; i < len(segments); i++ {
+		previous := strings.ToLower(segments[i-1])
+		if (previous == "users" || previous == "members") &&
+			strings.HasPrefix(segments[i], "{") && strings.HasSuffix(segments[i], "}") {
+			return strings.Trim(segments[i], "{}")
+		}
+	}
+
+	return ""
+}
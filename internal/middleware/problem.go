@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"encoding/json"
+	"go-user-api/internal/model"
+	"net/http"
+)
+
+// writeProblem answers with an application/problem+json body that carries
+// the request ID, so clients can quote it in support requests.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(model.Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		RequestID: GetRequestID(r.Context()),
+	})
+}
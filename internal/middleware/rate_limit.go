@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go-user-api/internal/logging"
+	"go-user-api/internal/metrics"
+	"go-user-api/internal/ratelimit"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimitKey names the bucket a request is charged to.
+type RateLimitKey func(r *http.Request) string
+
+// KeyByIP charges requests to the client address.
+func KeyByIP(r *http.Request) string {
+	return "ip:" + ClientIP(r)
+}
+
+// KeyByUser charges requests to the user of their token, and anonymous
+// requests to the client address. It works before JWTAuth's middleware too.
+func KeyByUser(jwtAuth *JWTAuth) RateLimitKey {
+	return func(r *http.Request) string {
+		if userID, ok := r.Context().Value(UserIDKey).(int); ok {
+			return "user:" + strconv.Itoa(userID)
+		}
+
+		if claims := jwtAuth.Claims(r); claims != nil {
+			return "user:" + strconv.Itoa(claims.UserID)
+		}
+
+		return KeyByIP(r)
+	}
+}
+
+// KeyByToken charges requests to their token once jwtAuth accepts it, and
+// others to the client address, so made-up tokens can't each claim a fresh
+// bucket. Tokens are hashed so the store never holds them.
+func KeyByToken(jwtAuth *JWTAuth) RateLimitKey {
+	return func(r *http.Request) string {
+		if jwtAuth.Claims(r) == nil {
+			return KeyByIP(r)
+		}
+
+		token, _ := requestToken(r)
+		sum := sha256.Sum256([]byte(token))
+		return "token:" + hex.EncodeToString(sum[:16])
+	}
+}
+
+// RateLimitPolicy limits the requests of each key to Rate. The Name keeps
+// the keys of policies sharing a store apart.
+type RateLimitPolicy struct {
+	Name string
+	Rate ratelimit.Rate
+	Key  RateLimitKey
+}
+
+// RateLimit enforces the policy with the generic cell rate algorithm. Every
+// response carries RateLimit-* headers, and rejected requests get a 429
+// problem response with Retry-After. Should the store fail, requests are let
+// through rather than taking the service down with it.
+func RateLimit(store ratelimit.Store, policy RateLimitPolicy) func(http.Handler) http.Handler {
+	policyHeader := fmt.Sprintf("%d;w=%d", policy.Rate.Limit, int(policy.Rate.Period.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res, err := ratelimit.Take(r.Context(), store, policy.Name+":"+policy.Key(r), policy.Rate, time.Now())
+			if err != nil {
+				logging.FromContext(r.Context()).Error("rate limit check failed", "policy", policy.Name, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Policy", policyHeader)
+			h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
+
+			if !res.Allowed {
+				metrics.RateLimited.WithLabelValues(policy.Name).Inc()
+				logging.FromContext(r.Context()).Warn("rate limit exceeded", "policy", policy.Name)
+
+				retryAfter := seconds(res.RetryAfter)
+				h.Set("Retry-After", strconv.Itoa(retryAfter))
+				writeProblem(w, r, http.StatusTooManyRequests, fmt.Sprintf("Too many requests, retry in %d seconds.", retryAfter))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// seconds rounds d up to whole seconds, so clients don't retry too early.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
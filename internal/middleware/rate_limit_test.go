@@ -0,0 +1,93 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"go-user-api/internal/ratelimit"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit(t *testing.T) {
+	limit := middleware.RateLimit(ratelimit.NewMemoryStore(), middleware.RateLimitPolicy{
+		Name: "auth",
+		Rate: ratelimit.Rate{Limit: 2, Period: time.Minute},
+		Key:  middleware.KeyByIP,
+	})
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.With(limit).Post("/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	})
+	r.Get("/auth/profile", func(w http.ResponseWriter, r *http.Request) {})
+
+	send := func(method string, target string, remote string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.RemoteAddr = remote
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := send(http.MethodPost, "/auth/login", "203.0.113.7:1234")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, "2;w=60", rr.Header().Get("RateLimit-Policy"))
+	assert.Equal(t, "2", rr.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rr.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", rr.Header().Get("RateLimit-Reset"))
+
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodPost, "/auth/login", "203.0.113.7:1234").Code)
+
+	rr = send(http.MethodPost, "/auth/login", "203.0.113.7:4321")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", rr.Header().Get("Retry-After"))
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+
+	var problem model.Problem
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+	assert.Equal(t, http.StatusTooManyRequests, problem.Status)
+	assert.NotEmpty(t, problem.RequestID)
+
+	// other clients have their own limit, and other routes none
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodPost, "/auth/login", "198.51.100.1:1234").Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/auth/profile", "203.0.113.7:1234").Code)
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	jane, _ := auth.GenerateJWT(5)
+	john, _ := auth.GenerateJWT(6)
+
+	req := func(token string, remote string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remote
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return req
+	}
+
+	assert.Equal(t, "ip:203.0.113.7", middleware.KeyByIP(req(jane, "203.0.113.7:1")))
+
+	assert.Equal(t, "user:5", middleware.KeyByUser(middleware.NewJWTAuth())(req(jane, "203.0.113.7:1")))
+	assert.Equal(t, "ip:203.0.113.7", middleware.KeyByUser(middleware.NewJWTAuth())(req("garbage", "203.0.113.7:1")))
+
+	byToken := middleware.KeyByToken(middleware.NewJWTAuth())
+	assert.Equal(t, byToken(req(jane, "203.0.113.7:1")), byToken(req(jane, "198.51.100.1:1")))
+	assert.NotEqual(t, byToken(req(jane, "203.0.113.7:1")), byToken(req(john, "203.0.113.7:1")))
+	assert.NotContains(t, byToken(req(jane, "203.0.113.7:1")), jane)
+	assert.Equal(t, "ip:203.0.113.7", byToken(req("", "203.0.113.7:1")))
+
+	// tokens that don't verify are charged to the address, not a bucket of their own
+	assert.Equal(t, "ip:203.0.113.7", byToken(req("garbage", "203.0.113.7:1")))
+	assert.Equal(t, "ip:203.0.113.7", byToken(req(jane+"x", "203.0.113.7:1")))
+}
@@ -0,0 +1,51 @@
+package middleware_test
+
+import (
+	"go-user-api/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRealIP(t *testing.T) {
+	_, err := middleware.ParseTrustedProxies("10.0.0.0/8, proxy.internal")
+	assert.Error(t, err)
+
+	trusted, err := middleware.ParseTrustedProxies("10.0.0.0/8, 192.0.2.1")
+	require.NoError(t, err)
+
+	var seen string
+	h := middleware.RealIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = middleware.ClientIP(r)
+	}))
+
+	for _, c := range []struct {
+		name      string
+		remote    string
+		forwarded string
+		realIP    string
+		want      string
+	}{
+		{"direct", "203.0.113.7:51234", "", "", "203.0.113.7"},
+		{"spoofed by the client", "203.0.113.7:51234", "198.51.100.1", "", "203.0.113.7"},
+		{"through a proxy", "192.0.2.1:443", "198.51.100.1", "", "198.51.100.1"},
+		{"through two proxies", "10.1.1.1:443", "6.6.6.6, 198.51.100.1, 10.2.2.2", "", "198.51.100.1"},
+		{"only proxies", "10.1.1.1:443", "10.3.3.3, 10.2.2.2", "", "10.3.3.3"},
+		{"malformed hop", "10.1.1.1:443", "198.51.100.1, garbage", "", "10.1.1.1"},
+		{"real ip header", "192.0.2.1:443", "", "198.51.100.9", "198.51.100.9"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = c.remote
+		if c.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", c.forwarded)
+		}
+		if c.realIP != "" {
+			req.Header.Set("X-Real-IP", c.realIP)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Equal(t, c.want, seen, c.name)
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"go-user-api/internal/rebac"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RequireRelation only lets through users holding relation on the object of
+// objectType named by the URL parameter param, e.g. edit on user {id}. It
+// must run after JWTAuth.Middleware.
+func RequireRelation(engine *rebac.Engine, objectType string, relation string, param string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := r.Context().Value(UserIDKey).(int)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			object := rebac.Object{Type: objectType, ID: chi.URLParam(r, param)}
+			subject := rebac.Subject{Type: "user", ID: strconv.Itoa(userID)}
+			allowed, err := engine.Check(r.Context(), object, relation, subject)
+			if err != nil {
+				http.Error(w, "something went wrong", http.StatusInternalServerError)
+				return
+			}
+
+			if !allowed {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"go-user-api/internal/logging"
+	"net/http"
+	"runtime/debug"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+)
+
+// Recoverer turns a panic in a handler into a logged error and a 500
+// problem response, unless the handler already started its response.
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := chimw.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+
+			// the server uses this panic to abort a response on purpose
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			logging.FromContext(r.Context()).Error("panic serving request", "panic", rec, "stack", string(debug.Stack()))
+
+			if ww.Status() == 0 {
+				writeProblem(ww, r, http.StatusInternalServerError, "")
+			}
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
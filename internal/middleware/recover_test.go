@@ -0,0 +1,68 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-user-api/internal/logging"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLogAndRecoverer(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "", "json")
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID, middleware.RequestLogger(logger), middleware.AccessLog, middleware.Recoverer)
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+	r.Get("/boom", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/5", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var access map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &access))
+	assert.Equal(t, "request", access["msg"])
+	assert.Equal(t, float64(http.StatusOK), access["status"])
+	assert.Equal(t, float64(5), access["bytes"])
+	assert.Equal(t, "/users/{id}", access["route"])
+	assert.Contains(t, access, "duration")
+
+	buf.Reset()
+	rr := httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/boom", nil)
+	req.Header.Set("X-Request-ID", "req-7")
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+	var problem model.Problem
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+	assert.Equal(t, http.StatusInternalServerError, problem.Status)
+	assert.Equal(t, "req-7", problem.RequestID)
+	assert.Equal(t, "/boom", problem.Instance)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"panic":"boom"`)
+	assert.Contains(t, lines[1], `"status":500`)
+
+	assert.Panics(t, func() {
+		middleware.Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
@@ -0,0 +1,34 @@
+package middleware_test
+
+import (
+	"go-user-api/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	h := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = middleware.GetRequestID(r.Context())
+	}))
+
+	for id, propagated := range map[string]bool{
+		"":                       false,
+		"req-42":                 true,
+		"has spaces":             false,
+		strings.Repeat("a", 200): false,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Request-ID", id)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		assert.NotEmpty(t, seen)
+		assert.Equal(t, seen, rr.Header().Get("X-Request-ID"))
+		assert.Equal(t, propagated, seen == id, "%q", id)
+	}
+}
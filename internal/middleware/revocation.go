@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/repository"
+)
+
+// RevocationCheck rejects access tokens revoked through /oauth/revoke.
+func RevocationCheck(grants repository.GrantRepository) TokenCheck {
+	return func(ctx context.Context, claims *auth.Claims) error {
+		if claims.ID == "" {
+			return nil
+		}
+
+		revoked, err := grants.IsAccessTokenRevoked(ctx, claims.ID)
+		if err != nil {
+			return err
+		}
+
+		if revoked {
+			return errors.New("token has been revoked")
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"encoding/json"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/scim"
+	"net/http"
+	"strings"
+)
+
+// SCIMAuth only lets through requests bearing the provisioning token. It is
+// separate from user and client tokens so it can be rotated on its own and
+// grants nothing outside /scim.
+func SCIMAuth(token string) func(http.Handler) http.Handler {
+	tokenHash := auth.HashSecret(token)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || !auth.CompareSecret(presented, tokenHash) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+				w.Header().Set("Content-Type", scim.ContentType)
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(scim.NewError(http.StatusUnauthorized, "", "invalid or missing provisioning token"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
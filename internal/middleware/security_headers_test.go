@@ -0,0 +1,39 @@
+package middleware_test
+
+import (
+	"go-user-api/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	get := func(h http.Handler, target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		return rr
+	}
+
+	r := chi.NewRouter()
+	r.Use(middleware.SecurityHeaders(24 * time.Hour))
+	r.Get("/users", func(w http.ResponseWriter, r *http.Request) {})
+	r.With(middleware.ContentSecurityPolicy(middleware.SwaggerContentSecurityPolicy)).Get("/swagger/*", func(w http.ResponseWriter, r *http.Request) {})
+
+	rr := get(r, "/users")
+	assert.Equal(t, "max-age=86400; includeSubDomains", rr.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "nosniff", rr.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", rr.Header().Get("X-Frame-Options"))
+	assert.Equal(t, middleware.APIContentSecurityPolicy, rr.Header().Get("Content-Security-Policy"))
+
+	rr = get(r, "/swagger/index.html")
+	assert.Equal(t, middleware.SwaggerContentSecurityPolicy, rr.Header().Get("Content-Security-Policy"))
+
+	r = chi.NewRouter()
+	r.Use(middleware.SecurityHeaders(0))
+	r.Get("/users", func(w http.ResponseWriter, r *http.Request) {})
+	assert.Empty(t, get(r, "/users").Header().Get("Strict-Transport-Security"))
+}
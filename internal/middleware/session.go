@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"go-user-api/internal/auth"
+	"go-user-api/internal/repository"
+	"go-user-api/internal/tenant"
+	"time"
+)
+
+// sessionTouchInterval limits how often a session's last-seen time is
+// written, so busy clients don't cause a write per request.
+const sessionTouchInterval = time.Minute
+
+// SessionCheck rejects tokens whose login session was revoked or expired.
+// Tokens without a session, such as service account tokens, pass.
+func SessionCheck(sessions repository.SessionRepository) TokenCheck {
+	return func(ctx context.Context, claims *auth.Claims) error {
+		if claims.SessionID == 0 {
+			return nil
+		}
+
+		// the request may not be scoped to the token's organization yet
+		if claims.TenantID != 0 {
+			ctx = tenant.WithID(ctx, claims.TenantID)
+		}
+
+		session, err := sessions.Get(ctx, claims.SessionID)
+		if err != nil {
+			return err
+		}
+
+		if session.UserID != claims.UserID || !session.Active() {
+			return errors.New("session has ended")
+		}
+
+		if time.Since(session.LastSeenAt) > sessionTouchInterval {
+			sessions.Touch(ctx, session.ID)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"go-user-api/internal/logging"
+	"go-user-api/internal/repository"
+	"go-user-api/internal/tenant"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TenantHeader names the organization of a request by its slug.
+const TenantHeader = "X-Tenant-ID"
+
+// ResolveTenant scopes the request to the organization named by the
+// X-Tenant-ID header or, with a baseDomain, by the subdomain of the host,
+// e.g. acme for acme.example.com. Requests naming neither stay unscoped and
+// get the default organization; JWTAuth.Middleware later scopes them to the
+// organization of their token.
+func ResolveTenant(organizations repository.OrganizationRepository, baseDomain string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			slug := r.Header.Get(TenantHeader)
+			if slug == "" {
+				slug = subdomain(r.Host, baseDomain)
+			}
+
+			if slug == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			org, err := organizations.GetBySlug(r.Context(), strings.ToLower(slug))
+			if err != nil || org == nil {
+				http.Error(w, "Unknown organization", http.StatusNotFound)
+				return
+			}
+
+			ctx := logging.With(tenant.WithID(r.Context(), org.ID), "tenant_id", org.ID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// subdomain returns the label of host directly below baseDomain.
+func subdomain(host string, baseDomain string) string {
+	if baseDomain == "" {
+		return ""
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	label, ok := strings.CutSuffix(strings.ToLower(host), "."+strings.ToLower(baseDomain))
+	if !ok || label == "" || strings.Contains(label, ".") || label == "www" {
+		return ""
+	}
+
+	return label
+}
+
+// RequireDefaultTenant only lets through requests of the default
+// organization. After RequireAdmin it keeps routes to the admins of the
+// whole deployment; on its own it keeps anonymous requests, such as sign-up,
+// from creating accounts in an organization they merely name.
+func RequireDefaultTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tenant.ID(r.Context()) != tenant.DefaultID {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireTenantUser answers 404 unless the user named by the id URL
+// parameter belongs to the organization of the request. It guards admin
+// routes whose handlers look the user up by ID alone.
+func RequireTenantUser(users repository.UserRepository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+			if user, err := users.Get(r.Context(), id); err != nil || user == nil {
+				http.Error(w, "User not found", http.StatusNotFound)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireOwnOrganization answers 404 unless the organization named by the id
+// URL parameter is the organization of the request. Requests of the default
+// organization may name any. It must run after RequireAdmin.
+func RequireOwnOrganization(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+		if current := tenant.ID(r.Context()); current != id && current != tenant.DefaultID {
+			http.Error(w, "Organization not found", http.StatusNotFound)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
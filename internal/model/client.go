@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// Client is an OAuth2 client. Confidential clients (service accounts, server
+// side apps) authenticate with a client ID and secret; public clients (SPAs,
+// mobile apps) have no secret and must use PKCE.
+type Client struct {
+	ID              int       `json:"id"`
+	ClientID        string    `json:"client_id"`
+	Name            string    `json:"name"`
+	SecretHash      string    `json:"-"`
+	Scopes          []string  `json:"scopes"`
+	RedirectURIs    []string  `json:"redirect_uris"`
+	Public          bool      `json:"public"`
+	CreatedAt       time.Time `json:"created_at"`
+	SecretRotatedAt time.Time `json:"secret_rotated_at"`
+}
+
+type ClientInput struct {
+	Name         string   `json:"name" validate:"required,min=3"`
+	Scopes       []string `json:"scopes" validate:"dive,required,excludesall= "`
+	RedirectURIs []string `json:"redirect_uris" validate:"dive,required"`
+	Public       bool     `json:"public"`
+}
+
+// ClientCredentials is returned when a client is created or its secret is
+// rotated. The plain secret is never stored and is only shown this once.
+// Public clients have no secret.
+type ClientCredentials struct {
+	Client
+	ClientSecret string `json:"client_secret,omitempty"`
+}
@@ -3,3 +3,14 @@ package model
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
+
+// Problem is an RFC 9457 problem details body, sent as
+// application/problem+json.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
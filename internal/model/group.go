@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Group is a named set of users, maintained by provisioning clients or
+// admins. Members of its subgroups are effective members too.
+type Group struct {
+	ID             int       `json:"id"`
+	OrganizationID int       `json:"organization_id,omitempty"`
+	DisplayName    string    `json:"display_name"`
+	ExternalID     string    `json:"external_id,omitempty"`
+	Members        []int     `json:"members"`
+	Subgroups      []int     `json:"subgroups"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type GroupInput struct {
+	DisplayName string `json:"display_name" validate:"required"`
+}
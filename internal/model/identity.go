@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Identity links an account at an external OpenID Connect provider to a
+// local user. The same account can be linked once in each organization.
+type Identity struct {
+	ID             int       `json:"id"`
+	OrganizationID int       `json:"organization_id,omitempty"`
+	UserID         int       `json:"user_id"`
+	Provider       string    `json:"provider"`
+	Subject        string    `json:"subject"`
+	Email          string    `json:"email"`
+	CreatedAt      time.Time `json:"created_at"`
+}
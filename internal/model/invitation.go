@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+// Statuses of an invitation.
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+// Invitation asks someone to join an organization with a role. It is
+// accepted from an emailed link, either by an existing user or by signing up.
+type Invitation struct {
+	ID             int        `json:"id"`
+	OrganizationID int        `json:"organization_id"`
+	Email          string     `json:"email"`
+	Role           string     `json:"role"`
+	InvitedBy      int        `json:"invited_by,omitempty"`
+	TokenID        string     `json:"-"`
+	Status         string     `json:"status"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at,omitempty"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// StatusAt derives the status of the invitation at the given time.
+func (i *Invitation) StatusAt(now time.Time) string {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationAccepted
+	case i.RevokedAt != nil:
+		return InvitationRevoked
+	case !now.Before(i.ExpiresAt):
+		return InvitationExpired
+	default:
+		return InvitationPending
+	}
+}
+
+type InvitationInput struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role"`
+}
+
+type InvitationTokenInput struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// InvitationSignupInput creates the account of an invitee who has none. The
+// email is the invited one.
+type InvitationSignupInput struct {
+	Token    string `json:"token" validate:"required"`
+	Name     string `json:"name" validate:"required,min=3"`
+	Password string `json:"password" validate:"required,min=6"`
+}
+
+// InvitationPreview is what the invitee sees before accepting.
+type InvitationPreview struct {
+	Organization Organization `json:"organization"`
+	Email        string       `json:"email"`
+	Role         string       `json:"role"`
+	ExpiresAt    time.Time    `json:"expires_at"`
+}
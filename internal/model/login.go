@@ -5,3 +5,9 @@ type LoginInput struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=3"`
 }
+
+type MagicLinkInput struct {
+	Email string `json:"email" validate:"required,email"`
+	// BindDevice makes the link work only in the browser that requested it.
+	BindDevice bool `json:"bind_device"`
+}
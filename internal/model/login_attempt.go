@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// Outcomes of a login attempt.
+const (
+	LoginSuccess         = "success"
+	LoginUserNotFound    = "user_not_found"
+	LoginInvalidPassword = "invalid_password"
+	LoginDisabled        = "disabled"
+	LoginError           = "error"
+)
+
+// LoginAttempt is an entry of a user's login history.
+type LoginAttempt struct {
+	ID             int64 `json:"id"`
+	OrganizationID int   `json:"organization_id,omitempty"`
+	// UserID is 0 when the email matched no account.
+	UserID    int    `json:"user_id,omitempty"`
+	Email     string `json:"email,omitempty"`
+	Outcome   string `json:"outcome"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+	// Fingerprint identifies the device, see handler.deviceFingerprint.
+	Fingerprint string `json:"-"`
+	// NewDevice is set on successful logins from a device not seen before.
+	NewDevice bool      `json:"new_device"`
+	CreatedAt time.Time `json:"created_at"`
+}
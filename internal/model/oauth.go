@@ -0,0 +1,68 @@
+package model
+
+import "time"
+
+// TokenResponse is the OAuth2 access token response (RFC 6749 section 5.1).
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// OAuthError is the OAuth2 error response (RFC 6749 section 5.2).
+type OAuthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// IntrospectionResponse describes a token (RFC 7662 section 2.2). Inactive
+// tokens only report active: false.
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+}
+
+// AuthorizationCode is a pending authorization code grant. Only the hash of
+// the code is stored.
+type AuthorizationCode struct {
+	CodeHash string
+	ClientID string
+	UserID   int
+	// OrganizationID is the organization of the user.
+	OrganizationID int
+	// RedirectURI is the redirect_uri of the authorization request, which
+	// the token request must repeat. It is empty if the client left it out.
+	RedirectURI   string
+	Scope         string
+	CodeChallenge string
+	Nonce         string
+	AuthTime      time.Time
+	ExpiresAt     time.Time
+	UsedAt        *time.Time
+}
+
+// RefreshToken is an opaque, single-use refresh token. Only the hash of the
+// token is stored; every use rotates it.
+type RefreshToken struct {
+	ID        int
+	TokenHash string
+	ClientID  string
+	UserID    int
+	// OrganizationID is the organization of the user.
+	OrganizationID int
+	Scope          string
+	AuthTime       time.Time
+	ExpiresAt      time.Time
+	RevokedAt      *time.Time
+	CreatedAt      time.Time
+}
@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// Organization is a tenant. Users belong to exactly one, and emails are only
+// unique within it.
+type Organization struct {
+	ID int `json:"id"`
+	// Slug names the organization in subdomains and the X-Tenant-ID header.
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type OrganizationInput struct {
+	Slug string `json:"slug" validate:"required,min=2,max=63"`
+	Name string `json:"name" validate:"required"`
+	// Admin, when set, becomes the first admin of the organization.
+	Admin *OrganizationMemberInput `json:"admin,omitempty"`
+}
+
+type OrganizationMemberInput struct {
+	Name     string `json:"name" validate:"required,min=3"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,min=6"`
+	Role     string `json:"role"`
+}
+
+type RoleInput struct {
+	Role string `json:"role" validate:"required"`
+}
@@ -0,0 +1,17 @@
+package model
+
+// RelationTupleInput is a relation tuple in its string form, e.g. object
+// "team:1", relation "manager" and subject "user:2" or "team:3#member".
+type RelationTupleInput struct {
+	Object   string `json:"object" validate:"required"`
+	Relation string `json:"relation" validate:"required"`
+	Subject  string `json:"subject" validate:"required"`
+}
+
+type RelationCheck struct {
+	Allowed bool `json:"allowed"`
+}
+
+type RelationObjects struct {
+	Objects []string `json:"objects"`
+}
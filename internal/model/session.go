@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// Session is a sign-in of a user on one device. Tokens issued at login carry
+// its ID, so revoking the session signs that device out.
+type Session struct {
+	ID             int        `json:"id"`
+	OrganizationID int        `json:"organization_id,omitempty"`
+	UserID         int        `json:"user_id"`
+	IP             string     `json:"ip"`
+	UserAgent      string     `json:"user_agent"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastSeenAt     time.Time  `json:"last_seen_at"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	// Current marks the session of the token making the request.
+	Current bool `json:"current"`
+}
+
+// Active reports whether tokens of the session are still accepted.
+func (s *Session) Active() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
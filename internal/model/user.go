@@ -1,11 +1,34 @@
 package model
 
-import "time"
+import (
+	"log/slog"
+	"time"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
 
 type User struct {
-	ID        int       `json:"id"`
-	Name      string    `json:"name" validate:"required,min=3"`
-	Email     string    `json:"email" validate:"required,email"`
-	Password  string    `json:"password,omitempty" validate:"required,min=6"`
-	CreatedAt time.Time `json:"created_at"`
+	ID             int       `json:"id"`
+	OrganizationID int       `json:"organization_id,omitempty"`
+	Name           string    `json:"name" validate:"required,min=3"`
+	Email          string    `json:"email" validate:"required,email"`
+	Password       string    `json:"password,omitempty" validate:"required,min=6"`
+	Role           string    `json:"role,omitempty"`
+	EmailVerified  bool      `json:"email_verified"`
+	ExternalID     string    `json:"external_id,omitempty"`
+	Disabled       bool      `json:"disabled,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at,omitzero"`
+}
+
+// LogValue leaves the password hash and personal details out of logs.
+func (u User) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("id", u.ID),
+		slog.Int("organization_id", u.OrganizationID),
+		slog.String("role", u.Role),
+	)
 }
@@ -0,0 +1,96 @@
+package policy
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Engine holds the current policy of a file and swaps in new versions of it.
+// A version that fails to load is logged and the previous one stays active.
+type Engine struct {
+	path string
+
+	mu      sync.RWMutex
+	policy  *Policy
+	modTime time.Time
+}
+
+// NewEngine loads the policy file at path, failing if it is invalid.
+func NewEngine(path string) (*Engine, error) {
+	e := &Engine{path: path}
+	if _, err := e.Reload(); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// Policy returns the active policy.
+func (e *Engine) Policy() *Policy {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.policy
+}
+
+// Evaluate decides a request with the active policy.
+func (e *Engine) Evaluate(method string, route string, input Input) Decision {
+	return e.Policy().Evaluate(method, route, input)
+}
+
+// Reload loads the file if it changed since the last load and reports
+// whether a new policy became active.
+func (e *Engine) Reload() (bool, error) {
+	info, err := os.Stat(e.path)
+	if err != nil {
+		return false, err
+	}
+
+	e.mu.RLock()
+	unchanged := !e.modTime.IsZero() && info.ModTime().Equal(e.modTime)
+	e.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	p, err := Load(e.path)
+	if err != nil && e.Policy() == nil {
+		return false, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// remembering the time of a broken version reports it only once
+	e.modTime = info.ModTime()
+	if err != nil {
+		return false, err
+	}
+
+	e.policy = p
+	return true, nil
+}
+
+// Watch checks the file for changes every interval until ctx is done.
+func (e *Engine) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := e.Reload()
+			if err != nil {
+				slog.Error("policy reload failed, keeping the active version", "version", e.Policy().Version, "error", err)
+			} else if reloaded {
+				p := e.Policy()
+				slog.Info("policy loaded", "version", p.Version, "mode", p.Mode)
+			}
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// Package policy evaluates authorization rules written as CEL expressions
+// over the attributes of a request. Rules live in a versioned JSON file that
+// is reloaded when it changes, so they can be changed without a deploy.
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+)
+
+const (
+	// ModeEnforce rejects requests the policy denies.
+	ModeEnforce = "enforce"
+	// ModeShadow only logs decisions, to try out a policy on live traffic.
+	ModeShadow = "shadow"
+
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// File is the JSON form of a policy:
+//
+//	{
+//	  "version": "2026-10-01.1",
+//	  "mode": "shadow",
+//	  "default": "allow",
+//	  "rules": [{
+//	    "name": "only-admins-delete-users",
+//	    "effect": "deny",
+//	    "methods": ["DELETE"],
+//	    "routes": ["/users/{id}"],
+//	    "when": "!('admin' in subject.groups)"
+//	  }]
+//	}
+//
+// Rules apply to the listed methods and chi route patterns, or to all when
+// none are listed. A matching deny rule wins over allow rules; without a
+// matching rule the default effect applies.
+type File struct {
+	Version string `json:"version"`
+	Mode    string `json:"mode"`
+	Default string `json:"default"`
+	Rules   []Rule `json:"rules"`
+}
+
+type Rule struct {
+	Name    string   `json:"name"`
+	Effect  string   `json:"effect"`
+	Methods []string `json:"methods,omitempty"`
+	Routes  []string `json:"routes,omitempty"`
+	// When is a CEL expression over subject, request and resource that
+	// must evaluate to a bool.
+	When string `json:"when"`
+}
+
+// Input are the attributes a policy decides on, each exposed to CEL as a map
+// of the same name.
+type Input struct {
+	// Subject holds the claims of the request's token, if any.
+	Subject map[string]any
+	// Request holds the method, path, route pattern and URL parameters.
+	Request map[string]any
+	// Resource holds what the request acts on, such as the user it names.
+	Resource map[string]any
+}
+
+// Decision is the outcome of a policy for one request.
+type Decision struct {
+	Allowed bool
+	// Rule names the deciding rule, or is empty when the default applied.
+	Rule    string
+	Version string
+	// Enforced is false in shadow mode.
+	Enforced bool
+}
+
+// Policy is a parsed and compiled File.
+type Policy struct {
+	Version string
+	Mode    string
+	Default string
+	rules   []compiledRule
+}
+
+type compiledRule struct {
+	Rule
+	program cel.Program
+}
+
+var env = mustEnv()
+
+func mustEnv() *cel.Env {
+	attrs := cel.MapType(cel.StringType, cel.DynType)
+	e, err := cel.NewEnv(
+		cel.Variable("subject", attrs),
+		cel.Variable("request", attrs),
+		cel.Variable("resource", attrs),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	return e
+}
+
+// Load reads and compiles the policy file at path.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("policy %s: %w", path, err)
+	}
+
+	return p, nil
+}
+
+// Parse compiles a policy, rejecting it as a whole if any rule is invalid.
+func Parse(data []byte) (*Policy, error) {
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+
+	if f.Version == "" {
+		return nil, errors.New("version is required")
+	}
+
+	p := &Policy{Version: f.Version, Mode: f.Mode, Default: f.Default}
+	if p.Mode == "" {
+		p.Mode = ModeEnforce
+	}
+	if p.Default == "" {
+		p.Default = EffectAllow
+	}
+
+	if p.Mode != ModeEnforce && p.Mode != ModeShadow {
+		return nil, fmt.Errorf("mode must be %s or %s", ModeEnforce, ModeShadow)
+	}
+	if p.Default != EffectAllow && p.Default != EffectDeny {
+		return nil, fmt.Errorf("default must be %s or %s", EffectAllow, EffectDeny)
+	}
+
+	for i, rule := range f.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+
+		if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+			return nil, fmt.Errorf("%s: effect must be %s or %s", rule.Name, EffectAllow, EffectDeny)
+		}
+
+		ast, issues := env.Compile(rule.When)
+		if issues != nil && issues.Err() != nil {
+			return nil, fmt.Errorf("%s: %w", rule.Name, issues.Err())
+		}
+
+		if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+			return nil, fmt.Errorf("%s: when must be a bool expression", rule.Name)
+		}
+
+		program, err := env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", rule.Name, err)
+		}
+
+		for i, m := range rule.Methods {
+			rule.Methods[i] = strings.ToUpper(m)
+		}
+
+		p.rules = append(p.rules, compiledRule{Rule: rule, program: program})
+	}
+
+	return p, nil
+}
+
+// Evaluate decides a request. A rule whose expression fails to evaluate,
+// e.g. on a missing attribute, counts as matching if it is a deny rule and
+// as not matching otherwise, so errors never grant access.
+func (p *Policy) Evaluate(method string, route string, input Input) Decision {
+	vars := map[string]any{
+		"subject":  orEmpty(input.Subject),
+		"request":  orEmpty(input.Request),
+		"resource": orEmpty(input.Resource),
+	}
+
+	decision := Decision{Allowed: p.Default == EffectAllow, Version: p.Version, Enforced: p.Mode == ModeEnforce}
+	allowedBy := ""
+
+	for _, rule := range p.rules {
+		if !rule.applies(method, route) {
+			continue
+		}
+
+		out, _, err := rule.program.Eval(vars)
+		matched := err == nil && out.Value() == true
+		if err != nil && rule.Effect == EffectDeny {
+			matched = true
+		}
+
+		if !matched {
+			continue
+		}
+
+		if rule.Effect == EffectDeny {
+			decision.Allowed = false
+			decision.Rule = rule.Name
+			return decision
+		}
+
+		if allowedBy == "" {
+			allowedBy = rule.Name
+		}
+	}
+
+	if allowedBy != "" {
+		decision.Allowed = true
+		decision.Rule = allowedBy
+	}
+
+	return decision
+}
+
+func (r *compiledRule) applies(method string, route string) bool {
+	return (len(r.Methods) == 0 || slices.Contains(r.Methods, method)) &&
+		(len(r.Routes) == 0 || slices.Contains(r.Routes, route))
+}
+
+func orEmpty(m map[string]any) map[string]any {
+	if m == nil {
+		return map[string]any{}
+	}
+
+	return m
+}
@@ -0,0 +1,124 @@
+package policy_test
+
+import (
+	"go-user-api/internal/policy"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const adminOnlyDeletes = `{
+	"version": "1",
+	"default": "deny",
+	"rules": [
+		{"name": "admins", "effect": "allow", "when": "'admin' in subject.groups"},
+		{"name": "self", "effect": "allow", "routes": ["/users/{id}"], "when": "resource.user.id == subject.user_id"},
+		{"name": "no-deletes", "effect": "deny", "methods": ["delete"], "routes": ["/users/{id}"], "when": "!('admin' in subject.groups)"},
+		{"name": "disabled", "effect": "deny", "when": "resource.user.disabled"}
+	]
+}`
+
+func TestParse(t *testing.T) {
+	for name, src := range map[string]string{
+		"no version":      `{"rules": []}`,
+		"unknown mode":    `{"version": "1", "mode": "audit"}`,
+		"unknown default": `{"version": "1", "default": "maybe"}`,
+		"unknown effect":  `{"version": "1", "rules": [{"effect": "permit", "when": "true"}]}`,
+		"syntax error":    `{"version": "1", "rules": [{"effect": "allow", "when": "subject.user_id =="}]}`,
+		"not a bool":      `{"version": "1", "rules": [{"effect": "allow", "when": "1 + 1"}]}`,
+		"unknown var":     `{"version": "1", "rules": [{"effect": "allow", "when": "user.id == 1"}]}`,
+	} {
+		_, err := policy.Parse([]byte(src))
+		assert.Error(t, err, name)
+	}
+
+	p, err := policy.Parse([]byte(`{"version": "1"}`))
+	require.NoError(t, err)
+	assert.Equal(t, policy.ModeEnforce, p.Mode)
+	assert.Equal(t, policy.EffectAllow, p.Default)
+}
+
+func TestEvaluate(t *testing.T) {
+	p, err := policy.Parse([]byte(adminOnlyDeletes))
+	require.NoError(t, err)
+
+	subject := func(id int, groups ...string) map[string]any {
+		return map[string]any{"user_id": id, "groups": append([]string{}, groups...)}
+	}
+	user := func(id int, disabled bool) map[string]any {
+		return map[string]any{"user": map[string]any{"id": id, "disabled": disabled}}
+	}
+
+	for _, c := range []struct {
+		name    string
+		method  string
+		input   policy.Input
+		allowed bool
+		rule    string
+	}{
+		{"own profile", "PUT", policy.Input{Subject: subject(5), Resource: user(5, false)}, true, "self"},
+		{"someone else's", "PUT", policy.Input{Subject: subject(5), Resource: user(6, false)}, false, ""},
+		{"admin", "PUT", policy.Input{Subject: subject(1, "admin"), Resource: user(6, false)}, true, "admins"},
+		{"deleting oneself", "DELETE", policy.Input{Subject: subject(5), Resource: user(5, false)}, false, "no-deletes"},
+		{"admin deletes", "DELETE", policy.Input{Subject: subject(1, "admin"), Resource: user(6, false)}, true, "admins"},
+		{"deny wins", "PUT", policy.Input{Subject: subject(1, "admin"), Resource: user(6, true)}, false, "disabled"},
+		// resource.user is missing, which fails the disabled rule closed
+		{"no resource", "PUT", policy.Input{Subject: subject(1, "admin")}, false, "disabled"},
+	} {
+		decision := p.Evaluate(c.method, "/users/{id}", c.input)
+		assert.Equal(t, c.allowed, decision.Allowed, c.name)
+		assert.Equal(t, c.rule, decision.Rule, c.name)
+		assert.Equal(t, "1", decision.Version, c.name)
+		assert.True(t, decision.Enforced, c.name)
+	}
+
+	// without matching rules the default applies, here deny
+	p, err = policy.Parse([]byte(`{"version": "2", "mode": "shadow", "default": "deny",
+		"rules": [{"effect": "allow", "routes": ["/health"], "when": "true"}]}`))
+	require.NoError(t, err)
+	decision := p.Evaluate("GET", "/users", policy.Input{})
+	assert.False(t, decision.Allowed)
+	assert.False(t, decision.Enforced)
+	assert.True(t, p.Evaluate("GET", "/health", policy.Input{}).Allowed)
+}
+
+func TestEngineReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	write := func(src string, modTime time.Time) {
+		require.NoError(t, os.WriteFile(path, []byte(src), 0o600))
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+
+	start := time.Now().Add(-time.Hour)
+	write(`{"version": "1", "default": "deny"}`, start)
+
+	engine, err := policy.NewEngine(path)
+	require.NoError(t, err)
+	assert.False(t, engine.Evaluate("GET", "/users", policy.Input{}).Allowed)
+
+	reloaded, err := engine.Reload()
+	assert.NoError(t, err)
+	assert.False(t, reloaded, "unchanged file")
+
+	write(`{"version": "2", "default": "allow"}`, start.Add(time.Minute))
+	reloaded, err = engine.Reload()
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, "2", engine.Policy().Version)
+	assert.True(t, engine.Evaluate("GET", "/users", policy.Input{}).Allowed)
+
+	// a broken version keeps the previous one active
+	write(`{"version": "3", "rules": [{"effect": "allow", "when": "nope("}]}`, start.Add(2*time.Minute))
+	_, err = engine.Reload()
+	assert.Error(t, err)
+	assert.Equal(t, "2", engine.Policy().Version)
+	_, err = engine.Reload()
+	assert.NoError(t, err, "reported once")
+
+	_, err = policy.NewEngine(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate allows Limit requests per Period, in bursts of up to Burst requests.
+// Burst defaults to Limit.
+type Rate struct {
+	Limit  int
+	Period time.Duration
+	Burst  int
+}
+
+// ParseRate parses a rate such as "10/1m", "100/h" or "5/1s:20", where the
+// number after the colon is the burst.
+func ParseRate(s string) (Rate, error) {
+	limit, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Rate{}, fmt.Errorf("rate %q is not of the form limit/period", s)
+	}
+
+	var rate Rate
+	var err error
+
+	period, burst, hasBurst := strings.Cut(period, ":")
+	if hasBurst {
+		if rate.Burst, err = strconv.Atoi(burst); err != nil || rate.Burst <= 0 {
+			return Rate{}, fmt.Errorf("invalid burst in rate %q", s)
+		}
+	}
+
+	if rate.Limit, err = strconv.Atoi(limit); err != nil || rate.Limit <= 0 {
+		return Rate{}, fmt.Errorf("invalid limit in rate %q", s)
+	}
+
+	// a bare unit stands for one of it, as in 100/h
+	if period != "" && (period[0] < '0' || period[0] > '9') {
+		period = "1" + period
+	}
+
+	if rate.Period, err = time.ParseDuration(period); err != nil || rate.Period <= 0 {
+		return Rate{}, fmt.Errorf("invalid period in rate %q", s)
+	}
+
+	// the interval between requests must be at least a nanosecond
+	if rate.interval() == 0 {
+		return Rate{}, fmt.Errorf("limit exceeds the period in rate %q", s)
+	}
+
+	return rate, nil
+}
+
+func (r Rate) burst() int {
+	if r.Burst > 0 {
+		return r.Burst
+	}
+	return r.Limit
+}
+
+// interval is the time it takes to earn back one request.
+func (r Rate) interval() time.Duration {
+	return r.Period / time.Duration(r.Limit)
+}
+
+// Store keeps the theoretical arrival time (TAT) of each key for the generic
+// cell rate algorithm: the time at which the key's burst is fully restored.
+type Store interface {
+	// Take admits a request for key if its TAT, advanced by interval, is at
+	// most tolerance after now, and then stores the advanced TAT. It
+	// returns whether the request was admitted and the TAT of the key.
+	Take(ctx context.Context, key string, now time.Time, interval, tolerance time.Duration) (bool, time.Time, error)
+}
+
+// Result is the outcome of charging a request against a Rate.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the burst is fully restored.
+	Reset time.Duration
+	// RetryAfter is how long until the next request is allowed, zero when
+	// this one was.
+	RetryAfter time.Duration
+}
+
+// Take charges a request for key against rate at now.
+func Take(ctx context.Context, store Store, key string, rate Rate, now time.Time) (Result, error) {
+	interval := rate.interval()
+	tolerance := time.Duration(rate.burst()) * interval
+
+	allowed, tat, err := store.Take(ctx, key, now, interval, tolerance)
+	if err != nil {
+		return Result{}, err
+	}
+
+	res := Result{Allowed: allowed, Limit: rate.burst(), Reset: max(tat.Sub(now), 0)}
+	if allowed {
+		res.Remaining = int((tolerance - tat.Sub(now)) / interval)
+	} else {
+		res.RetryAfter = max(tat.Add(interval).Sub(now)-tolerance, 0)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,63 @@
+package ratelimit_test
+
+import (
+	"context"
+	"go-user-api/internal/ratelimit"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRate(t *testing.T) {
+	for s, want := range map[string]ratelimit.Rate{
+		"10/1m":   {Limit: 10, Period: time.Minute},
+		"100/h":   {Limit: 100, Period: time.Hour},
+		"5/1s:20": {Limit: 5, Period: time.Second, Burst: 20},
+		"3/500ms": {Limit: 3, Period: 500 * time.Millisecond},
+	} {
+		rate, err := ratelimit.ParseRate(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, want, rate, s)
+	}
+
+	for _, s := range []string{"", "10", "0/1m", "ten/1m", "10/soon", "10/-1m", "10/1m:0", "2000000000/1s"} {
+		_, err := ratelimit.ParseRate(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestTake(t *testing.T) {
+	ctx := context.Background()
+	store := ratelimit.NewMemoryStore()
+	rate := ratelimit.Rate{Limit: 3, Period: 3 * time.Second}
+	now := time.Now()
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		res, err := ratelimit.Take(ctx, store, "a", rate, now)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 3, res.Limit)
+		assert.Equal(t, remaining, res.Remaining)
+	}
+
+	res, err := ratelimit.Take(ctx, store, "a", rate, now)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 3*time.Second, res.Reset)
+
+	// other keys have their own burst
+	res, _ = ratelimit.Take(ctx, store, "b", rate, now)
+	assert.True(t, res.Allowed)
+
+	// one request is earned back per second
+	res, _ = ratelimit.Take(ctx, store, "a", rate, now.Add(time.Second))
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	res, _ = ratelimit.Take(ctx, store, "a", rate, now.Add(time.Minute))
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 2, res.Remaining)
+}
@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps the state of the limits in memory, so each server
+// instance counts on its own.
+type MemoryStore struct {
+	mu        sync.Mutex
+	tats      map[string]time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tats: map[string]time.Time{}}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, now time.Time, interval, tolerance time.Duration) (bool, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// keys whose burst is restored hold no state worth keeping
+	if now.Sub(s.lastSweep) >= time.Minute {
+		for k, tat := range s.tats {
+			if !tat.After(now) {
+				delete(s.tats, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	tat := s.tats[key]
+	if tat.Before(now) {
+		tat = now
+	}
+
+	if next := tat.Add(interval); next.Sub(now) <= tolerance {
+		s.tats[key] = next
+		return true, next, nil
+	}
+
+	return false, tat, nil
+}
@@ -0,0 +1,65 @@
+// Package ratelimit limits how often a key, such as an email address or a
+// client IP, may perform an action.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter allows limit events per key within a sliding window. State is kept
+// in memory, so each server instance counts on its own.
+type Limiter struct {
+	limit  int
+	window time.Duration
+
+	mu        sync.Mutex
+	events    map[string][]time.Time
+	lastSweep time.Time
+}
+
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{limit: limit, window: window, events: map[string][]time.Time{}}
+}
+
+// Allow records an event for key if it is within the limit. Otherwise it
+// returns false and how long until the next event is allowed.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) >= l.window {
+		for k := range l.events {
+			l.prune(k, now)
+		}
+		l.lastSweep = now
+	}
+
+	events := l.prune(key, now)
+	if len(events) >= l.limit {
+		return false, events[0].Add(l.window).Sub(now)
+	}
+
+	l.events[key] = append(events, now)
+	return true, 0
+}
+
+// prune drops the events of key that left the window and returns the rest.
+// Keys without events are deleted, so idle keys don't accumulate.
+func (l *Limiter) prune(key string, now time.Time) []time.Time {
+	events := l.events[key]
+
+	i := 0
+	for i < len(events) && now.Sub(events[i]) >= l.window {
+		i++
+	}
+
+	if i == len(events) {
+		delete(l.events, key)
+		return nil
+	}
+
+	l.events[key] = events[i:]
+	return events[i:]
+}
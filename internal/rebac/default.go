@@ -0,0 +1,18 @@
+package rebac
+
+// DefaultSchema guards user profiles. Every user holds self on their own
+// profile (the database keeps these tuples), and the managers of a user's
+// team may edit and delete the profile.
+const DefaultSchema = `
+type team
+  relation manager: user
+  relation member: user | team#member
+  permission members = member + manager
+
+type user
+  relation self: user
+  relation team: team
+  permission manage = team->manager
+  permission edit = self + manage
+  permission view = edit + team->members
+`
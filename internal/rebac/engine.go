@@ -0,0 +1,236 @@
+package rebac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// MaxDepth bounds how many relations a Check follows, which also bounds the
+// work a deeply nested set of tuples can cause.
+const MaxDepth = 25
+
+// ErrMaxDepth is returned when a decision needs more than MaxDepth hops.
+var ErrMaxDepth = errors.New("rebac: maximum depth exceeded")
+
+// Store holds relation tuples.
+type Store interface {
+	WriteTuples(ctx context.Context, tuples ...Tuple) error
+	DeleteTuples(ctx context.Context, tuples ...Tuple) error
+	// ReadTuples returns the tuples of object#relation.
+	ReadTuples(ctx context.Context, object Object, relation string) ([]Tuple, error)
+	// ObjectIDs returns the IDs of all objects of the type that have tuples.
+	ObjectIDs(ctx context.Context, objectType string) ([]string, error)
+}
+
+type Engine struct {
+	schema *Schema
+	store  Store
+}
+
+func NewEngine(schema *Schema, store Store) *Engine {
+	return &Engine{schema: schema, store: store}
+}
+
+// Write stores tuples after checking them against the schema.
+func (e *Engine) Write(ctx context.Context, tuples ...Tuple) error {
+	for _, t := range tuples {
+		if err := e.schema.ValidateTuple(t); err != nil {
+			return err
+		}
+	}
+
+	return e.store.WriteTuples(ctx, tuples...)
+}
+
+func (e *Engine) Delete(ctx context.Context, tuples ...Tuple) error {
+	return e.store.DeleteTuples(ctx, tuples...)
+}
+
+// Check reports whether subject holds relation, a relation or permission,
+// on object.
+func (e *Engine) Check(ctx context.Context, object Object, relation string, subject Subject) (bool, error) {
+	c := &check{engine: e, subject: subject, visited: map[string]bool{}}
+	return c.eval(ctx, object, relation, 0)
+}
+
+// check is the state of one Check. Permissions are unions, so an
+// object#relation already visited cannot add anything: either it granted and
+// the check is over, or it is still being evaluated higher up.
+type check struct {
+	engine  *Engine
+	subject Subject
+	visited map[string]bool
+}
+
+func (c *check) eval(ctx context.Context, object Object, name string, depth int) (bool, error) {
+	if depth > MaxDepth {
+		return false, ErrMaxDepth
+	}
+
+	key := object.String() + "#" + name
+	if c.visited[key] {
+		return false, nil
+	}
+	c.visited[key] = true
+
+	r, err := c.engine.schema.relation(object.Type, name)
+	if err != nil {
+		return false, err
+	}
+
+	if !r.isPermission() {
+		tuples, err := c.engine.store.ReadTuples(ctx, object, name)
+		if err != nil {
+			return false, err
+		}
+
+		for _, t := range tuples {
+			if t.Subject == c.subject {
+				return true, nil
+			}
+		}
+
+		for _, t := range tuples {
+			if t.Subject.Relation == "" {
+				continue
+			}
+			if ok, err := c.eval(ctx, t.Subject.Object(), t.Subject.Relation, depth+1); ok || err != nil {
+				return ok, err
+			}
+		}
+
+		return false, nil
+	}
+
+	for _, t := range r.terms {
+		if t.Via == "" {
+			if ok, err := c.eval(ctx, object, t.Relation, depth+1); ok || err != nil {
+				return ok, err
+			}
+			continue
+		}
+
+		tuples, err := c.engine.store.ReadTuples(ctx, object, t.Via)
+		if err != nil {
+			return false, err
+		}
+
+		for _, via := range tuples {
+			if ok, err := c.eval(ctx, via.Subject.Object(), t.Relation, depth+1); ok || err != nil {
+				return ok, err
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// Node is a tree of who holds a relation. Subjects hold it directly;
+// Children are the usersets and terms it is the union of.
+type Node struct {
+	Object   string   `json:"object"`
+	Relation string   `json:"relation"`
+	Subjects []string `json:"subjects,omitempty"`
+	Children []*Node  `json:"children,omitempty"`
+}
+
+// Expand returns the tree of subjects holding relation on object. An
+// object#relation that already appears higher up the tree is not expanded
+// again.
+func (e *Engine) Expand(ctx context.Context, object Object, relation string) (*Node, error) {
+	return e.expand(ctx, object, relation, map[string]bool{}, 0)
+}
+
+func (e *Engine) expand(ctx context.Context, object Object, name string, path map[string]bool, depth int) (*Node, error) {
+	if depth > MaxDepth {
+		return nil, ErrMaxDepth
+	}
+
+	node := &Node{Object: object.String(), Relation: name}
+	key := node.Object + "#" + name
+	if path[key] {
+		return node, nil
+	}
+	path[key] = true
+	defer delete(path, key)
+
+	r, err := e.schema.relation(object.Type, name)
+	if err != nil {
+		return nil, err
+	}
+
+	add := func(target Object, relation string) error {
+		child, err := e.expand(ctx, target, relation, path, depth+1)
+		if err == nil {
+			node.Children = append(node.Children, child)
+		}
+		return err
+	}
+
+	if !r.isPermission() {
+		tuples, err := e.store.ReadTuples(ctx, object, name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, t := range tuples {
+			if t.Subject.Relation == "" {
+				node.Subjects = append(node.Subjects, t.Subject.String())
+			} else if err := add(t.Subject.Object(), t.Subject.Relation); err != nil {
+				return nil, err
+			}
+		}
+
+		return node, nil
+	}
+
+	for _, t := range r.terms {
+		if t.Via == "" {
+			if err := add(object, t.Relation); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		tuples, err := e.store.ReadTuples(ctx, object, t.Via)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, via := range tuples {
+			if err := add(via.Subject.Object(), t.Relation); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return node, nil
+}
+
+// ListObjects returns the IDs of the objects of objectType on which subject
+// holds relation. It checks every object of the type that has tuples, so
+// objects without any are never listed.
+func (e *Engine) ListObjects(ctx context.Context, objectType string, relation string, subject Subject) ([]string, error) {
+	if _, err := e.schema.relation(objectType, relation); err != nil {
+		return nil, err
+	}
+
+	ids, err := e.store.ObjectIDs(ctx, objectType)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := []string{}
+	for _, id := range ids {
+		ok, err := e.Check(ctx, Object{Type: objectType, ID: id}, relation, subject)
+		if err != nil {
+			return nil, fmt.Errorf("rebac: listing %s: %w", objectType, err)
+		}
+		if ok {
+			objects = append(objects, id)
+		}
+	}
+
+	return objects, nil
+}
@@ -0,0 +1,128 @@
+package rebac_test
+
+import (
+	"context"
+	"go-user-api/internal/rebac"
+	"go-user-api/internal/testutils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEngine(t *testing.T, tuples ...string) *rebac.Engine {
+	schema, err := rebac.ParseSchema(rebac.DefaultSchema)
+	require.NoError(t, err)
+
+	engine := rebac.NewEngine(schema, &testutils.MockTupleStore{})
+	for _, s := range tuples {
+		tuple, err := rebac.ParseTuple(s)
+		require.NoError(t, err)
+		require.NoError(t, engine.Write(context.Background(), tuple))
+	}
+
+	return engine
+}
+
+func user(id string) rebac.Subject {
+	return rebac.Subject{Type: "user", ID: id}
+}
+
+func TestParseSchema(t *testing.T) {
+	for name, src := range map[string]string{
+		"unknown keyword":       "type user\n  role admin: user",
+		"relation before type":  "relation owner: user",
+		"unknown subject type":  "type doc\n  relation owner: group",
+		"unknown userset":       "type user\ntype team\n  relation member: team#members",
+		"unknown computed term": "type user\ntype doc\n  relation owner: user\n  permission edit = owner + writer",
+		"arrow over permission": "type user\ntype doc\n  relation owner: user\n  permission p = owner\n  permission q = p->owner",
+		"duplicate relation":    "type user\ntype doc\n  relation owner: user\n  relation owner: user",
+	} {
+		_, err := rebac.ParseSchema(src)
+		assert.Error(t, err, name)
+	}
+
+	_, err := rebac.ParseSchema("// profiles\ntype user\n\ntype doc\n  relation owner: user\n  permission edit = owner")
+	assert.NoError(t, err)
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	engine := newEngine(t,
+		"user:5#self@user:5",
+		"user:5#team@team:1",
+		"team:1#manager@user:2",
+		// team 1 contains team 2, and team 2 contains team 1 back
+		"team:1#member@team:2#member",
+		"team:2#member@team:1#member",
+		"team:2#member@user:3",
+	)
+	profile := rebac.Object{Type: "user", ID: "5"}
+
+	for _, c := range []struct {
+		relation string
+		subject  string
+		allowed  bool
+	}{
+		{"edit", "5", true},    // self
+		{"edit", "2", true},    // manages the team of user 5
+		{"manage", "5", false}, // doesn't manage their own team
+		{"edit", "3", false},   // only a member
+		{"view", "3", true},    // member through the nested team
+		{"view", "4", false},
+	} {
+		allowed, err := engine.Check(ctx, profile, c.relation, user(c.subject))
+		assert.NoError(t, err)
+		assert.Equal(t, c.allowed, allowed, "user %s %s user:5", c.subject, c.relation)
+	}
+
+	_, err := engine.Check(ctx, profile, "own", user("5"))
+	assert.ErrorIs(t, err, rebac.ErrUnknownRelation)
+
+	tuple, _ := rebac.ParseTuple("team:1#manager@team:2#member")
+	assert.ErrorIs(t, engine.Write(ctx, tuple), rebac.ErrInvalidTuple)
+	tuple, _ = rebac.ParseTuple("user:5#edit@user:9")
+	assert.ErrorIs(t, engine.Write(ctx, tuple), rebac.ErrInvalidTuple, "permissions are computed")
+
+	tuple, _ = rebac.ParseTuple("team:1#manager@user:2")
+	assert.NoError(t, engine.Delete(ctx, tuple))
+	allowed, err := engine.Check(ctx, profile, "edit", user("2"))
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+func TestExpandAndListObjects(t *testing.T) {
+	ctx := context.Background()
+	engine := newEngine(t,
+		"user:5#self@user:5",
+		"user:5#team@team:1",
+		"user:6#self@user:6",
+		"user:6#team@team:1",
+		"user:7#self@user:7",
+		"team:1#manager@user:2",
+		"team:1#member@team:2#member",
+		"team:2#member@user:3",
+	)
+
+	tree, err := engine.Expand(ctx, rebac.Object{Type: "team", ID: "1"}, "members")
+	require.NoError(t, err)
+	assert.Equal(t, "team:1", tree.Object)
+	require.Len(t, tree.Children, 2)
+	member, manager := tree.Children[0], tree.Children[1]
+	assert.Equal(t, []string{"user:2"}, manager.Subjects)
+	require.Len(t, member.Children, 1)
+	assert.Equal(t, "team:2", member.Children[0].Object)
+	assert.Equal(t, []string{"user:3"}, member.Children[0].Subjects)
+
+	objects, err := engine.ListObjects(ctx, "user", "edit", user("2"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"5", "6"}, objects)
+
+	objects, err = engine.ListObjects(ctx, "user", "view", user("3"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"5", "6"}, objects)
+
+	objects, err = engine.ListObjects(ctx, "user", "edit", user("7"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"7"}, objects)
+}
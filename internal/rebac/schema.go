@@ -0,0 +1,218 @@
+// Package rebac decides authorization from relationships between objects,
+// Zanzibar style: relation tuples such as "team:1#manager@user:2" are stored,
+// and a schema derives permissions from them, e.g. that the managers of a
+// user's team may edit the user.
+package rebac
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var identifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+var (
+	// ErrUnknownRelation is returned for a relation the schema doesn't declare.
+	ErrUnknownRelation = errors.New("rebac: unknown relation")
+	// ErrInvalidTuple is returned for a tuple the schema doesn't allow.
+	ErrInvalidTuple = errors.New("rebac: invalid tuple")
+)
+
+// Schema declares the object types and their relations. Relations hold
+// tuples; permissions are computed from relations and other permissions.
+//
+//	type team
+//	  relation manager: user
+//	  relation member: user | team#member
+//
+//	type user
+//	  relation self: user
+//	  relation team: team
+//	  permission edit = self + team->manager
+//
+// A permission is the union of its terms. "self" names another relation of
+// the same object, "team->manager" the manager relation of every object the
+// team relation points to. Lines starting with // are comments.
+type Schema struct {
+	types map[string]map[string]*relation
+}
+
+type relation struct {
+	// allowed lists the subjects a relation may hold, as "type" or
+	// "type#relation" for usersets. It is empty for permissions.
+	allowed []Subject
+	terms   []term
+}
+
+func (r *relation) isPermission() bool {
+	return r.terms != nil
+}
+
+// term is one operand of a permission: Relation of the same object, or,
+// with Via set, Relation of every object the Via relation points to.
+type term struct {
+	Via      string
+	Relation string
+}
+
+// ParseSchema parses and validates a schema.
+func ParseSchema(src string) (*Schema, error) {
+	s := &Schema{types: map[string]map[string]*relation{}}
+	var current map[string]*relation
+
+	scanner := bufio.NewScanner(strings.NewReader(src))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		keyword, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+
+		var err error
+		switch keyword {
+		case "type":
+			if !identifier.MatchString(rest) {
+				err = fmt.Errorf("invalid type name %q", rest)
+			} else if s.types[rest] != nil {
+				err = fmt.Errorf("type %s declared twice", rest)
+			} else {
+				current = map[string]*relation{}
+				s.types[rest] = current
+			}
+		case "relation", "permission":
+			if current == nil {
+				err = fmt.Errorf("%s outside of a type", keyword)
+			} else {
+				err = parseRelation(current, keyword, rest)
+			}
+		default:
+			err = fmt.Errorf("unknown keyword %q", keyword)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("rebac schema line %d: %w", n, err)
+		}
+	}
+
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("rebac schema: %w", err)
+	}
+
+	return s, nil
+}
+
+func parseRelation(relations map[string]*relation, keyword string, def string) error {
+	sep := ":"
+	if keyword == "permission" {
+		sep = "="
+	}
+
+	name, body, ok := strings.Cut(def, sep)
+	name = strings.TrimSpace(name)
+	if !ok || !identifier.MatchString(name) {
+		return fmt.Errorf("expected %s name %s ...", keyword, sep)
+	}
+
+	if relations[name] != nil {
+		return fmt.Errorf("%s declared twice", name)
+	}
+
+	r := &relation{}
+	if keyword == "relation" {
+		for _, part := range strings.Split(body, "|") {
+			typ, rel, _ := strings.Cut(strings.TrimSpace(part), "#")
+			if !identifier.MatchString(typ) || (rel != "" && !identifier.MatchString(rel)) {
+				return fmt.Errorf("invalid subject type %q", strings.TrimSpace(part))
+			}
+			r.allowed = append(r.allowed, Subject{Type: typ, Relation: rel})
+		}
+	} else {
+		r.terms = []term{}
+		for _, part := range strings.Split(body, "+") {
+			part = strings.TrimSpace(part)
+			via, rel, arrow := strings.Cut(part, "->")
+			if !arrow {
+				via, rel = "", part
+			}
+			if (arrow && !identifier.MatchString(via)) || !identifier.MatchString(rel) {
+				return fmt.Errorf("invalid term %q", part)
+			}
+			r.terms = append(r.terms, term{Via: via, Relation: rel})
+		}
+	}
+
+	relations[name] = r
+	return nil
+}
+
+// validate checks that every name a relation refers to is declared.
+func (s *Schema) validate() error {
+	for typ, relations := range s.types {
+		for name, r := range relations {
+			for _, allowed := range r.allowed {
+				target := s.types[allowed.Type]
+				if target == nil {
+					return fmt.Errorf("%s#%s: unknown type %s", typ, name, allowed.Type)
+				}
+				if allowed.Relation != "" && target[allowed.Relation] == nil {
+					return fmt.Errorf("%s#%s: unknown relation %s#%s", typ, name, allowed.Type, allowed.Relation)
+				}
+			}
+
+			for _, t := range r.terms {
+				if t.Via == "" {
+					if relations[t.Relation] == nil {
+						return fmt.Errorf("%s#%s: unknown relation %s", typ, name, t.Relation)
+					}
+					continue
+				}
+
+				via := relations[t.Via]
+				if via == nil || via.isPermission() {
+					return fmt.Errorf("%s#%s: %s must be a relation of %s", typ, name, t.Via, typ)
+				}
+				for _, allowed := range via.allowed {
+					if s.types[allowed.Type][t.Relation] == nil {
+						return fmt.Errorf("%s#%s: unknown relation %s#%s", typ, name, allowed.Type, t.Relation)
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) relation(objectType string, name string) (*relation, error) {
+	r := s.types[objectType][name]
+	if r == nil {
+		return nil, fmt.Errorf("%w %s#%s", ErrUnknownRelation, objectType, name)
+	}
+
+	return r, nil
+}
+
+// ValidateTuple checks that the schema allows the tuple to be stored.
+func (s *Schema) ValidateTuple(t Tuple) error {
+	r, err := s.relation(t.Object.Type, t.Relation)
+	if err != nil {
+		return err
+	}
+
+	if r.isPermission() {
+		return fmt.Errorf("%w: %s#%s is a permission, it cannot be written", ErrInvalidTuple, t.Object.Type, t.Relation)
+	}
+
+	for _, allowed := range r.allowed {
+		if allowed.Type == t.Subject.Type && allowed.Relation == t.Subject.Relation {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s#%s cannot hold %s", ErrInvalidTuple, t.Object.Type, t.Relation, t.Subject)
+}
@@ -0,0 +1,91 @@
+package rebac
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Object is an instance of a schema type, written "type:id".
+type Object struct {
+	Type string
+	ID   string
+}
+
+func (o Object) String() string {
+	return o.Type + ":" + o.ID
+}
+
+// Subject is who a relation points to: an object, or with Relation set, the
+// set of subjects holding that relation on it ("team:1#member").
+type Subject struct {
+	Type     string
+	ID       string
+	Relation string
+}
+
+func (s Subject) String() string {
+	if s.Relation == "" {
+		return s.Type + ":" + s.ID
+	}
+
+	return s.Type + ":" + s.ID + "#" + s.Relation
+}
+
+// Object returns the object the subject names.
+func (s Subject) Object() Object {
+	return Object{Type: s.Type, ID: s.ID}
+}
+
+// Tuple states that Subject holds Relation on Object, written
+// "object#relation@subject".
+type Tuple struct {
+	Object   Object
+	Relation string
+	Subject  Subject
+}
+
+func (t Tuple) String() string {
+	return t.Object.String() + "#" + t.Relation + "@" + t.Subject.String()
+}
+
+// ParseObject parses "type:id".
+func ParseObject(s string) (Object, error) {
+	typ, id, ok := strings.Cut(s, ":")
+	if !ok || !identifier.MatchString(typ) || id == "" || strings.ContainsAny(id, "#@") {
+		return Object{}, fmt.Errorf("rebac: invalid object %q", s)
+	}
+
+	return Object{Type: typ, ID: id}, nil
+}
+
+// ParseSubject parses "type:id" or "type:id#relation".
+func ParseSubject(s string) (Subject, error) {
+	obj, rel, hasRelation := strings.Cut(s, "#")
+	o, err := ParseObject(obj)
+	if err != nil || (hasRelation && !identifier.MatchString(rel)) {
+		return Subject{}, fmt.Errorf("rebac: invalid subject %q", s)
+	}
+
+	return Subject{Type: o.Type, ID: o.ID, Relation: rel}, nil
+}
+
+// ParseTuple parses "type:id#relation@subject".
+func ParseTuple(s string) (Tuple, error) {
+	left, subject, ok := strings.Cut(s, "@")
+	obj, rel, ok2 := strings.Cut(left, "#")
+	if !ok || !ok2 || !identifier.MatchString(rel) {
+		return Tuple{}, fmt.Errorf("rebac: invalid tuple %q", s)
+	}
+
+	o, err := ParseObject(obj)
+	if err != nil {
+		return Tuple{}, err
+	}
+
+	sub, err := ParseSubject(subject)
+	if err != nil {
+		return Tuple{}, err
+	}
+
+	return Tuple{Object: o, Relation: rel, Subject: sub}, nil
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"go-user-api/internal/audit"
+	"go-user-api/internal/model"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// auditChainLock serializes appends, so that every event links to the one
+// committed just before it.
+const auditChainLock = 0x617564697400
+
+type AuditRepo struct {
+	db *pgxpool.Pool
+}
+
+type AuditRepository interface {
+	audit.Recorder
+	List(ctx context.Context, filter model.AuditFilter) ([]*model.AuditEvent, error)
+	// Walk calls fn for every event, oldest first, until fn returns an error.
+	Walk(ctx context.Context, fn func(*model.AuditEvent) error) error
+}
+
+func NewAuditRepo(db *pgxpool.Pool) *AuditRepo {
+	return &AuditRepo{db: db}
+}
+
+const auditColumns = "id, occurred_at, actor_id, impersonator_id, action, target_type, target_id, before, after, ip, user_agent, request_id, prev_hash, hash"
+
+func scanAuditEvent(row pgx.Row) (*model.AuditEvent, error) {
+	var e model.AuditEvent
+	err := row.Scan(&e.ID, &e.OccurredAt, &e.ActorID, &e.ImpersonatorID, &e.Action, &e.TargetType, &e.TargetID,
+		&e.Before, &e.After, &e.IP, &e.UserAgent, &e.RequestID, &e.PrevHash, &e.Hash)
+	return &e, err
+}
+
+// Record links e to the end of the chain and appends it.
+func (r *AuditRepo) Record(ctx context.Context, e *model.AuditEvent) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", auditChainLock); err != nil {
+			return err
+		}
+
+		err := tx.QueryRow(ctx, "SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1").Scan(&e.PrevHash)
+		if err != nil && err != pgx.ErrNoRows {
+			return err
+		}
+
+		// the column keeps microseconds, and the hash must survive the round trip
+		e.OccurredAt = time.Now().UTC().Truncate(time.Microsecond)
+		e.Hash = audit.Hash(e.PrevHash, e)
+
+		return tx.QueryRow(ctx,
+			"INSERT INTO audit_log (occurred_at, actor_id, impersonator_id, action, target_type, target_id, before, after, ip, user_agent, request_id, prev_hash, hash) "+
+				"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id",
+			e.OccurredAt, e.ActorID, e.ImpersonatorID, e.Action, e.TargetType, e.TargetID, nullJSON(e.Before), nullJSON(e.After),
+			e.IP, e.UserAgent, e.RequestID, e.PrevHash, e.Hash).Scan(&e.ID)
+	})
+}
+
+// List returns the matching events, newest first.
+func (r *AuditRepo) List(ctx context.Context, filter model.AuditFilter) ([]*model.AuditEvent, error) {
+	var conds []string
+	var args []any
+	where := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if filter.ActorID != 0 {
+		where("(actor_id = $%[1]d OR impersonator_id = $%[1]d)", filter.ActorID)
+	}
+	if filter.Action != "" {
+		where("action = $%d", filter.Action)
+	}
+	if filter.TargetType != "" {
+		where("target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		where("target_id = $%d", filter.TargetID)
+	}
+	if !filter.Since.IsZero() {
+		where("occurred_at >= $%d", filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		where("occurred_at < $%d", filter.Until.UTC())
+	}
+	if filter.BeforeID != 0 {
+		where("id < $%d", filter.BeforeID)
+	}
+
+	query := "SELECT " + auditColumns + " FROM audit_log"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	events := []*model.AuditEvent{}
+	for rows.Next() {
+		e, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+func (r *AuditRepo) Walk(ctx context.Context, fn func(*model.AuditEvent) error) error {
+	rows, err := r.db.Query(ctx, "SELECT "+auditColumns+" FROM audit_log ORDER BY id")
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanAuditEvent(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// nullJSON stores a missing document as NULL rather than invalid JSON.
+func nullJSON(doc []byte) any {
+	if len(doc) == 0 {
+		return nil
+	}
+
+	return string(doc)
+}
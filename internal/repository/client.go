@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"go-user-api/internal/model"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ClientRepo struct {
+	db *pgxpool.Pool
+}
+
+type ClientRepository interface {
+	Create(ctx context.Context, c *model.Client) error
+	Get(ctx context.Context, id int) (*model.Client, error)
+	GetByClientID(ctx context.Context, clientID string) (*model.Client, error)
+	GetAllClients(ctx context.Context) ([]*model.Client, error)
+	Update(ctx context.Context, c *model.Client) error
+	UpdateSecret(ctx context.Context, id int, secretHash string) error
+	Delete(ctx context.Context, id int) error
+}
+
+func NewClientRepo(db *pgxpool.Pool) *ClientRepo {
+	return &ClientRepo{db: db}
+}
+
+const clientColumns = "id, client_id, name, secret_hash, scopes, redirect_uris, public, created_at, secret_rotated_at"
+
+func scanClient(row interface{ Scan(...any) error }) (*model.Client, error) {
+	var c model.Client
+
+	err := row.Scan(&c.ID, &c.ClientID, &c.Name, &c.SecretHash, &c.Scopes, &c.RedirectURIs, &c.Public, &c.CreatedAt, &c.SecretRotatedAt)
+	return &c, err
+}
+
+func (r *ClientRepo) Create(ctx context.Context, c *model.Client) error {
+	return r.db.QueryRow(ctx,
+		"INSERT INTO clients (client_id, name, secret_hash, scopes, redirect_uris, public) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, secret_rotated_at",
+		c.ClientID, c.Name, c.SecretHash, c.Scopes, c.RedirectURIs, c.Public).Scan(&c.ID, &c.CreatedAt, &c.SecretRotatedAt)
+}
+
+func (r *ClientRepo) Get(ctx context.Context, id int) (*model.Client, error) {
+	return scanClient(r.db.QueryRow(ctx, "SELECT "+clientColumns+" FROM clients WHERE id = $1", id))
+}
+
+func (r *ClientRepo) GetByClientID(ctx context.Context, clientID string) (*model.Client, error) {
+	return scanClient(r.db.QueryRow(ctx, "SELECT "+clientColumns+" FROM clients WHERE client_id = $1", clientID))
+}
+
+func (r *ClientRepo) GetAllClients(ctx context.Context) ([]*model.Client, error) {
+	rows, err := r.db.Query(ctx, "SELECT "+clientColumns+" FROM clients ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var clients []*model.Client
+
+	for rows.Next() {
+		c, err := scanClient(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		clients = append(clients, c)
+	}
+
+	return clients, rows.Err()
+}
+
+func (r *ClientRepo) Update(ctx context.Context, c *model.Client) error {
+	res, err := r.db.Exec(ctx, "UPDATE clients SET name = $1, scopes = $2, redirect_uris = $3 WHERE id = $4",
+		c.Name, c.Scopes, c.RedirectURIs, c.ID)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("no clients found with id: %d", c.ID)
+	}
+
+	return nil
+}
+
+func (r *ClientRepo) UpdateSecret(ctx context.Context, id int, secretHash string) error {
+	res, err := r.db.Exec(ctx,
+		"UPDATE clients SET secret_hash = $1, secret_rotated_at = now() WHERE id = $2", secretHash, id)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("no clients found with id: %d", id)
+	}
+
+	return nil
+}
+
+func (r *ClientRepo) Delete(ctx context.Context, id int) error {
+	res, err := r.db.Exec(ctx, "DELETE FROM clients WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("no clients found with id: %d", id)
+	}
+
+	return nil
+}
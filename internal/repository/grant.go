@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"go-user-api/internal/model"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// GrantRepo stores the authorization codes and refresh tokens of the OAuth2
+// authorization server. They are created in the organization of the context
+// and looked up by the hash of their secret from any organization. The
+// denylist of access tokens is shared by all organizations.
+type GrantRepo struct {
+	db *pgxpool.Pool
+}
+
+type GrantRepository interface {
+	CreateAuthorizationCode(ctx context.Context, c *model.AuthorizationCode) error
+	// GetAuthorizationCode returns an unused, unexpired code without
+	// consuming it.
+	GetAuthorizationCode(ctx context.Context, codeHash string) (*model.AuthorizationCode, error)
+	// ConsumeAuthorizationCode marks an unused, unexpired code as used and
+	// returns it. A code can only be consumed once.
+	ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*model.AuthorizationCode, error)
+	CreateRefreshToken(ctx context.Context, t *model.RefreshToken) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	// ConsumeRefreshToken revokes an active refresh token and returns it, so
+	// that each refresh token can only be exchanged once.
+	ConsumeRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, tokenHash string) error
+	// RevokeRefreshTokens revokes those of the user in the organization of
+	// ctx.
+	RevokeRefreshTokens(ctx context.Context, clientID string, userID int) error
+	// RevokeAccessToken denylists a JWT by its jti until it expires anyway.
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	// ConsumeTokenID spends a single-use token by its jti. It reports false
+	// when the token was spent or revoked before.
+	ConsumeTokenID(ctx context.Context, jti string, expiresAt time.Time) (bool, error)
+}
+
+func NewGrantRepo(db *pgxpool.Pool) *GrantRepo {
+	return &GrantRepo{db: db}
+}
+
+func (r *GrantRepo) CreateAuthorizationCode(ctx context.Context, c *model.AuthorizationCode) error {
+	return inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO authorization_codes (code_hash, client_id, user_id, organization_id, redirect_uri, scope, code_challenge, nonce, auth_time, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			c.CodeHash, c.ClientID, c.UserID, c.OrganizationID, c.RedirectURI, c.Scope, c.CodeChallenge, c.Nonce, c.AuthTime, c.ExpiresAt)
+		return err
+	})
+}
+
+const authorizationCodeColumns = "code_hash, client_id, user_id, organization_id, redirect_uri, scope, code_challenge, nonce, auth_time, expires_at, used_at"
+
+func scanAuthorizationCode(row interface{ Scan(...any) error }) (*model.AuthorizationCode, error) {
+	var c model.AuthorizationCode
+
+	err := row.Scan(&c.CodeHash, &c.ClientID, &c.UserID, &c.OrganizationID, &c.RedirectURI, &c.Scope, &c.CodeChallenge, &c.Nonce, &c.AuthTime, &c.ExpiresAt, &c.UsedAt)
+	return &c, err
+}
+
+// queryAuthorizationCode runs a query returning the code of codeHash,
+// holding its secret.
+func (r *GrantRepo) queryAuthorizationCode(ctx context.Context, codeHash string, sql string) (*model.AuthorizationCode, error) {
+	var c *model.AuthorizationCode
+
+	err := holdingSecret(ctx, r.db, codeHash, func(tx pgx.Tx) error {
+		var err error
+		c, err = scanAuthorizationCode(tx.QueryRow(ctx, sql, codeHash))
+		return err
+	})
+	return c, err
+}
+
+func (r *GrantRepo) GetAuthorizationCode(ctx context.Context, codeHash string) (*model.AuthorizationCode, error) {
+	return r.queryAuthorizationCode(ctx, codeHash,
+		"SELECT "+authorizationCodeColumns+" FROM authorization_codes WHERE code_hash = $1 AND used_at IS NULL AND expires_at > now()")
+}
+
+func (r *GrantRepo) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*model.AuthorizationCode, error) {
+	return r.queryAuthorizationCode(ctx, codeHash,
+		`UPDATE authorization_codes SET used_at = now()
+		WHERE code_hash = $1 AND used_at IS NULL AND expires_at > now()
+		RETURNING `+authorizationCodeColumns)
+}
+
+const refreshTokenColumns = "id, token_hash, client_id, user_id, organization_id, scope, auth_time, expires_at, revoked_at, created_at"
+
+func scanRefreshToken(row interface{ Scan(...any) error }) (*model.RefreshToken, error) {
+	var t model.RefreshToken
+
+	err := row.Scan(&t.ID, &t.TokenHash, &t.ClientID, &t.UserID, &t.OrganizationID, &t.Scope, &t.AuthTime, &t.ExpiresAt, &t.RevokedAt, &t.CreatedAt)
+	return &t, err
+}
+
+// queryRefreshToken runs a query returning the refresh token of tokenHash,
+// holding its secret.
+func (r *GrantRepo) queryRefreshToken(ctx context.Context, tokenHash string, sql string) (*model.RefreshToken, error) {
+	var t *model.RefreshToken
+
+	err := holdingSecret(ctx, r.db, tokenHash, func(tx pgx.Tx) error {
+		var err error
+		t, err = scanRefreshToken(tx.QueryRow(ctx, sql, tokenHash))
+		return err
+	})
+	return t, err
+}
+
+func (r *GrantRepo) CreateRefreshToken(ctx context.Context, t *model.RefreshToken) error {
+	return inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx,
+			`INSERT INTO refresh_tokens (token_hash, client_id, user_id, organization_id, scope, auth_time, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`,
+			t.TokenHash, t.ClientID, t.UserID, t.OrganizationID, t.Scope, t.AuthTime, t.ExpiresAt).Scan(&t.ID, &t.CreatedAt)
+	})
+}
+
+func (r *GrantRepo) GetRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	return r.queryRefreshToken(ctx, tokenHash, "SELECT "+refreshTokenColumns+" FROM refresh_tokens WHERE token_hash = $1")
+}
+
+func (r *GrantRepo) ConsumeRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	return r.queryRefreshToken(ctx, tokenHash,
+		`UPDATE refresh_tokens SET revoked_at = now()
+		WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > now()
+		RETURNING `+refreshTokenColumns)
+}
+
+func (r *GrantRepo) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	return holdingSecret(ctx, r.db, tokenHash, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx,
+			"UPDATE refresh_tokens SET revoked_at = now() WHERE token_hash = $1 AND revoked_at IS NULL", tokenHash)
+		return err
+	})
+}
+
+func (r *GrantRepo) RevokeRefreshTokens(ctx context.Context, clientID string, userID int) error {
+	return inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx,
+			"UPDATE refresh_tokens SET revoked_at = now() WHERE client_id = $1 AND user_id = $2 AND revoked_at IS NULL",
+			clientID, userID)
+		return err
+	})
+}
+
+func (r *GrantRepo) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	_, err := r.db.Exec(ctx,
+		"INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING", jti, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	// entries are only needed until the token would have expired anyway
+	_, err = r.db.Exec(ctx, "DELETE FROM revoked_tokens WHERE expires_at < now()")
+	return err
+}
+
+func (r *GrantRepo) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+
+	err := r.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)", jti).Scan(&revoked)
+	return revoked, err
+}
+
+func (r *GrantRepo) ConsumeTokenID(ctx context.Context, jti string, expiresAt time.Time) (bool, error) {
+	res, err := r.db.Exec(ctx,
+		"INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING", jti, expiresAt)
+	if err != nil {
+		return false, err
+	}
+
+	return res.RowsAffected() == 1, nil
+}
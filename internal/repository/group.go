@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-user-api/internal/model"
+	"go-user-api/internal/tenant"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type GroupRepo struct {
+	db *pgxpool.Pool
+}
+
+// ErrGroupCycle is returned when nesting a group would make it a subgroup of
+// itself.
+var ErrGroupCycle = errors.New("group would contain itself")
+
+// GroupRepository is scoped to the organization of the context: groups and
+// their members belong to one organization, like users.
+type GroupRepository interface {
+	Create(ctx context.Context, g *model.Group) error
+	Get(ctx context.Context, id int) (*model.Group, error)
+	GetAllGroups(ctx context.Context) ([]*model.Group, error)
+	// Update replaces the attributes and direct members of the group. Its
+	// subgroups are left alone.
+	Update(ctx context.Context, g *model.Group) error
+	Delete(ctx context.Context, id int) error
+	// GetGroupsForUser returns the groups the user is a direct member of.
+	GetGroupsForUser(ctx context.Context, userID int) ([]*model.Group, error)
+	// GetEffectiveGroups also returns the groups that contain those groups,
+	// at any depth.
+	GetEffectiveGroups(ctx context.Context, userID int) ([]*model.Group, error)
+	AddMember(ctx context.Context, groupID int, userID int) error
+	RemoveMember(ctx context.Context, groupID int, userID int) error
+	// AddSubgroup nests childID in parentID. It fails with ErrGroupCycle if
+	// parentID is childID or one of its subgroups.
+	AddSubgroup(ctx context.Context, parentID int, childID int) error
+	RemoveSubgroup(ctx context.Context, parentID int, childID int) error
+}
+
+func NewGroupRepo(db *pgxpool.Pool) *GroupRepo {
+	return &GroupRepo{db: db}
+}
+
+// memberIDs aggregates the members of a group, in ID order.
+const memberIDs = "COALESCE((SELECT array_agg(m.user_id ORDER BY m.user_id) FROM group_members m WHERE m.group_id = g.id), '{}')"
+
+// subgroupIDs aggregates the direct subgroups of a group, in ID order.
+const subgroupIDs = "COALESCE((SELECT array_agg(s.child_id ORDER BY s.child_id) FROM group_subgroups s WHERE s.parent_id = g.id), '{}')"
+
+const groupColumns = "g.id, g.organization_id, g.display_name, g.external_id, " + memberIDs + ", " + subgroupIDs + ", g.created_at, g.updated_at"
+
+func scanGroup(row pgx.Row) (*model.Group, error) {
+	var g model.Group
+	err := row.Scan(&g.ID, &g.OrganizationID, &g.DisplayName, &g.ExternalID, &g.Members, &g.Subgroups, &g.CreatedAt, &g.UpdatedAt)
+	return &g, err
+}
+
+// Create inserts the group and its members in one transaction.
+func (r *GroupRepo) Create(ctx context.Context, g *model.Group) error {
+	return inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx,
+			"INSERT INTO groups (organization_id, display_name, external_id) VALUES ($1, $2, $3) RETURNING id, organization_id, created_at, updated_at",
+			tenant.ID(ctx), g.DisplayName, g.ExternalID).Scan(&g.ID, &g.OrganizationID, &g.CreatedAt, &g.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		return setMembers(ctx, tx, g.ID, g.Members)
+	})
+}
+
+func (r *GroupRepo) Get(ctx context.Context, id int) (*model.Group, error) {
+	var g *model.Group
+
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		var err error
+		g, err = scanGroup(tx.QueryRow(ctx, "SELECT "+groupColumns+" FROM groups g WHERE g.id = $1 AND g.organization_id = $2", id, tenant.ID(ctx)))
+		return err
+	})
+	return g, err
+}
+
+func (r *GroupRepo) GetAllGroups(ctx context.Context) ([]*model.Group, error) {
+	return r.queryGroups(ctx, "SELECT "+groupColumns+" FROM groups g WHERE g.organization_id = $1 ORDER BY g.id", tenant.ID(ctx))
+}
+
+func (r *GroupRepo) GetGroupsForUser(ctx context.Context, userID int) ([]*model.Group, error) {
+	return r.queryGroups(ctx, "SELECT "+groupColumns+
+		" FROM groups g JOIN group_members gm ON gm.group_id = g.id WHERE gm.user_id = $1 AND g.organization_id = $2 ORDER BY g.id", userID, tenant.ID(ctx))
+}
+
+// GetEffectiveGroups walks up from the user's direct groups to every group
+// that contains one of them, within the organization. UNION drops rows
+// already found, so the walk ends even if the nesting were cyclic.
+func (r *GroupRepo) GetEffectiveGroups(ctx context.Context, userID int) ([]*model.Group, error) {
+	return r.queryGroups(ctx, `
+		WITH RECURSIVE effective (id) AS (
+			SELECT gm.group_id FROM group_members gm JOIN groups g ON g.id = gm.group_id WHERE gm.user_id = $1 AND g.organization_id = $2
+			UNION
+			SELECT s.parent_id FROM group_subgroups s JOIN effective e ON s.child_id = e.id
+		)
+		SELECT `+groupColumns+` FROM groups g JOIN effective e ON e.id = g.id WHERE g.organization_id = $2 ORDER BY g.id`, userID, tenant.ID(ctx))
+}
+
+func (r *GroupRepo) queryGroups(ctx context.Context, query string, args ...any) ([]*model.Group, error) {
+	var groups []*model.Group
+
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			g, err := scanGroup(rows)
+			if err != nil {
+				return err
+			}
+
+			groups = append(groups, g)
+		}
+
+		return rows.Err()
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// Update replaces the group's attributes and its complete member list.
+func (r *GroupRepo) Update(ctx context.Context, g *model.Group) error {
+	return inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx,
+			"UPDATE groups SET display_name = $1, external_id = $2, updated_at = now() WHERE id = $3 AND organization_id = $4 RETURNING updated_at",
+			g.DisplayName, g.ExternalID, g.ID, tenant.ID(ctx)).Scan(&g.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		return setMembers(ctx, tx, g.ID, g.Members)
+	})
+}
+
+// setMembers replaces the members of the group, skipping users of other
+// organizations.
+func setMembers(ctx context.Context, tx pgx.Tx, groupID int, members []int) error {
+	if _, err := tx.Exec(ctx, "DELETE FROM group_members WHERE group_id = $1", groupID); err != nil {
+		return err
+	}
+
+	if len(members) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(ctx,
+		"INSERT INTO group_members (group_id, user_id) SELECT $1, u.id FROM users u WHERE u.id = ANY($2::int[]) AND u.organization_id = $3 ON CONFLICT DO NOTHING",
+		groupID, members, tenant.ID(ctx))
+	return err
+}
+
+// exec runs a statement in the organization of ctx and returns the number of
+// rows it changed.
+func (r *GroupRepo) exec(ctx context.Context, sql string, args ...any) (int64, error) {
+	var n int64
+
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		res, err := tx.Exec(ctx, sql, args...)
+		n = res.RowsAffected()
+		return err
+	})
+	return n, err
+}
+
+func (r *GroupRepo) Delete(ctx context.Context, id int) error {
+	n, err := r.exec(ctx, "DELETE FROM groups WHERE id = $1 AND organization_id = $2", id, tenant.ID(ctx))
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return fmt.Errorf("no groups found with id: %d", id)
+	}
+
+	return nil
+}
+
+// AddMember adds the user if both the group and the user belong to the
+// organization of ctx.
+func (r *GroupRepo) AddMember(ctx context.Context, groupID int, userID int) error {
+	_, err := r.exec(ctx,
+		`INSERT INTO group_members (group_id, user_id)
+		SELECT g.id, u.id FROM groups g, users u
+		WHERE g.id = $1 AND u.id = $2 AND g.organization_id = $3 AND u.organization_id = $3
+		ON CONFLICT DO NOTHING`, groupID, userID, tenant.ID(ctx))
+	return err
+}
+
+func (r *GroupRepo) RemoveMember(ctx context.Context, groupID int, userID int) error {
+	n, err := r.exec(ctx,
+		"DELETE FROM group_members m USING groups g WHERE m.group_id = g.id AND m.group_id = $1 AND m.user_id = $2 AND g.organization_id = $3",
+		groupID, userID, tenant.ID(ctx))
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return fmt.Errorf("user %d is not a member of group %d", userID, groupID)
+	}
+
+	return nil
+}
+
+// AddSubgroup checks for a cycle and inserts the edge in one transaction.
+// Nesting changes are serialized by an advisory lock, so two concurrent
+// changes cannot close a cycle together. Both groups must belong to the
+// organization of ctx.
+func (r *GroupRepo) AddSubgroup(ctx context.Context, parentID int, childID int) error {
+	return inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('group_subgroups'))"); err != nil {
+			return err
+		}
+
+		var found int
+		err := tx.QueryRow(ctx, "SELECT count(*) FROM groups WHERE id IN ($1, $2) AND organization_id = $3", parentID, childID, tenant.ID(ctx)).Scan(&found)
+		if err != nil {
+			return err
+		}
+
+		if want := len(slices.Compact([]int{parentID, childID})); found != want {
+			return fmt.Errorf("no groups found with ids: %d, %d", parentID, childID)
+		}
+
+		var cycle bool
+		err = tx.QueryRow(ctx, `
+			WITH RECURSIVE descendants (id) AS (
+				SELECT $1::int
+				UNION
+				SELECT s.child_id FROM group_subgroups s JOIN descendants d ON s.parent_id = d.id
+			)
+			SELECT EXISTS (SELECT 1 FROM descendants WHERE id = $2)`, childID, parentID).Scan(&cycle)
+		if err != nil {
+			return err
+		}
+
+		if cycle {
+			return ErrGroupCycle
+		}
+
+		_, err = tx.Exec(ctx,
+			"INSERT INTO group_subgroups (parent_id, child_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", parentID, childID)
+		return err
+	})
+}
+
+func (r *GroupRepo) RemoveSubgroup(ctx context.Context, parentID int, childID int) error {
+	n, err := r.exec(ctx,
+		"DELETE FROM group_subgroups s USING groups g WHERE s.parent_id = g.id AND s.parent_id = $1 AND s.child_id = $2 AND g.organization_id = $3",
+		parentID, childID, tenant.ID(ctx))
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return fmt.Errorf("group %d is not a subgroup of group %d", childID, parentID)
+	}
+
+	return nil
+}
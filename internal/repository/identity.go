@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"go-user-api/internal/model"
+	"go-user-api/internal/tenant"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IdentityRepo struct {
+	db *pgxpool.Pool
+}
+
+// IdentityRepository is scoped to the organization of the context, so an
+// external account links to a different user in each organization.
+type IdentityRepository interface {
+	// Create links the identity to a user of the organization, and fails if
+	// the user belongs to another one.
+	Create(ctx context.Context, i *model.Identity) error
+	GetByProviderSubject(ctx context.Context, provider string, subject string) (*model.Identity, error)
+	GetAllByUser(ctx context.Context, userID int) ([]*model.Identity, error)
+	Delete(ctx context.Context, id int, userID int) error
+}
+
+func NewIdentityRepo(db *pgxpool.Pool) *IdentityRepo {
+	return &IdentityRepo{db: db}
+}
+
+const identityColumns = "id, organization_id, user_id, provider, subject, email, created_at"
+
+func scanIdentity(row pgx.Row) (*model.Identity, error) {
+	var i model.Identity
+	err := row.Scan(&i.ID, &i.OrganizationID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.CreatedAt)
+	return &i, err
+}
+
+func (r *IdentityRepo) Create(ctx context.Context, i *model.Identity) error {
+	return inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx,
+			`INSERT INTO identities (organization_id, user_id, provider, subject, email)
+			SELECT organization_id, id, $2, $3, $4 FROM users WHERE id = $1 AND organization_id = $5
+			RETURNING id, organization_id, created_at`,
+			i.UserID, i.Provider, i.Subject, i.Email, tenant.ID(ctx)).Scan(&i.ID, &i.OrganizationID, &i.CreatedAt)
+	})
+}
+
+func (r *IdentityRepo) GetByProviderSubject(ctx context.Context, provider string, subject string) (*model.Identity, error) {
+	var i *model.Identity
+
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		var err error
+		i, err = scanIdentity(tx.QueryRow(ctx,
+			"SELECT "+identityColumns+" FROM identities WHERE provider = $1 AND subject = $2 AND organization_id = $3",
+			provider, subject, tenant.ID(ctx)))
+		return err
+	})
+	return i, err
+}
+
+func (r *IdentityRepo) GetAllByUser(ctx context.Context, userID int) ([]*model.Identity, error) {
+	var identities []*model.Identity
+
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx,
+			"SELECT "+identityColumns+" FROM identities WHERE user_id = $1 AND organization_id = $2 ORDER BY id", userID, tenant.ID(ctx))
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			i, err := scanIdentity(rows)
+			if err != nil {
+				return err
+			}
+
+			identities = append(identities, i)
+		}
+
+		return rows.Err()
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return identities, nil
+}
+
+func (r *IdentityRepo) Delete(ctx context.Context, id int, userID int) error {
+	return inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		res, err := tx.Exec(ctx, "DELETE FROM identities WHERE id = $1 AND user_id = $2 AND organization_id = $3", id, userID, tenant.ID(ctx))
+		if err != nil {
+			return err
+		}
+
+		if res.RowsAffected() == 0 {
+			return fmt.Errorf("no identities found with id: %d", id)
+		}
+
+		return nil
+	})
+}
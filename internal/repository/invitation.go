@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-user-api/internal/model"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type InvitationRepo struct {
+	db *pgxpool.Pool
+}
+
+// InvitationRepository is scoped to the organization of the context.
+// Invitations are accepted from other organizations, so callers scope the
+// context to the organization of the invitation, which its link names.
+type InvitationRepository interface {
+	Create(ctx context.Context, i *model.Invitation) error
+	Get(ctx context.Context, id int) (*model.Invitation, error)
+	// GetOpen returns the invitation of email that is neither accepted nor
+	// revoked, if any.
+	GetOpen(ctx context.Context, organizationID int, email string) (*model.Invitation, error)
+	GetByOrganization(ctx context.Context, organizationID int) ([]*model.Invitation, error)
+	// SetToken records a newly emailed token and its expiry.
+	SetToken(ctx context.Context, id int, tokenID string, expiresAt time.Time) error
+	// Accept claims the invitation and creates user in its organization with
+	// its role, all or nothing, so an invitation is only spent on an account
+	// that exists. Accept and Revoke report false if the invitation was no
+	// longer open.
+	Accept(ctx context.Context, id int, user *model.User) (bool, error)
+	Revoke(ctx context.Context, id int) (bool, error)
+}
+
+func NewInvitationRepo(db *pgxpool.Pool) *InvitationRepo {
+	return &InvitationRepo{db: db}
+}
+
+const invitationColumns = "id, organization_id, email, role, COALESCE(invited_by, 0), token_id, expires_at, accepted_at, revoked_at, created_at"
+
+func scanInvitation(row pgx.Row) (*model.Invitation, error) {
+	var i model.Invitation
+	err := row.Scan(&i.ID, &i.OrganizationID, &i.Email, &i.Role, &i.InvitedBy, &i.TokenID, &i.ExpiresAt, &i.AcceptedAt, &i.RevokedAt, &i.CreatedAt)
+	return &i, err
+}
+
+func (r *InvitationRepo) Create(ctx context.Context, i *model.Invitation) error {
+	return inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx,
+			"INSERT INTO invitations (organization_id, email, role, invited_by, token_id, expires_at) VALUES ($1, $2, $3, NULLIF($4, 0), $5, $6) RETURNING id, created_at",
+			i.OrganizationID, i.Email, i.Role, i.InvitedBy, i.TokenID, i.ExpiresAt).Scan(&i.ID, &i.CreatedAt)
+	})
+}
+
+func (r *InvitationRepo) queryInvitation(ctx context.Context, sql string, args ...any) (*model.Invitation, error) {
+	var i *model.Invitation
+
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		var err error
+		i, err = scanInvitation(tx.QueryRow(ctx, sql, args...))
+		return err
+	})
+	return i, err
+}
+
+// update runs an update in the organization of ctx and returns the number of
+// rows it changed.
+func (r *InvitationRepo) update(ctx context.Context, sql string, args ...any) (int64, error) {
+	var n int64
+
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		res, err := tx.Exec(ctx, sql, args...)
+		n = res.RowsAffected()
+		return err
+	})
+	return n, err
+}
+
+func (r *InvitationRepo) Get(ctx context.Context, id int) (*model.Invitation, error) {
+	return r.queryInvitation(ctx, "SELECT "+invitationColumns+" FROM invitations WHERE id = $1", id)
+}
+
+func (r *InvitationRepo) GetOpen(ctx context.Context, organizationID int, email string) (*model.Invitation, error) {
+	return r.queryInvitation(ctx,
+		"SELECT "+invitationColumns+" FROM invitations WHERE organization_id = $1 AND lower(email) = lower($2) AND accepted_at IS NULL AND revoked_at IS NULL",
+		organizationID, email)
+}
+
+func (r *InvitationRepo) GetByOrganization(ctx context.Context, organizationID int) ([]*model.Invitation, error) {
+	invitations := []*model.Invitation{}
+
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, "SELECT "+invitationColumns+" FROM invitations WHERE organization_id = $1 ORDER BY id DESC", organizationID)
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			i, err := scanInvitation(rows)
+			if err != nil {
+				return err
+			}
+			invitations = append(invitations, i)
+		}
+
+		return rows.Err()
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+func (r *InvitationRepo) SetToken(ctx context.Context, id int, tokenID string, expiresAt time.Time) error {
+	n, err := r.update(ctx, "UPDATE invitations SET token_id = $1, expires_at = $2 WHERE id = $3", tokenID, expiresAt, id)
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return fmt.Errorf("no invitations found with id: %d", id)
+	}
+
+	return nil
+}
+
+func (r *InvitationRepo) Accept(ctx context.Context, id int, u *model.User) (bool, error) {
+	accepted := false
+
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		var organizationID int
+		var role string
+		err := tx.QueryRow(ctx,
+			"UPDATE invitations SET accepted_at = now() WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > now() RETURNING organization_id, role",
+			id).Scan(&organizationID, &role)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx,
+			"INSERT INTO users (organization_id, name, email, password, email_verified, external_id, disabled, role) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, organization_id, role, created_at, updated_at",
+			organizationID, u.Name, u.Email, u.Password, u.EmailVerified, u.ExternalID, u.Disabled, role).Scan(&u.ID, &u.OrganizationID, &u.Role, &u.CreatedAt, &u.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		accepted = true
+		return nil
+	})
+
+	return accepted && err == nil, err
+}
+
+func (r *InvitationRepo) Revoke(ctx context.Context, id int) (bool, error) {
+	n, err := r.update(ctx, "UPDATE invitations SET revoked_at = now() WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL", id)
+	return n == 1, err
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"go-user-api/internal/model"
+	"go-user-api/internal/tenant"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type LoginAttemptRepo struct {
+	db *pgxpool.Pool
+}
+
+// LoginAttemptRepository is scoped to the organization of the context.
+type LoginAttemptRepository interface {
+	Create(ctx context.Context, a *model.LoginAttempt) error
+	GetByUser(ctx context.Context, userID int, limit int) ([]*model.LoginAttempt, error)
+	// CountSuccesses returns how many times the user signed in from the
+	// device with the fingerprint, and from anywhere.
+	CountSuccesses(ctx context.Context, userID int, fingerprint string) (fromDevice int, total int, err error)
+}
+
+func NewLoginAttemptRepo(db *pgxpool.Pool) *LoginAttemptRepo {
+	return &LoginAttemptRepo{db: db}
+}
+
+func (r *LoginAttemptRepo) Create(ctx context.Context, a *model.LoginAttempt) error {
+	return inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx,
+			"INSERT INTO login_attempts (organization_id, user_id, email, outcome, ip, user_agent, fingerprint, new_device) "+
+				"VALUES ($1, NULLIF($2, 0), $3, $4, $5, $6, $7, $8) RETURNING id, organization_id, created_at",
+			tenant.ID(ctx), a.UserID, a.Email, a.Outcome, a.IP, a.UserAgent, a.Fingerprint, a.NewDevice).Scan(&a.ID, &a.OrganizationID, &a.CreatedAt)
+	})
+}
+
+func (r *LoginAttemptRepo) GetByUser(ctx context.Context, userID int, limit int) ([]*model.LoginAttempt, error) {
+	attempts := []*model.LoginAttempt{}
+
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx,
+			"SELECT id, organization_id, user_id, email, outcome, ip, user_agent, fingerprint, new_device, created_at FROM login_attempts "+
+				"WHERE user_id = $1 AND organization_id = $2 ORDER BY created_at DESC, id DESC LIMIT $3", userID, tenant.ID(ctx), limit)
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var a model.LoginAttempt
+			if err := rows.Scan(&a.ID, &a.OrganizationID, &a.UserID, &a.Email, &a.Outcome, &a.IP, &a.UserAgent, &a.Fingerprint, &a.NewDevice, &a.CreatedAt); err != nil {
+				return err
+			}
+			attempts = append(attempts, &a)
+		}
+
+		return rows.Err()
+	})
+	return attempts, err
+}
+
+func (r *LoginAttemptRepo) CountSuccesses(ctx context.Context, userID int, fingerprint string) (int, int, error) {
+	var fromDevice, total int
+
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx,
+			"SELECT count(*) FILTER (WHERE fingerprint = $3), count(*) FROM login_attempts WHERE user_id = $1 AND organization_id = $2 AND outcome = 'success'",
+			userID, tenant.ID(ctx), fingerprint).Scan(&fromDevice, &total)
+	})
+	return fromDevice, total, err
+}
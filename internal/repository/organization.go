@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"go-user-api/internal/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OrganizationRepo struct {
+	db *pgxpool.Pool
+}
+
+type OrganizationRepository interface {
+	Create(ctx context.Context, o *model.Organization) error
+	Get(ctx context.Context, id int) (*model.Organization, error)
+	GetBySlug(ctx context.Context, slug string) (*model.Organization, error)
+	GetAllOrganizations(ctx context.Context) ([]*model.Organization, error)
+	Update(ctx context.Context, o *model.Organization) error
+	// Delete removes the organization with all of its users.
+	Delete(ctx context.Context, id int) error
+}
+
+func NewOrganizationRepo(db *pgxpool.Pool) *OrganizationRepo {
+	return &OrganizationRepo{db: db}
+}
+
+func scanOrganization(row pgx.Row) (*model.Organization, error) {
+	var o model.Organization
+	err := row.Scan(&o.ID, &o.Slug, &o.Name, &o.CreatedAt)
+	return &o, err
+}
+
+func (r *OrganizationRepo) Create(ctx context.Context, o *model.Organization) error {
+	return r.db.QueryRow(ctx,
+		"INSERT INTO organizations (slug, name) VALUES ($1, $2) RETURNING id, created_at", o.Slug, o.Name).Scan(&o.ID, &o.CreatedAt)
+}
+
+func (r *OrganizationRepo) Get(ctx context.Context, id int) (*model.Organization, error) {
+	return scanOrganization(r.db.QueryRow(ctx, "SELECT id, slug, name, created_at FROM organizations WHERE id = $1", id))
+}
+
+func (r *OrganizationRepo) GetBySlug(ctx context.Context, slug string) (*model.Organization, error) {
+	return scanOrganization(r.db.QueryRow(ctx, "SELECT id, slug, name, created_at FROM organizations WHERE slug = $1", slug))
+}
+
+func (r *OrganizationRepo) GetAllOrganizations(ctx context.Context) ([]*model.Organization, error) {
+	rows, err := r.db.Query(ctx, "SELECT id, slug, name, created_at FROM organizations ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	organizations := []*model.Organization{}
+	for rows.Next() {
+		o, err := scanOrganization(rows)
+		if err != nil {
+			return nil, err
+		}
+		organizations = append(organizations, o)
+	}
+
+	return organizations, rows.Err()
+}
+
+func (r *OrganizationRepo) Update(ctx context.Context, o *model.Organization) error {
+	res, err := r.db.Exec(ctx, "UPDATE organizations SET name = $1 WHERE id = $2", o.Name, o.ID)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("no organizations found with id: %d", o.ID)
+	}
+
+	return nil
+}
+
+func (r *OrganizationRepo) Delete(ctx context.Context, id int) error {
+	res, err := r.db.Exec(ctx, "DELETE FROM organizations WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("no organizations found with id: %d", id)
+	}
+
+	return nil
+}
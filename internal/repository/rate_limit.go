@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RateLimitRepo is a ratelimit.Store shared by every instance of the
+// service. Each request is one upsert, so concurrent requests for a key are
+// serialized by its row lock.
+type RateLimitRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewRateLimitRepo(db *pgxpool.Pool) *RateLimitRepo {
+	return &RateLimitRepo{db: db}
+}
+
+func (r *RateLimitRepo) Take(ctx context.Context, key string, now time.Time, interval, tolerance time.Duration) (bool, time.Time, error) {
+	now = now.UTC()
+
+	var tat time.Time
+	err := r.db.QueryRow(ctx,
+		"INSERT INTO rate_limits (key, tat) VALUES ($1, $2::timestamp + $3::interval) "+
+			"ON CONFLICT (key) DO UPDATE SET tat = GREATEST(rate_limits.tat, $2::timestamp) + $3::interval "+
+			"WHERE GREATEST(rate_limits.tat, $2::timestamp) + $3::interval <= $2::timestamp + $4::interval "+
+			"RETURNING tat",
+		key, now, interval, tolerance).Scan(&tat)
+	if err == nil {
+		return true, tat, nil
+	}
+
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return false, time.Time{}, err
+	}
+
+	// the key is over its limit, so the update was skipped
+	err = r.db.QueryRow(ctx, "SELECT tat FROM rate_limits WHERE key = $1", key).Scan(&tat)
+	return false, tat, err
+}
+
+// DeleteExpired removes the keys whose burst was restored before now.
+func (r *RateLimitRepo) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, "DELETE FROM rate_limits WHERE tat <= $1", now.UTC())
+	return tag.RowsAffected(), err
+}
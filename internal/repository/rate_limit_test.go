@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"go-user-api/internal/ratelimit"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitRepo(t *testing.T) {
+	store := NewRateLimitRepo(rlsPool(t))
+	ctx := context.Background()
+	rate := ratelimit.Rate{Limit: 2, Period: 2 * time.Second}
+	now := time.Now()
+
+	for remaining := 1; remaining >= 0; remaining-- {
+		res, err := ratelimit.Take(ctx, store, "ip:203.0.113.7", rate, now)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, remaining, res.Remaining)
+	}
+
+	res, err := ratelimit.Take(ctx, store, "ip:203.0.113.7", rate, now)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+
+	res, err = ratelimit.Take(ctx, store, "ip:203.0.113.7", rate, now.Add(time.Second))
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	deleted, err := store.DeleteExpired(ctx, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"go-user-api/internal/rebac"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TupleRepo is the Postgres rebac.Store.
+type TupleRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewTupleRepo(db *pgxpool.Pool) *TupleRepo {
+	return &TupleRepo{db: db}
+}
+
+// WriteTuples stores all tuples or none. Tuples that exist already are kept.
+func (r *TupleRepo) WriteTuples(ctx context.Context, tuples ...rebac.Tuple) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		for _, t := range tuples {
+			_, err := tx.Exec(ctx,
+				`INSERT INTO relation_tuples (object_type, object_id, relation, subject_type, subject_id, subject_relation)
+				VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT DO NOTHING`,
+				t.Object.Type, t.Object.ID, t.Relation, t.Subject.Type, t.Subject.ID, t.Subject.Relation)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *TupleRepo) DeleteTuples(ctx context.Context, tuples ...rebac.Tuple) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		for _, t := range tuples {
+			_, err := tx.Exec(ctx,
+				`DELETE FROM relation_tuples WHERE object_type = $1 AND object_id = $2 AND relation = $3
+				AND subject_type = $4 AND subject_id = $5 AND subject_relation = $6`,
+				t.Object.Type, t.Object.ID, t.Relation, t.Subject.Type, t.Subject.ID, t.Subject.Relation)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *TupleRepo) ReadTuples(ctx context.Context, object rebac.Object, relation string) ([]rebac.Tuple, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT subject_type, subject_id, subject_relation FROM relation_tuples
+		WHERE object_type = $1 AND object_id = $2 AND relation = $3 ORDER BY subject_type, subject_id, subject_relation`,
+		object.Type, object.ID, relation)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var tuples []rebac.Tuple
+	for rows.Next() {
+		t := rebac.Tuple{Object: object, Relation: relation}
+		if err := rows.Scan(&t.Subject.Type, &t.Subject.ID, &t.Subject.Relation); err != nil {
+			return nil, err
+		}
+		tuples = append(tuples, t)
+	}
+
+	return tuples, rows.Err()
+}
+
+func (r *TupleRepo) ObjectIDs(ctx context.Context, objectType string) ([]string, error) {
+	rows, err := r.db.Query(ctx, "SELECT DISTINCT object_id FROM relation_tuples WHERE object_type = $1 ORDER BY object_id", objectType)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
@@ -0,0 +1,330 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"go-user-api/internal/model"
+	"go-user-api/internal/tenant"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rlsPool migrates a throwaway schema of the database in TEST_DATABASE_URL
+// and returns a pool whose connections act as an ordinary role in it, the
+// way the application is meant to connect. The suite is skipped without a
+// database.
+func rlsPool(t *testing.T) *pgxpool.Pool {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	schema := fmt.Sprintf("rls_test_%d", time.Now().UnixNano())
+	role := schema + "_app"
+
+	config, err := pgxpool.ParseConfig(dsn)
+	require.NoError(t, err)
+	config.ConnConfig.RuntimeParams["search_path"] = schema
+
+	admin, err := pgxpool.NewWithConfig(ctx, config)
+	require.NoError(t, err)
+	t.Cleanup(admin.Close)
+
+	_, err = admin.Exec(ctx, "CREATE SCHEMA "+schema)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		admin.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE")
+		admin.Exec(ctx, "DROP ROLE IF EXISTS "+role)
+	})
+
+	files, err := filepath.Glob("../../migrations/*.up.sql")
+	require.NoError(t, err)
+	sort.Strings(files)
+
+	conn, err := admin.Acquire(ctx)
+	require.NoError(t, err)
+	for _, file := range files {
+		sql, err := os.ReadFile(file)
+		require.NoError(t, err)
+		// the simple protocol runs every statement of the file
+		_, err = conn.Conn().PgConn().Exec(ctx, string(sql)).ReadAll()
+		require.NoError(t, err, file)
+	}
+	conn.Release()
+
+	for _, stmt := range []string{
+		"CREATE ROLE " + role + " NOLOGIN",
+		"GRANT USAGE ON SCHEMA " + schema + " TO " + role,
+		"GRANT ALL ON ALL TABLES IN SCHEMA " + schema + " TO " + role,
+		"GRANT ALL ON ALL SEQUENCES IN SCHEMA " + schema + " TO " + role,
+		"GRANT " + role + " TO CURRENT_USER",
+	} {
+		_, err := admin.Exec(ctx, stmt)
+		require.NoError(t, err, stmt)
+	}
+
+	appConfig := config.Copy()
+	appConfig.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		_, err := conn.Exec(ctx, "SET ROLE "+role)
+		return err
+	}
+
+	app, err := pgxpool.NewWithConfig(ctx, appConfig)
+	require.NoError(t, err)
+	t.Cleanup(app.Close)
+
+	return app
+}
+
+func TestRowLevelSecurity(t *testing.T) {
+	db := rlsPool(t)
+	users := NewUserRepo(db)
+
+	other := &model.Organization{Slug: "other", Name: "Other"}
+	require.NoError(t, NewOrganizationRepo(db).Create(context.Background(), other))
+
+	defaultCtx := tenant.WithID(context.Background(), tenant.DefaultID)
+	otherCtx := tenant.WithID(context.Background(), other.ID)
+
+	require.NoError(t, users.Create(defaultCtx, &model.User{Name: "Default", Email: "a@example.com", Password: "x"}))
+	otherUser := &model.User{Name: "Other", Email: "a@example.com", Password: "x"}
+	require.NoError(t, users.Create(otherCtx, otherUser))
+
+	// a query that forgets its organization filter
+	countUsers := func(ctx context.Context) int {
+		var n int
+		require.NoError(t, inTenant(ctx, db, func(tx pgx.Tx) error {
+			return tx.QueryRow(ctx, "SELECT count(*) FROM users").Scan(&n)
+		}))
+		return n
+	}
+
+	t.Run("unfiltered query only sees the current organization", func(t *testing.T) {
+		assert.Equal(t, 1, countUsers(defaultCtx))
+		assert.Equal(t, 1, countUsers(otherCtx))
+
+		var organizations []int
+		require.NoError(t, inTenant(otherCtx, db, func(tx pgx.Tx) error {
+			rows, err := tx.Query(otherCtx, "SELECT organization_id FROM users")
+			if err != nil {
+				return err
+			}
+			organizations, err = pgx.CollectRows(rows, pgx.RowTo[int])
+			return err
+		}))
+		assert.Equal(t, []int{other.ID}, organizations)
+	})
+
+	t.Run("organization without users sees nothing", func(t *testing.T) {
+		assert.Equal(t, 0, countUsers(tenant.WithID(context.Background(), other.ID+1)))
+	})
+
+	t.Run("query outside a scoped transaction sees nothing", func(t *testing.T) {
+		var n int
+		require.NoError(t, db.QueryRow(context.Background(), "SELECT count(*) FROM users").Scan(&n))
+		assert.Equal(t, 0, n)
+	})
+
+	t.Run("cannot write rows of another organization", func(t *testing.T) {
+		err := inTenant(otherCtx, db, func(tx pgx.Tx) error {
+			_, err := tx.Exec(otherCtx, "INSERT INTO users (organization_id, name, email, password) VALUES ($1, 'Intruder', 'b@example.com', 'x')", tenant.DefaultID)
+			return err
+		})
+		assert.Error(t, err)
+
+		var updated int64
+		require.NoError(t, inTenant(otherCtx, db, func(tx pgx.Tx) error {
+			res, err := tx.Exec(otherCtx, "UPDATE users SET name = 'Renamed' WHERE organization_id = $1", tenant.DefaultID)
+			updated = res.RowsAffected()
+			return err
+		}))
+		assert.Zero(t, updated)
+	})
+
+	t.Run("settings are local to the transaction", func(t *testing.T) {
+		ctx := tenant.WithUser(otherCtx, 42)
+		var tenantID, userID string
+		require.NoError(t, inTenant(ctx, db, func(tx pgx.Tx) error {
+			return tx.QueryRow(ctx, "SELECT current_setting('app.tenant_id'), current_setting('app.user_id')").Scan(&tenantID, &userID)
+		}))
+		assert.Equal(t, fmt.Sprint(other.ID), tenantID)
+		assert.Equal(t, "42", userID)
+
+		var after string
+		require.NoError(t, db.QueryRow(context.Background(), "SELECT coalesce(current_setting('app.tenant_id', true), '')").Scan(&after))
+		assert.Empty(t, after)
+	})
+
+	t.Run("grants are only visible to their organization or to the holder of their secret", func(t *testing.T) {
+		require.NoError(t, NewClientRepo(db).Create(context.Background(), &model.Client{ClientID: "app", Name: "App", Scopes: []string{}, RedirectURIs: []string{}}))
+		grants := NewGrantRepo(db)
+
+		code := &model.AuthorizationCode{CodeHash: "code-hash", ClientID: "app", UserID: otherUser.ID, OrganizationID: other.ID,
+			CodeChallenge: "challenge", AuthTime: time.Now(), ExpiresAt: time.Now().Add(time.Minute)}
+		assert.Error(t, grants.CreateAuthorizationCode(defaultCtx, code), "code of another organization")
+		require.NoError(t, grants.CreateAuthorizationCode(otherCtx, code))
+
+		token := &model.RefreshToken{TokenHash: "token-hash", ClientID: "app", UserID: otherUser.ID, OrganizationID: other.ID,
+			AuthTime: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+		require.NoError(t, grants.CreateRefreshToken(otherCtx, token))
+
+		count := func(ctx context.Context, table string) int {
+			var n int
+			require.NoError(t, inTenant(ctx, db, func(tx pgx.Tx) error {
+				return tx.QueryRow(ctx, "SELECT count(*) FROM "+table).Scan(&n)
+			}))
+			return n
+		}
+		assert.Equal(t, 0, count(defaultCtx, "authorization_codes"))
+		assert.Equal(t, 0, count(defaultCtx, "refresh_tokens"))
+		assert.Equal(t, 1, count(otherCtx, "refresh_tokens"))
+
+		// the token endpoint redeems them without knowing their organization
+		redeemed, err := grants.ConsumeAuthorizationCode(defaultCtx, "code-hash")
+		require.NoError(t, err)
+		assert.Equal(t, other.ID, redeemed.OrganizationID)
+		_, err = grants.GetRefreshToken(defaultCtx, "token-hash")
+		require.NoError(t, err)
+
+		// holding one secret shows no other grant
+		require.NoError(t, holdingSecret(defaultCtx, db, "code-hash", func(tx pgx.Tx) error {
+			var n int
+			require.NoError(t, tx.QueryRow(defaultCtx, "SELECT count(*) FROM refresh_tokens").Scan(&n))
+			assert.Zero(t, n)
+			return nil
+		}))
+
+		require.NoError(t, grants.RevokeRefreshTokens(defaultCtx, "app", otherUser.ID))
+		refreshed, err := grants.GetRefreshToken(otherCtx, "token-hash")
+		require.NoError(t, err)
+		assert.Nil(t, refreshed.RevokedAt, "revoking from another organization")
+	})
+
+	t.Run("invitations are only visible to their organization", func(t *testing.T) {
+		invitations := NewInvitationRepo(db)
+
+		invitation := &model.Invitation{OrganizationID: other.ID, Email: "b@example.com", Role: model.RoleUser, ExpiresAt: time.Now().Add(time.Hour)}
+		assert.Error(t, invitations.Create(defaultCtx, invitation), "invitation of another organization")
+		require.NoError(t, invitations.Create(otherCtx, invitation))
+
+		_, err := invitations.Get(defaultCtx, invitation.ID)
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+		revoked, err := invitations.Revoke(defaultCtx, invitation.ID)
+		require.NoError(t, err)
+		assert.False(t, revoked)
+		accepted, err := invitations.Accept(defaultCtx, invitation.ID, &model.User{Name: "B", Email: "b@example.com", Password: "x"})
+		require.NoError(t, err)
+		assert.False(t, accepted)
+
+		// accepting creates the member in the invitation's organization
+		member := &model.User{Name: "B", Email: "b@example.com", Password: "x"}
+		accepted, err = invitations.Accept(otherCtx, invitation.ID, member)
+		require.NoError(t, err)
+		assert.True(t, accepted)
+		assert.Equal(t, other.ID, member.OrganizationID)
+		assert.Equal(t, model.RoleUser, member.Role)
+
+		found, err := invitations.Get(otherCtx, invitation.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "b@example.com", found.Email)
+	})
+	t.Run("groups are only visible to their organization", func(t *testing.T) {
+		groups := NewGroupRepo(db)
+
+		group := &model.Group{DisplayName: "engineering"}
+		require.NoError(t, groups.Create(otherCtx, group))
+		require.NoError(t, groups.AddMember(otherCtx, group.ID, otherUser.ID))
+
+		_, err := groups.Get(defaultCtx, group.ID)
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+		effective, err := groups.GetEffectiveGroups(defaultCtx, otherUser.ID)
+		require.NoError(t, err)
+		assert.Empty(t, effective)
+
+		// the same name is free in every organization
+		require.NoError(t, groups.Create(defaultCtx, &model.Group{DisplayName: "engineering"}))
+
+		effective, err = groups.GetEffectiveGroups(otherCtx, otherUser.ID)
+		require.NoError(t, err)
+		assert.Len(t, effective, 1)
+	})
+	t.Run("identities are only visible to their organization", func(t *testing.T) {
+		identities := NewIdentityRepo(db)
+
+		assert.Error(t, identities.Create(defaultCtx, &model.Identity{UserID: otherUser.ID, Provider: "corp", Subject: "ext-1"}), "user of another organization")
+		require.NoError(t, identities.Create(otherCtx, &model.Identity{UserID: otherUser.ID, Provider: "corp", Subject: "ext-1"}))
+
+		_, err := identities.GetByProviderSubject(defaultCtx, "corp", "ext-1")
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+
+		found, err := identities.GetByProviderSubject(otherCtx, "corp", "ext-1")
+		require.NoError(t, err)
+		assert.Equal(t, other.ID, found.OrganizationID)
+	})
+	t.Run("sessions and login attempts are only visible to their organization", func(t *testing.T) {
+		sessions := NewSessionRepo(db)
+		session := &model.Session{UserID: otherUser.ID, ExpiresAt: time.Now().Add(time.Hour)}
+		require.NoError(t, sessions.Create(otherCtx, session))
+		assert.Equal(t, other.ID, session.OrganizationID)
+
+		_, err := sessions.Get(defaultCtx, session.ID)
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+		assert.Error(t, sessions.Revoke(defaultCtx, session.ID, otherUser.ID))
+		found, err := sessions.Get(otherCtx, session.ID)
+		require.NoError(t, err)
+		assert.True(t, found.Active())
+
+		attempts := NewLoginAttemptRepo(db)
+		require.NoError(t, attempts.Create(otherCtx, &model.LoginAttempt{UserID: otherUser.ID, Outcome: model.LoginSuccess, Fingerprint: "device"}))
+
+		_, total, err := attempts.CountSuccesses(defaultCtx, otherUser.ID, "device")
+		require.NoError(t, err)
+		assert.Zero(t, total)
+		history, err := attempts.GetByUser(otherCtx, otherUser.ID, 10)
+		require.NoError(t, err)
+		assert.Len(t, history, 1)
+
+		err = inTenant(defaultCtx, db, func(tx pgx.Tx) error {
+			_, err := tx.Exec(defaultCtx, "INSERT INTO sessions (organization_id, user_id, expires_at) VALUES ($1, $2, now())", other.ID, otherUser.ID)
+			return err
+		})
+		assert.Error(t, err, "session in another organization")
+	})
+	t.Run("group members and subgroups are only visible with their group", func(t *testing.T) {
+		groups := NewGroupRepo(db)
+		parent := &model.Group{DisplayName: "platform"}
+		child := &model.Group{DisplayName: "sre"}
+		require.NoError(t, groups.Create(otherCtx, parent))
+		require.NoError(t, groups.Create(otherCtx, child))
+		require.NoError(t, groups.AddMember(otherCtx, child.ID, otherUser.ID))
+		require.NoError(t, groups.AddSubgroup(otherCtx, parent.ID, child.ID))
+
+		// queries that forget to join groups
+		count := func(ctx context.Context, table string) int {
+			var n int
+			require.NoError(t, inTenant(ctx, db, func(tx pgx.Tx) error {
+				return tx.QueryRow(ctx, "SELECT count(*) FROM "+table).Scan(&n)
+			}))
+			return n
+		}
+		assert.Zero(t, count(defaultCtx, "group_subgroups"))
+		assert.Equal(t, 1, count(otherCtx, "group_subgroups"))
+		assert.Equal(t, 2, count(otherCtx, "group_members"), "members of engineering and sre")
+		assert.Zero(t, count(defaultCtx, "group_members"))
+
+		err := inTenant(defaultCtx, db, func(tx pgx.Tx) error {
+			_, err := tx.Exec(defaultCtx, "INSERT INTO group_members (group_id, user_id) VALUES ($1, $2)", child.ID, otherUser.ID)
+			return err
+		})
+		assert.Error(t, err, "member of a group of another organization")
+	})
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"go-user-api/internal/model"
+	"go-user-api/internal/tenant"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SessionRepo struct {
+	db *pgxpool.Pool
+}
+
+// SessionRepository is scoped to the organization of the context.
+type SessionRepository interface {
+	Create(ctx context.Context, s *model.Session) error
+	Get(ctx context.Context, id int) (*model.Session, error)
+	// GetActiveByUser lists the sessions that are neither revoked nor expired.
+	GetActiveByUser(ctx context.Context, userID int) ([]*model.Session, error)
+	Touch(ctx context.Context, id int) error
+	Revoke(ctx context.Context, id int, userID int) error
+}
+
+func NewSessionRepo(db *pgxpool.Pool) *SessionRepo {
+	return &SessionRepo{db: db}
+}
+
+const sessionColumns = "id, organization_id, user_id, ip, user_agent, created_at, last_seen_at, expires_at, revoked_at"
+
+func scanSession(row pgx.Row) (*model.Session, error) {
+	var s model.Session
+	err := row.Scan(&s.ID, &s.OrganizationID, &s.UserID, &s.IP, &s.UserAgent, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt, &s.RevokedAt)
+	return &s, err
+}
+
+func (r *SessionRepo) Create(ctx context.Context, s *model.Session) error {
+	return inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx,
+			"INSERT INTO sessions (organization_id, user_id, ip, user_agent, expires_at) VALUES ($1, $2, $3, $4, $5) RETURNING id, organization_id, created_at, last_seen_at",
+			tenant.ID(ctx), s.UserID, s.IP, s.UserAgent, s.ExpiresAt).Scan(&s.ID, &s.OrganizationID, &s.CreatedAt, &s.LastSeenAt)
+	})
+}
+
+func (r *SessionRepo) Get(ctx context.Context, id int) (*model.Session, error) {
+	var s *model.Session
+
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		var err error
+		s, err = scanSession(tx.QueryRow(ctx,
+			"SELECT "+sessionColumns+" FROM sessions WHERE id = $1 AND organization_id = $2", id, tenant.ID(ctx)))
+		return err
+	})
+	return s, err
+}
+
+func (r *SessionRepo) GetActiveByUser(ctx context.Context, userID int) ([]*model.Session, error) {
+	var sessions []*model.Session
+
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, "SELECT "+sessionColumns+
+			" FROM sessions WHERE user_id = $1 AND organization_id = $2 AND revoked_at IS NULL AND expires_at > now() ORDER BY last_seen_at DESC",
+			userID, tenant.ID(ctx))
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			s, err := scanSession(rows)
+			if err != nil {
+				return err
+			}
+
+			sessions = append(sessions, s)
+		}
+
+		return rows.Err()
+	})
+	return sessions, err
+}
+
+// Touch records that the session was just used.
+func (r *SessionRepo) Touch(ctx context.Context, id int) error {
+	return inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, "UPDATE sessions SET last_seen_at = now() WHERE id = $1 AND organization_id = $2", id, tenant.ID(ctx))
+		return err
+	})
+}
+
+func (r *SessionRepo) Revoke(ctx context.Context, id int, userID int) error {
+	return inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		res, err := tx.Exec(ctx,
+			"UPDATE sessions SET revoked_at = now() WHERE id = $1 AND user_id = $2 AND organization_id = $3 AND revoked_at IS NULL",
+			id, userID, tenant.ID(ctx))
+		if err != nil {
+			return err
+		}
+
+		if res.RowsAffected() == 0 {
+			return fmt.Errorf("no active sessions found with id: %d", id)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"go-user-api/internal/tenant"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// inTenant runs fn in a transaction scoped to the organization and user of
+// ctx. The app.tenant_id setting drives the row-level security policies on
+// tenant-owned tables, so even a query missing its organization filter
+// cannot see another organization's rows. Both settings are local to the
+// transaction and never leak to the next user of the pooled connection.
+func inTenant(ctx context.Context, db *pgxpool.Pool, fn func(tx pgx.Tx) error) error {
+	return holdingSecret(ctx, db, "", fn)
+}
+
+// holdingSecret runs fn like inTenant, and also lets it see the
+// authorization code or refresh token whose secret hashes to secretHash,
+// whichever organization it belongs to. Presenting the secret is what
+// entitles a client to its grant, and the token endpoint is not scoped to
+// an organization.
+func holdingSecret(ctx context.Context, db *pgxpool.Pool, secretHash string, fn func(tx pgx.Tx) error) error {
+	return pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx,
+			"SELECT set_config('app.tenant_id', $1, true), set_config('app.user_id', $2, true), set_config('app.secret_hash', $3, true)",
+			strconv.Itoa(tenant.ID(ctx)), strconv.Itoa(tenant.UserID(ctx)), secretHash)
+		if err != nil {
+			return err
+		}
+
+		return fn(tx)
+	})
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"go-user-api/internal/model"
+	"go-user-api/internal/tenant"
+	"go-user-api/internal/tracing"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestUserRepoSpans(t *testing.T) {
+	db := rlsPool(t)
+	users := NewUserRepo(db)
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	ctx, parent := provider.Tracer("test").Start(tenant.WithID(context.Background(), tenant.DefaultID), "request")
+	require.NoError(t, users.Create(ctx, &model.User{Name: "Jane", Email: "jane@example.com", Password: "x"}))
+	require.NoError(t, users.Create(ctx, &model.User{Name: "John", Email: "john@example.com", Password: "x"}))
+	_, err := users.GetAllUsers(ctx)
+	require.NoError(t, err)
+	_, err = users.GetByEmail(ctx, "nobody@example.com")
+	require.Error(t, err)
+	parent.End()
+
+	rows := map[string]int64{}
+	for _, span := range exporter.GetSpans() {
+		if span.Name == "request" {
+			continue
+		}
+
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID(), span.Name)
+		assert.Contains(t, span.Attributes, attribute.String("db.statement.name", span.Name))
+		for _, attr := range span.Attributes {
+			if attr.Key == "db.rows" {
+				rows[span.Name] += attr.Value.AsInt64()
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]int64{"users.create": 2, "users.list": 2, "users.get_by_email": 0}, rows)
+}
@@ -3,8 +3,11 @@ package repository
 import (
 	"context"
 	"fmt"
+	"go-user-api/internal/logging"
 	"go-user-api/internal/model"
+	"go-user-api/internal/tenant"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -12,12 +15,18 @@ type UserRepo struct {
 	db *pgxpool.Pool
 }
 
+// UserRepository is scoped to the organization of the context: every query
+// only sees, and Create only adds, users of that organization. UserRepo runs
+// each query in a transaction scoped by inTenant, so the row-level security
+// policy on users enforces the same boundary.
 type UserRepository interface {
 	Create(ctx context.Context, u *model.User) error
 	Get(ctx context.Context, id int) (*model.User, error)
 	GetAllUsers(ctx context.Context) ([]*model.User, error)
 	GetByEmail(ctx context.Context, email string) (*model.User, error)
 	Update(ctx context.Context, u *model.User) error
+	UpdateProvisioned(ctx context.Context, u *model.User) error
+	UpdateRole(ctx context.Context, id int, role string) error
 	Delete(ctx context.Context, id int) error
 }
 
@@ -26,42 +35,60 @@ func NewUserRepo(db *pgxpool.Pool) *UserRepo {
 }
 
 func (r *UserRepo) Create(ctx context.Context, u *model.User) error {
-	return r.db.QueryRow(ctx,
-		"INSERT INTO users (name, email, password) VALUES ($1, $2, $3) RETURNING id", u.Name, u.Email, u.Password).Scan(&u.ID)
-
+	ctx, span := startSpan(ctx, "users.create")
+
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx,
+			"INSERT INTO users (organization_id, name, email, password, email_verified, external_id, disabled) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, organization_id, created_at, updated_at",
+			tenant.ID(ctx), u.Name, u.Email, u.Password, u.EmailVerified, u.ExternalID, u.Disabled).Scan(&u.ID, &u.OrganizationID, &u.CreatedAt, &u.UpdatedAt)
+	})
+	endSpan(span, rowCount(err), err)
+	return err
 }
 
 func (r *UserRepo) Get(ctx context.Context, id int) (*model.User, error) {
-	row := r.db.QueryRow(ctx, "SELECT id, name, email FROM users WHERE id = $1", id)
+	ctx, span := startSpan(ctx, "users.get")
 	var u model.User
 
-	err := row.Scan(&u.ID, &u.Name, &u.Email)
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx,
+			"SELECT id, organization_id, name, email, role, email_verified, external_id, disabled, created_at, updated_at FROM users WHERE id = $1 AND organization_id = $2",
+			id, tenant.ID(ctx)).Scan(&u.ID, &u.OrganizationID, &u.Name, &u.Email, &u.Role, &u.EmailVerified, &u.ExternalID, &u.Disabled, &u.CreatedAt, &u.UpdatedAt)
+	})
+	endSpan(span, rowCount(err), err)
 	return &u, err
 }
 
 func (r *UserRepo) GetAllUsers(ctx context.Context) ([]*model.User, error) {
-	rows, err := r.db.Query(ctx, "SELECT id, name, email, password, created_at FROM users")
+	ctx, span := startSpan(ctx, "users.list")
+	var users []*model.User
 
-	if err != nil {
-		return nil, err
-	}
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx,
+			"SELECT id, organization_id, name, email, password, role, email_verified, external_id, disabled, created_at, updated_at FROM users WHERE organization_id = $1 ORDER BY id",
+			tenant.ID(ctx))
 
-	defer rows.Close()
+		if err != nil {
+			return err
+		}
 
-	var users []*model.User
+		defer rows.Close()
 
-	for rows.Next() {
-		var u model.User
-		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Password, &u.CreatedAt); err != nil {
+		for rows.Next() {
+			var u model.User
+			if err := rows.Scan(&u.ID, &u.OrganizationID, &u.Name, &u.Email, &u.Password, &u.Role, &u.EmailVerified, &u.ExternalID, &u.Disabled, &u.CreatedAt, &u.UpdatedAt); err != nil {
+				logging.FromContext(ctx).Error("could not scan user", "error", err)
+				return err
+			}
 
-			fmt.Println("error: ", err.Error())
-			return nil, err
+			users = append(users, &u)
 		}
 
-		users = append(users, &u)
-	}
+		return rows.Err()
+	})
+	endSpan(span, int64(len(users)), err)
 
-	if err := rows.Err(); err != nil {
+	if err != nil {
 		return nil, err
 	}
 
@@ -69,26 +96,86 @@ func (r *UserRepo) GetAllUsers(ctx context.Context) ([]*model.User, error) {
 }
 
 func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*model.User, error) {
-	row := r.db.QueryRow(ctx, "SELECT id, name, email, password FROM users WHERE email = $1", email)
+	ctx, span := startSpan(ctx, "users.get_by_email")
 	var u model.User
 
-	err := row.Scan(&u.ID, &u.Name, &u.Email, &u.Password)
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx,
+			"SELECT id, organization_id, name, email, password, role, email_verified, external_id, disabled, created_at, updated_at FROM users WHERE email = $1 AND organization_id = $2",
+			email, tenant.ID(ctx)).Scan(&u.ID, &u.OrganizationID, &u.Name, &u.Email, &u.Password, &u.Role, &u.EmailVerified, &u.ExternalID, &u.Disabled, &u.CreatedAt, &u.UpdatedAt)
+	})
+	endSpan(span, rowCount(err), err)
 	return &u, err
 }
 
 func (r *UserRepo) Update(ctx context.Context, u *model.User) error {
-	_, err := r.db.Exec(ctx,
-		"UPDATE users SET name = $1, email = $2 WHERE id = $3", u.Name, u.Email, u.ID)
+	ctx, span := startSpan(ctx, "users.update")
+	var rows int64
+
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		res, err := tx.Exec(ctx,
+			"UPDATE users SET name = $1, email = $2, updated_at = now() WHERE id = $3 AND organization_id = $4",
+			u.Name, u.Email, u.ID, tenant.ID(ctx))
+		rows = res.RowsAffected()
+		return err
+	})
+	endSpan(span, rows, err)
+	return err
+}
 
+// UpdateProvisioned writes the attributes a provisioning client manages:
+// name, email, external ID and whether the account is disabled.
+func (r *UserRepo) UpdateProvisioned(ctx context.Context, u *model.User) error {
+	ctx, span := startSpan(ctx, "users.update_provisioned")
+
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx,
+			"UPDATE users SET name = $1, email = $2, external_id = $3, disabled = $4, updated_at = now() WHERE id = $5 AND organization_id = $6 RETURNING updated_at",
+			u.Name, u.Email, u.ExternalID, u.Disabled, u.ID, tenant.ID(ctx)).Scan(&u.UpdatedAt)
+	})
+	endSpan(span, rowCount(err), err)
+	return err
+}
+
+func (r *UserRepo) UpdateRole(ctx context.Context, id int, role string) error {
+	ctx, span := startSpan(ctx, "users.update_role")
+	var rows int64
+
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		res, err := tx.Exec(ctx,
+			"UPDATE users SET role = $1, updated_at = now() WHERE id = $2 AND organization_id = $3", role, id, tenant.ID(ctx))
+		if err != nil {
+			return err
+		}
+
+		rows = res.RowsAffected()
+		if rows == 0 {
+			return fmt.Errorf("no users found with id: %d", id)
+		}
+
+		return nil
+	})
+	endSpan(span, rows, err)
 	return err
 }
 
 func (r *UserRepo) Delete(ctx context.Context, id int) error {
-	res, err := r.db.Exec(ctx, "DELETE FROM users WHERE id = $1", id)
+	ctx, span := startSpan(ctx, "users.delete")
+	var rows int64
 
-	if res.RowsAffected() == 0 {
-		return fmt.Errorf("no users found with id: %d", id)
-	}
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		res, err := tx.Exec(ctx, "DELETE FROM users WHERE id = $1 AND organization_id = $2", id, tenant.ID(ctx))
+		if err != nil {
+			return err
+		}
+
+		rows = res.RowsAffected()
+		if rows == 0 {
+			return fmt.Errorf("no users found with id: %d", id)
+		}
 
+		return nil
+	})
+	endSpan(span, rows, err)
 	return err
 }
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterAuditRoutes(r chi.Router, auditHandler *handler.AuditHandler, jwtAuth *middleware.JWTAuth, users repository.UserRepository) {
+	r.Route("/admin/audit", func(r chi.Router) {
+		// the log spans all organizations
+		r.Use(jwtAuth.Middleware, middleware.RequireAdmin(users), middleware.RequireDefaultTenant)
+
+		r.Get("/", auditHandler.GetAuditLog)
+		r.Get("/verify", auditHandler.VerifyAuditLog)
+	})
+}
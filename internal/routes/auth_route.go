@@ -3,12 +3,19 @@ package routes
 import (
 	"go-user-api/internal/handler"
 	"go-user-api/internal/middleware"
+	"net/http"
 
 	"github.com/go-chi/chi/v5"
 )
 
-func RegisterAuthRoutes(r chi.Router, authHandler *handler.AuthRouteHandler) {
-	r.Post("/auth/signup", authHandler.Signup)
-	r.Post("/auth/login", authHandler.Login)
-	r.With(middleware.JWTAuthMiddleware).Get("/auth/profile", authHandler.GetUserProfile)
+// RegisterAuthRoutes serves sign-up and password login behind limits, e.g.
+// a rate limit per client address, and the routes of signed-in users.
+// Anyone may sign up to the default organization only; the others take
+// members by invitation.
+func RegisterAuthRoutes(r chi.Router, authHandler *handler.AuthRouteHandler, jwtAuth *middleware.JWTAuth, limits ...func(http.Handler) http.Handler) {
+	r.With(limits...).With(middleware.RequireDefaultTenant).Post("/auth/signup", authHandler.Signup)
+	r.With(limits...).Post("/auth/login", authHandler.Login)
+	r.With(jwtAuth.Middleware).Get("/auth/profile", authHandler.GetUserProfile)
+	r.With(jwtAuth.Middleware).Post("/auth/logout", authHandler.Logout)
+	r.With(jwtAuth.Middleware).Get("/auth/login-history", authHandler.GetLoginHistory)
 }
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterClientRoutes(r chi.Router, clientHandler *handler.ClientHandler, jwtAuth *middleware.JWTAuth, users repository.UserRepository) {
+	r.Route("/admin/clients", func(r chi.Router) {
+		// clients are shared by all organizations
+		r.Use(jwtAuth.Middleware, middleware.RequireAdmin(users), middleware.RequireDefaultTenant)
+
+		r.Post("/", clientHandler.CreateClient)
+		r.Get("/", clientHandler.GetAllClients)
+		r.Get("/{id}", clientHandler.GetClient)
+		r.Put("/{id}", clientHandler.UpdateClient)
+		r.Delete("/{id}", clientHandler.DeleteClient)
+		r.Post("/{id}/secret", clientHandler.RotateClientSecret)
+	})
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterFederationRoutes(r chi.Router, federationHandler *handler.FederationHandler, jwtAuth *middleware.JWTAuth) {
+	r.Get("/auth/oidc/providers", federationHandler.GetProviders)
+	r.Get("/auth/oidc/{provider}/login", federationHandler.Login)
+	r.Get("/auth/oidc/{provider}/callback", federationHandler.Callback)
+	r.With(jwtAuth.Middleware, middleware.DenyImpersonation).Post("/auth/oidc/{provider}/link", federationHandler.Link)
+	r.With(jwtAuth.Middleware).Get("/auth/identities", federationHandler.GetIdentities)
+	r.With(jwtAuth.Middleware, middleware.DenyImpersonation).Delete("/auth/identities/{id}", federationHandler.DeleteIdentity)
+}
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/repository"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RegisterGroupRoutes serves group administration. Groups belong to an
+// organization, and its admins manage them.
+func RegisterGroupRoutes(r chi.Router, groupHandler *handler.GroupHandler, jwtAuth *middleware.JWTAuth, users repository.UserRepository) {
+	admin := []func(http.Handler) http.Handler{jwtAuth.Middleware, middleware.RequireAdmin(users)}
+
+	r.Route("/admin/groups", func(r chi.Router) {
+		r.Use(admin...)
+
+		r.Get("/", groupHandler.GetGroups)
+		r.Post("/", groupHandler.CreateGroup)
+		r.Get("/{id}", groupHandler.GetGroup)
+		r.Put("/{id}", groupHandler.UpdateGroup)
+		r.Delete("/{id}", groupHandler.DeleteGroup)
+		r.Put("/{id}/members/{userID}", groupHandler.AddGroupMember)
+		r.Delete("/{id}/members/{userID}", groupHandler.RemoveGroupMember)
+		r.Put("/{id}/subgroups/{childID}", groupHandler.AddSubgroup)
+		r.Delete("/{id}/subgroups/{childID}", groupHandler.RemoveSubgroup)
+	})
+
+	r.With(admin...).Get("/admin/users/{id}/groups", groupHandler.GetUserGroups)
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterImpersonationRoutes(r chi.Router, impersonationHandler *handler.ImpersonationHandler, jwtAuth *middleware.JWTAuth, users repository.UserRepository) {
+	r.With(jwtAuth.Middleware, middleware.DenyImpersonation, middleware.RequireAdmin(users)).
+		Post("/admin/users/{id}/impersonate", impersonationHandler.Impersonate)
+}
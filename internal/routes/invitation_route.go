@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterInvitationRoutes(r chi.Router, invitationHandler *handler.InvitationHandler, jwtAuth *middleware.JWTAuth, users repository.UserRepository) {
+	r.Route("/orgs/{id}/invitations", func(r chi.Router) {
+		r.Use(jwtAuth.Middleware, middleware.RequireAdmin(users), middleware.RequireOwnOrganization)
+
+		r.Get("/", invitationHandler.GetInvitations)
+		r.Post("/", invitationHandler.CreateInvitation)
+		r.Post("/{invitationID}/resend", invitationHandler.ResendInvitation)
+		r.Delete("/{invitationID}", invitationHandler.RevokeInvitation)
+	})
+
+	r.Get("/invitations", invitationHandler.PreviewInvitation)
+	r.Post("/invitations/signup", invitationHandler.InvitationSignup)
+	r.With(jwtAuth.Middleware, middleware.DenyImpersonation).Post("/invitations/accept", invitationHandler.AcceptInvitation)
+}
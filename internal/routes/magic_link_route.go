@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"go-user-api/internal/handler"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterMagicLinkRoutes(r chi.Router, magicLinkHandler *handler.MagicLinkHandler) {
+	r.Post("/auth/magic-link", magicLinkHandler.RequestMagicLink)
+	r.Get("/auth/magic-link/callback", magicLinkHandler.MagicLinkCallback)
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"go-user-api/internal/handler"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RegisterOAuthRoutes serves the authorization server. Its login form checks
+// passwords, so it is behind limits like password login.
+func RegisterOAuthRoutes(r chi.Router, oauthHandler *handler.OAuthHandler, limits ...func(http.Handler) http.Handler) {
+	r.Get("/oauth/authorize", oauthHandler.Authorize)
+	r.With(limits...).Post("/oauth/authorize", oauthHandler.AuthorizeSubmit)
+	r.Post("/oauth/token", oauthHandler.Token)
+	r.Post("/oauth/introspect", oauthHandler.Introspect)
+	r.Post("/oauth/revoke", oauthHandler.Revoke)
+}
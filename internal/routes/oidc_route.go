@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterOIDCRoutes(r chi.Router, oidcHandler *handler.OIDCHandler, jwtAuth *middleware.JWTAuth) {
+	r.Get("/.well-known/openid-configuration", oidcHandler.Discovery)
+	r.Get("/.well-known/jwks.json", oidcHandler.JWKS)
+	r.With(jwtAuth.OAuthMiddleware).Get("/userinfo", oidcHandler.UserInfo)
+	r.With(jwtAuth.OAuthMiddleware).Post("/userinfo", oidcHandler.UserInfo)
+}
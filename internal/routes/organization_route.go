@@ -0,0 +1,36 @@
+package routes
+
+import (
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterOrganizationRoutes(r chi.Router, organizationHandler *handler.OrganizationHandler, jwtAuth *middleware.JWTAuth, users repository.UserRepository) {
+	r.Route("/admin/organizations", func(r chi.Router) {
+		r.Use(jwtAuth.Middleware, middleware.RequireAdmin(users), middleware.RequireDefaultTenant)
+
+		r.Get("/", organizationHandler.GetOrganizations)
+		r.Post("/", organizationHandler.CreateOrganization)
+		r.Get("/{id}", organizationHandler.GetOrganization)
+		r.Put("/{id}", organizationHandler.UpdateOrganization)
+		r.Delete("/{id}", organizationHandler.DeleteOrganization)
+	})
+
+	r.Route("/tenant", func(r chi.Router) {
+		r.Use(jwtAuth.Middleware)
+
+		r.Get("/", organizationHandler.GetCurrentOrganization)
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireAdmin(users))
+
+			r.Get("/members", organizationHandler.GetMembers)
+			r.Post("/members", organizationHandler.CreateMember)
+			r.Put("/members/{id}/role", organizationHandler.UpdateMemberRole)
+			r.Delete("/members/{id}", organizationHandler.DeleteMember)
+		})
+	})
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterRelationRoutes(r chi.Router, relationHandler *handler.RelationHandler, jwtAuth *middleware.JWTAuth, users repository.UserRepository) {
+	r.Route("/admin/relations", func(r chi.Router) {
+		r.Use(jwtAuth.Middleware, middleware.RequireAdmin(users), middleware.RequireDefaultTenant)
+
+		r.Post("/", relationHandler.WriteRelation)
+		r.Delete("/", relationHandler.DeleteRelation)
+		r.Get("/check", relationHandler.CheckRelation)
+		r.Get("/expand", relationHandler.ExpandRelation)
+		r.Get("/objects", relationHandler.ListRelationObjects)
+	})
+}
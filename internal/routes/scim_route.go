@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterSCIMRoutes(r chi.Router, scimHandler *handler.SCIMHandler, token string) {
+	r.Route("/scim/v2", func(r chi.Router) {
+		r.Use(middleware.SCIMAuth(token))
+
+		r.Get("/ServiceProviderConfig", scimHandler.GetServiceProviderConfig)
+		r.Get("/ResourceTypes", scimHandler.GetResourceTypes)
+		r.Get("/ResourceTypes/{id}", scimHandler.GetResourceType)
+		r.Get("/Schemas", scimHandler.GetSchemas)
+		r.Get("/Schemas/{id}", scimHandler.GetSchema)
+
+		r.Get("/Users", scimHandler.GetUsers)
+		r.Post("/Users", scimHandler.CreateUser)
+		r.Get("/Users/{id}", scimHandler.GetUser)
+		r.Put("/Users/{id}", scimHandler.ReplaceUser)
+		r.Patch("/Users/{id}", scimHandler.PatchUser)
+		r.Delete("/Users/{id}", scimHandler.DeleteUser)
+
+		r.Get("/Groups", scimHandler.GetGroups)
+		r.Post("/Groups", scimHandler.CreateGroup)
+		r.Get("/Groups/{id}", scimHandler.GetGroup)
+		r.Put("/Groups/{id}", scimHandler.ReplaceGroup)
+		r.Patch("/Groups/{id}", scimHandler.PatchGroup)
+		r.Delete("/Groups/{id}", scimHandler.DeleteGroup)
+	})
+}
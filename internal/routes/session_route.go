@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterSessionRoutes(r chi.Router, sessionHandler *handler.SessionHandler, jwtAuth *middleware.JWTAuth, users repository.UserRepository) {
+	r.With(jwtAuth.Middleware).Get("/auth/sessions", sessionHandler.GetSessions)
+	r.With(jwtAuth.Middleware, middleware.DenyImpersonation).Delete("/auth/sessions/{id}", sessionHandler.DeleteSession)
+
+	r.Route("/admin/users/{id}/sessions", func(r chi.Router) {
+		r.Use(jwtAuth.Middleware, middleware.RequireAdmin(users), middleware.RequireTenantUser(users))
+
+		r.Get("/", sessionHandler.GetUserSessions)
+		r.Delete("/{sessionId}", sessionHandler.DeleteUserSession)
+	})
+}
@@ -2,16 +2,29 @@ package routes
 
 import (
 	"go-user-api/internal/handler"
+	"go-user-api/internal/middleware"
+	"go-user-api/internal/rebac"
 
 	"github.com/go-chi/chi/v5"
 )
 
-func RegisterUserRoutes(r chi.Router, userHandler *handler.UserHandler) {
+// RegisterUserRoutes serves the user API. With authz set, changing or
+// deleting a user takes a token whose user holds edit or manage on the
+// user, e.g. as the user themselves or a manager of their team. Users are
+// only created anonymously in the default organization, like sign-ups.
+func RegisterUserRoutes(r chi.Router, userHandler *handler.UserHandler, jwtAuth *middleware.JWTAuth, authz *rebac.Engine) {
 	const userRouteWithId string = "/users/{id}"
 
-	r.Post("/users", userHandler.CreateUser)
+	r.With(middleware.RequireDefaultTenant).Post("/users", userHandler.CreateUser)
 	r.Get("/users", userHandler.GetAllUsers)
 	r.Get(userRouteWithId, userHandler.GetUser)
-	r.Put(userRouteWithId, userHandler.UpdateUser)
-	r.Delete(userRouteWithId, userHandler.DeleteUser)
+
+	if authz == nil {
+		r.Put(userRouteWithId, userHandler.UpdateUser)
+		r.Delete(userRouteWithId, userHandler.DeleteUser)
+		return
+	}
+
+	r.With(jwtAuth.Middleware, middleware.RequireRelation(authz, "user", "edit", "id")).Put(userRouteWithId, userHandler.UpdateUser)
+	r.With(jwtAuth.Middleware, middleware.RequireRelation(authz, "user", "manage", "id")).Delete(userRouteWithId, userHandler.DeleteUser)
 }
@@ -0,0 +1,451 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Filter is a parsed filter expression (RFC 7644 section 3.4.2.2). It is
+// evaluated against the JSON form of a resource.
+type Filter interface {
+	Matches(resource map[string]any) bool
+}
+
+type logicalFilter struct {
+	and         bool
+	left, right Filter
+}
+
+func (f logicalFilter) Matches(resource map[string]any) bool {
+	if f.and {
+		return f.left.Matches(resource) && f.right.Matches(resource)
+	}
+
+	return f.left.Matches(resource) || f.right.Matches(resource)
+}
+
+type notFilter struct {
+	inner Filter
+}
+
+func (f notFilter) Matches(resource map[string]any) bool {
+	return !f.inner.Matches(resource)
+}
+
+// valuePathFilter matches when an entry of a multi-valued attribute matches
+// the inner filter, as in emails[type eq "work"].
+type valuePathFilter struct {
+	attr  string
+	inner Filter
+}
+
+func (f valuePathFilter) Matches(resource map[string]any) bool {
+	for _, v := range lookup(resource, []string{f.attr}) {
+		if entry, ok := v.(map[string]any); ok && f.inner.Matches(entry) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type compareFilter struct {
+	path  []string
+	op    string
+	value any
+}
+
+func (f compareFilter) Matches(resource map[string]any) bool {
+	values := lookup(resource, f.path)
+
+	switch f.op {
+	case "pr":
+		for _, v := range values {
+			if v != nil && v != "" {
+				return true
+			}
+		}
+		return false
+	case "ne":
+		return !compareFilter{path: f.path, op: "eq", value: f.value}.Matches(resource)
+	}
+
+	if f.value == nil {
+		return f.op == "eq" && len(values) == 0
+	}
+
+	for _, v := range values {
+		if compare(v, f.op, f.value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compare applies op to a resource value and a filter operand. Strings are
+// compared case-insensitively; timestamps compare correctly as strings
+// because they share one format.
+func compare(v any, op string, operand any) bool {
+	switch operand := operand.(type) {
+	case string:
+		s, ok := v.(string)
+		if !ok {
+			return false
+		}
+
+		s, operand = strings.ToLower(s), strings.ToLower(operand)
+		switch op {
+		case "eq":
+			return s == operand
+		case "co":
+			return strings.Contains(s, operand)
+		case "sw":
+			return strings.HasPrefix(s, operand)
+		case "ew":
+			return strings.HasSuffix(s, operand)
+		case "gt":
+			return s > operand
+		case "ge":
+			return s >= operand
+		case "lt":
+			return s < operand
+		case "le":
+			return s <= operand
+		}
+	case float64:
+		n, ok := v.(float64)
+		if !ok {
+			return false
+		}
+
+		switch op {
+		case "eq":
+			return n == operand
+		case "gt":
+			return n > operand
+		case "ge":
+			return n >= operand
+		case "lt":
+			return n < operand
+		case "le":
+			return n <= operand
+		}
+	case bool:
+		b, ok := v.(bool)
+		return ok && op == "eq" && b == operand
+	}
+
+	return false
+}
+
+// lookup returns the values at path. Multi-valued attributes are flattened,
+// so emails.value yields every email address.
+func lookup(v any, path []string) []any {
+	if list, ok := v.([]any); ok {
+		var values []any
+		for _, entry := range list {
+			values = append(values, lookup(entry, path)...)
+		}
+		return values
+	}
+
+	if len(path) == 0 {
+		if v == nil {
+			return nil
+		}
+		return []any{v}
+	}
+
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	key, ok := findKey(m, path[0])
+	if !ok {
+		return nil
+	}
+
+	return lookup(m[key], path[1:])
+}
+
+// findKey finds an attribute by name; attribute names are case-insensitive.
+func findKey(m map[string]any, name string) (string, bool) {
+	if _, ok := m[name]; ok {
+		return name, true
+	}
+
+	for key := range m {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+
+	return name, false
+}
+
+// attrPath splits an attribute path into its names. A schema URN prefix, as
+// in urn:ietf:params:scim:schemas:core:2.0:User:userName, is dropped.
+func attrPath(s string) []string {
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		s = s[i+1:]
+	}
+
+	return strings.Split(s, ".")
+}
+
+var compareOps = map[string]bool{"eq": true, "ne": true, "co": true, "sw": true, "ew": true, "gt": true, "ge": true, "lt": true, "le": true}
+
+// ParseFilter parses a filter expression. An empty expression returns a nil
+// Filter, which callers treat as matching everything.
+func ParseFilter(expr string) (Filter, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos != len(p.tokens) {
+		return nil, invalidFilter("unexpected %q", p.tokens[p.pos].text)
+	}
+
+	return f, nil
+}
+
+// EqualityValue reports the operand when f is a plain attr eq "value"
+// comparison, so callers can use an indexed lookup instead of a scan.
+func EqualityValue(f Filter, attr string) (string, bool) {
+	c, ok := f.(compareFilter)
+	if !ok || c.op != "eq" || len(c.path) != 1 || !strings.EqualFold(c.path[0], attr) {
+		return "", false
+	}
+
+	value, ok := c.value.(string)
+	return value, ok
+}
+
+func invalidFilter(format string, args ...any) *Error {
+	return NewError(http.StatusBadRequest, "invalidFilter", "invalid filter: "+fmt.Sprintf(format, args...))
+}
+
+type token struct {
+	text   string
+	quoted bool
+}
+
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(expr); {
+		switch c := expr[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case strings.IndexByte("()[]", c) >= 0:
+			tokens = append(tokens, token{text: string(c)})
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(expr) && expr[end] != '"' {
+				if expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return nil, invalidFilter("unterminated string")
+			}
+
+			var s string
+			if err := json.Unmarshal([]byte(expr[i:end+1]), &s); err != nil {
+				return nil, invalidFilter("malformed string %q", expr[i:end+1])
+			}
+
+			tokens = append(tokens, token{text: s, quoted: true})
+			i = end + 1
+		default:
+			end := i
+			for end < len(expr) && strings.IndexByte(" \t()[]\"", expr[end]) < 0 {
+				end++
+			}
+			tokens = append(tokens, token{text: expr[i:end]})
+			i = end
+		}
+	}
+
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *filterParser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+
+	return p.tokens[p.pos], true
+}
+
+// keyword reports whether the next token is the unquoted keyword kw and
+// consumes it if so.
+func (p *filterParser) keyword(kw string) bool {
+	t, ok := p.peek()
+	if !ok || t.quoted || !strings.EqualFold(t.text, kw) {
+		return false
+	}
+
+	p.pos++
+	return true
+}
+
+func (p *filterParser) expect(kw string) error {
+	if !p.keyword(kw) {
+		return invalidFilter("expected %q", kw)
+	}
+
+	return nil
+}
+
+func (p *filterParser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalFilter{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (Filter, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("and") {
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalFilter{and: true, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseFactor() (Filter, error) {
+	if p.keyword("not") {
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+
+		inner, err := p.parseGroup(")")
+		if err != nil {
+			return nil, err
+		}
+		return notFilter{inner: inner}, nil
+	}
+
+	if p.keyword("(") {
+		return p.parseGroup(")")
+	}
+
+	t, ok := p.peek()
+	if !ok || t.quoted {
+		return nil, invalidFilter("expected an attribute")
+	}
+	p.pos++
+	path := attrPath(t.text)
+
+	if p.keyword("[") {
+		if len(path) != 1 {
+			return nil, invalidFilter("unexpected %q", t.text)
+		}
+
+		inner, err := p.parseGroup("]")
+		if err != nil {
+			return nil, err
+		}
+		return valuePathFilter{attr: path[0], inner: inner}, nil
+	}
+
+	if p.keyword("pr") {
+		return compareFilter{path: path, op: "pr"}, nil
+	}
+
+	opToken, ok := p.peek()
+	if !ok || opToken.quoted || !compareOps[strings.ToLower(opToken.text)] {
+		return nil, invalidFilter("expected an operator after %q", t.text)
+	}
+	p.pos++
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	return compareFilter{path: path, op: strings.ToLower(opToken.text), value: value}, nil
+}
+
+func (p *filterParser) parseGroup(closing string) (Filter, error) {
+	inner, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expect(closing); err != nil {
+		return nil, err
+	}
+
+	return inner, nil
+}
+
+func (p *filterParser) parseValue() (any, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, invalidFilter("expected a value")
+	}
+	p.pos++
+
+	if t.quoted {
+		return t.text, nil
+	}
+
+	switch strings.ToLower(t.text) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+
+	n, err := strconv.ParseFloat(t.text, 64)
+	if err != nil {
+		return nil, invalidFilter("unexpected value %q", t.text)
+	}
+
+	return n, nil
+}
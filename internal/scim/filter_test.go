@@ -0,0 +1,78 @@
+package scim_test
+
+import (
+	"go-user-api/internal/scim"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testUser = map[string]any{
+	"userName": "Jane@Example.com",
+	"name":     map[string]any{"formatted": "Jane Doe"},
+	"active":   true,
+	"emails": []any{
+		map[string]any{"value": "jane@example.com", "type": "work"},
+		map[string]any{"value": "jd@home.example", "type": "home"},
+	},
+	"meta": map[string]any{"lastModified": "2024-05-01T10:00:00Z"},
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		filter string
+		match  bool
+	}{
+		{`userName eq "jane@example.com"`, true},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:userName eq "jane@example.com"`, true},
+		{`userName ne "jane@example.com"`, false},
+		{`name.formatted co "doe"`, true},
+		{`emails.value ew ".example"`, true},
+		{`emails[type eq "home" and value sw "jd"]`, true},
+		{`emails[type eq "other"]`, false},
+		{`active eq false or name.formatted sw "Jane"`, true},
+		{`not (active eq true) and userName pr`, false},
+		{`meta.lastModified gt "2024-01-01T00:00:00Z"`, true},
+		{`title pr`, false},
+		{`title eq null`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := scim.ParseFilter(tt.filter)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.match, f.Matches(testUser))
+		})
+	}
+
+	for _, invalid := range []string{`userName`, `userName eq`, `userName xx "a"`, `(userName pr`, `userName eq "open`} {
+		_, err := scim.ParseFilter(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	resource := map[string]any{
+		"displayName": "Engineering",
+		"members":     []any{map[string]any{"value": "1"}, map[string]any{"value": "2"}},
+	}
+
+	err := scim.ApplyPatch(resource, []scim.PatchOperation{
+		{Op: "add", Path: "members", Value: []any{map[string]any{"value": "2"}, map[string]any{"value": "3"}}},
+		{Op: "remove", Path: `members[value eq "1"]`},
+		{Op: "Replace", Value: map[string]any{"displayName": "Platform"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Platform", resource["displayName"])
+	assert.Equal(t, []any{map[string]any{"value": "2"}, map[string]any{"value": "3"}}, resource["members"])
+
+	err = scim.ApplyPatch(resource, []scim.PatchOperation{{Op: "remove", Path: "members", Value: []any{map[string]any{"value": "3"}}}})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{map[string]any{"value": "2"}}, resource["members"])
+
+	err = scim.ApplyPatch(resource, []scim.PatchOperation{{Op: "replace", Path: `members[value eq "9"].display`, Value: "x"}})
+	assert.Equal(t, "noTarget", err.(*scim.Error).ScimType)
+
+	err = scim.ApplyPatch(resource, []scim.PatchOperation{{Op: "move", Path: "displayName"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,276 @@
+package scim
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+type PatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// patchPath is a parsed PATCH path: attr, attr.sub, attr[filter] or
+// attr[filter].sub.
+type patchPath struct {
+	attr   string
+	sub    string
+	filter Filter
+}
+
+func parsePatchPath(s string) (patchPath, error) {
+	var p patchPath
+
+	if open := strings.IndexByte(s, '['); open >= 0 {
+		close := strings.LastIndexByte(s, ']')
+		if close < open {
+			return p, NewError(http.StatusBadRequest, "invalidPath", "invalid path "+s)
+		}
+
+		filter, err := ParseFilter(s[open+1 : close])
+		if err != nil || filter == nil {
+			return p, NewError(http.StatusBadRequest, "invalidPath", "invalid filter in path "+s)
+		}
+
+		p.filter = filter
+		if rest := s[close+1:]; rest != "" {
+			if !strings.HasPrefix(rest, ".") {
+				return p, NewError(http.StatusBadRequest, "invalidPath", "invalid path "+s)
+			}
+			p.sub = rest[1:]
+		}
+		s = s[:open]
+	}
+
+	names := attrPath(s)
+	if len(names) > 2 || (len(names) == 2 && p.filter != nil) || names[0] == "" {
+		return p, NewError(http.StatusBadRequest, "invalidPath", "invalid path "+s)
+	}
+
+	p.attr = names[0]
+	if len(names) == 2 {
+		p.sub = names[1]
+	}
+
+	return p, nil
+}
+
+// ApplyPatch applies PATCH operations (RFC 7644 section 3.5.2) to the JSON
+// form of a resource. Callers decode the result back into the resource and
+// validate it as they would a replacement.
+func ApplyPatch(resource map[string]any, ops []PatchOperation) error {
+	for _, op := range ops {
+		name := strings.ToLower(op.Op)
+		if name != "add" && name != "replace" && name != "remove" {
+			return NewError(http.StatusBadRequest, "invalidSyntax", "unsupported operation "+op.Op)
+		}
+
+		if op.Path != "" {
+			path, err := parsePatchPath(op.Path)
+			if err != nil {
+				return err
+			}
+
+			if err := applyOperation(resource, name, path, op.Value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// without a path the value holds the attributes to add or replace
+		if name == "remove" {
+			return NewError(http.StatusBadRequest, "noTarget", "remove requires a path")
+		}
+
+		values, ok := op.Value.(map[string]any)
+		if !ok {
+			return NewError(http.StatusBadRequest, "invalidValue", "value must be an object when no path is given")
+		}
+
+		for attr, value := range values {
+			path, err := parsePatchPath(attr)
+			if err != nil {
+				return err
+			}
+
+			if err := applyOperation(resource, name, path, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func applyOperation(resource map[string]any, op string, path patchPath, value any) error {
+	key, _ := findKey(resource, path.attr)
+
+	if path.filter != nil {
+		return applyFiltered(resource, key, op, path, value)
+	}
+
+	if path.sub != "" {
+		complex, ok := resource[key].(map[string]any)
+		if !ok {
+			if _, isList := resource[key].([]any); isList {
+				return NewError(http.StatusBadRequest, "invalidPath", "use a filter to address entries of "+path.attr)
+			}
+			if op == "remove" {
+				return nil
+			}
+			complex = map[string]any{}
+			resource[key] = complex
+		}
+
+		subKey, _ := findKey(complex, path.sub)
+		if op == "remove" {
+			delete(complex, subKey)
+		} else {
+			complex[subKey] = value
+		}
+		return nil
+	}
+
+	switch op {
+	case "remove":
+		// a value names the entries to remove from a multi-valued attribute
+		if list, ok := resource[key].([]any); ok && value != nil {
+			resource[key] = removeValues(list, value)
+			return nil
+		}
+		delete(resource, key)
+	case "add":
+		resource[key] = merge(resource[key], value)
+	case "replace":
+		if _, ok := resource[key].(map[string]any); ok {
+			resource[key] = merge(resource[key], value)
+		} else {
+			resource[key] = value
+		}
+	}
+
+	return nil
+}
+
+// merge adds value to an existing attribute: new entries are appended to a
+// multi-valued attribute, skipping ones already present, and complex values
+// have their sub-attributes set.
+func merge(existing any, value any) any {
+	switch existing := existing.(type) {
+	case []any:
+		values, ok := value.([]any)
+		if !ok {
+			values = []any{value}
+		}
+
+		for _, v := range values {
+			if !containsValue(existing, v) {
+				existing = append(existing, v)
+			}
+		}
+		return existing
+	case map[string]any:
+		values, ok := value.(map[string]any)
+		if !ok {
+			return value
+		}
+
+		for k, v := range values {
+			key, _ := findKey(existing, k)
+			existing[key] = v
+		}
+		return existing
+	}
+
+	return value
+}
+
+// containsValue reports whether list already holds v, comparing entries by
+// their value sub-attribute.
+func containsValue(list []any, v any) bool {
+	want := lookup(v, []string{"value"})
+	if len(want) == 0 {
+		return false
+	}
+
+	for _, entry := range list {
+		if reflect.DeepEqual(lookup(entry, []string{"value"}), want) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func removeValues(list []any, value any) []any {
+	values, ok := value.([]any)
+	if !ok {
+		values = []any{value}
+	}
+
+	kept := []any{}
+	for _, entry := range list {
+		if !containsValue(values, entry) {
+			kept = append(kept, entry)
+		}
+	}
+
+	return kept
+}
+
+// applyFiltered applies an operation to the entries of a multi-valued
+// attribute selected by the path filter.
+func applyFiltered(resource map[string]any, key string, op string, path patchPath, value any) error {
+	list, _ := resource[key].([]any)
+
+	var kept []any
+	matched := false
+
+	for _, v := range list {
+		entry, ok := v.(map[string]any)
+		if !ok || !path.filter.Matches(entry) {
+			kept = append(kept, v)
+			continue
+		}
+
+		matched = true
+		switch {
+		case op == "remove" && path.sub == "":
+			continue
+		case op == "remove":
+			subKey, _ := findKey(entry, path.sub)
+			delete(entry, subKey)
+		case path.sub == "":
+			if replacement, ok := value.(map[string]any); ok {
+				entry = replacement
+			}
+		default:
+			subKey, _ := findKey(entry, path.sub)
+			entry[subKey] = value
+		}
+
+		kept = append(kept, entry)
+	}
+
+	if !matched {
+		// removing something that is not there is not an error
+		if op == "remove" {
+			return nil
+		}
+		return NewError(http.StatusBadRequest, "noTarget", "no values match the path filter")
+	}
+
+	resource[key] = kept
+	if kept == nil {
+		delete(resource, key)
+	}
+
+	return nil
+}
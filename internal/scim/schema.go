@@ -0,0 +1,160 @@
+package scim
+
+type Supported struct {
+	Supported bool `json:"supported"`
+}
+
+type FilterSupport struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type BulkSupport struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type AuthenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary"`
+}
+
+type ServiceProviderConfig struct {
+	Schemas               []string               `json:"schemas"`
+	Patch                 Supported              `json:"patch"`
+	Bulk                  BulkSupport            `json:"bulk"`
+	Filter                FilterSupport          `json:"filter"`
+	ChangePassword        Supported              `json:"changePassword"`
+	Sort                  Supported              `json:"sort"`
+	ETag                  Supported              `json:"etag"`
+	AuthenticationSchemes []AuthenticationScheme `json:"authenticationSchemes"`
+	Meta                  Meta                   `json:"meta"`
+}
+
+// MaxResults caps the page size of list responses.
+const MaxResults = 200
+
+func NewServiceProviderConfig(baseURL string) ServiceProviderConfig {
+	return ServiceProviderConfig{
+		Schemas:        []string{ServiceProviderConfigSchema},
+		Patch:          Supported{Supported: true},
+		Filter:         FilterSupport{Supported: true, MaxResults: MaxResults},
+		ChangePassword: Supported{Supported: false},
+		AuthenticationSchemes: []AuthenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "Bearer token",
+			Description: "The provisioning token configured on the server",
+			Primary:     true,
+		}},
+		Meta: Meta{ResourceType: "ServiceProviderConfig", Location: baseURL + "/ServiceProviderConfig"},
+	}
+}
+
+type ResourceType struct {
+	Schemas  []string `json:"schemas"`
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Endpoint string   `json:"endpoint"`
+	Schema   string   `json:"schema"`
+	Meta     Meta     `json:"meta"`
+}
+
+func ResourceTypes(baseURL string) []ResourceType {
+	return []ResourceType{
+		{
+			Schemas:  []string{ResourceTypeSchema},
+			ID:       "User",
+			Name:     "User",
+			Endpoint: "/Users",
+			Schema:   UserSchema,
+			Meta:     Meta{ResourceType: "ResourceType", Location: baseURL + "/ResourceTypes/User"},
+		},
+		{
+			Schemas:  []string{ResourceTypeSchema},
+			ID:       "Group",
+			Name:     "Group",
+			Endpoint: "/Groups",
+			Schema:   GroupSchema,
+			Meta:     Meta{ResourceType: "ResourceType", Location: baseURL + "/ResourceTypes/Group"},
+		},
+	}
+}
+
+type Attribute struct {
+	Name          string      `json:"name"`
+	Type          string      `json:"type"`
+	MultiValued   bool        `json:"multiValued"`
+	Required      bool        `json:"required"`
+	CaseExact     bool        `json:"caseExact"`
+	Mutability    string      `json:"mutability"`
+	Returned      string      `json:"returned"`
+	Uniqueness    string      `json:"uniqueness"`
+	SubAttributes []Attribute `json:"subAttributes,omitempty"`
+}
+
+type Schema struct {
+	Schemas    []string    `json:"schemas"`
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	Attributes []Attribute `json:"attributes"`
+	Meta       Meta        `json:"meta"`
+}
+
+// attribute returns a single-valued, optional, read-write string attribute;
+// callers adjust the rest.
+func attribute(name string) Attribute {
+	return Attribute{Name: name, Type: "string", Mutability: "readWrite", Returned: "default", Uniqueness: "none"}
+}
+
+// Schemas describes the attributes this server supports, which are a subset
+// of the core schemas.
+func Schemas(baseURL string) []Schema {
+	userName := attribute("userName")
+	userName.Required, userName.Uniqueness = true, "server"
+
+	name := attribute("name")
+	name.Type, name.SubAttributes = "complex", []Attribute{attribute("formatted"), attribute("givenName"), attribute("familyName")}
+
+	emailValue := attribute("value")
+	emailValue.Mutability = "readOnly"
+	emails := attribute("emails")
+	emails.Type, emails.MultiValued, emails.Mutability = "complex", true, "readOnly"
+	emails.SubAttributes = []Attribute{emailValue, attribute("type"), {Name: "primary", Type: "boolean", Mutability: "readOnly", Returned: "default", Uniqueness: "none"}}
+
+	active := attribute("active")
+	active.Type = "boolean"
+
+	password := attribute("password")
+	password.Mutability, password.Returned = "writeOnly", "never"
+
+	displayName := attribute("displayName")
+	groupName := attribute("displayName")
+	groupName.Required, groupName.Uniqueness = true, "server"
+
+	memberValue := attribute("value")
+	memberValue.Mutability = "immutable"
+	memberRef := attribute("$ref")
+	memberRef.Type, memberRef.Mutability = "reference", "immutable"
+	members := attribute("members")
+	members.Type, members.MultiValued, members.SubAttributes = "complex", true, []Attribute{memberValue, memberRef}
+
+	return []Schema{
+		{
+			Schemas:    []string{SchemaSchema},
+			ID:         UserSchema,
+			Name:       "User",
+			Attributes: []Attribute{userName, name, displayName, emails, active, password, attribute("externalId")},
+			Meta:       Meta{ResourceType: "Schema", Location: baseURL + "/Schemas/" + UserSchema},
+		},
+		{
+			Schemas:    []string{SchemaSchema},
+			ID:         GroupSchema,
+			Name:       "Group",
+			Attributes: []Attribute{groupName, members, attribute("externalId")},
+			Meta:       Meta{ResourceType: "Schema", Location: baseURL + "/Schemas/" + GroupSchema},
+		},
+	}
+}
@@ -0,0 +1,63 @@
+// Package tenant carries the organization a request is scoped to, and the
+// user making it. Every user query is filtered by the organization, so one
+// organization never sees the users of another.
+package tenant
+
+import (
+	"context"
+	"errors"
+)
+
+// DefaultID is the organization of requests that name none. Its admins
+// administer the whole deployment.
+const DefaultID = 1
+
+// ErrMismatch is returned when a request is bound to two organizations, e.g.
+// a token of one organization sent to the subdomain of another.
+var ErrMismatch = errors.New("request belongs to another organization")
+
+type contextKey struct{}
+
+type userKey struct{}
+
+// WithID scopes ctx to the organization.
+func WithID(ctx context.Context, id int) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the organization ctx was scoped to, if any.
+func FromContext(ctx context.Context) (int, bool) {
+	id, ok := ctx.Value(contextKey{}).(int)
+	return id, ok
+}
+
+// ID returns the organization ctx is scoped to, or the default one.
+func ID(ctx context.Context) int {
+	if id, ok := FromContext(ctx); ok {
+		return id
+	}
+
+	return DefaultID
+}
+
+// Bind scopes ctx to the organization of a credential. It fails if the
+// request was already scoped to a different one.
+func Bind(ctx context.Context, id int) (context.Context, error) {
+	if current, ok := FromContext(ctx); ok && current != id {
+		return ctx, ErrMismatch
+	}
+
+	return WithID(ctx, id), nil
+}
+
+// WithUser records the authenticated user of the request, for the database
+// session settings the repositories derive from ctx.
+func WithUser(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, userKey{}, userID)
+}
+
+// UserID returns the authenticated user of ctx, or 0.
+func UserID(ctx context.Context) int {
+	id, _ := ctx.Value(userKey{}).(int)
+	return id
+}
@@ -0,0 +1,53 @@
+package testutils
+
+import (
+	"context"
+	"go-user-api/internal/audit"
+	"go-user-api/internal/model"
+	"time"
+)
+
+type MockAuditRepo struct {
+	Events []*model.AuditEvent
+}
+
+func (m *MockAuditRepo) Record(_ context.Context, e *model.AuditEvent) error {
+	if n := len(m.Events); n > 0 {
+		e.PrevHash = m.Events[n-1].Hash
+	}
+
+	e.ID = int64(len(m.Events) + 1)
+	e.OccurredAt = time.Now().UTC().Truncate(time.Microsecond)
+	e.Hash = audit.Hash(e.PrevHash, e)
+	m.Events = append(m.Events, e)
+	return nil
+}
+
+func (m *MockAuditRepo) List(_ context.Context, filter model.AuditFilter) ([]*model.AuditEvent, error) {
+	events := []*model.AuditEvent{}
+	for i := len(m.Events) - 1; i >= 0 && len(events) < filter.Limit; i-- {
+		e := m.Events[i]
+		if filter.ActorID != 0 && e.ActorID != filter.ActorID && e.ImpersonatorID != filter.ActorID ||
+			filter.Action != "" && e.Action != filter.Action ||
+			filter.TargetType != "" && e.TargetType != filter.TargetType ||
+			filter.TargetID != "" && e.TargetID != filter.TargetID ||
+			!filter.Since.IsZero() && e.OccurredAt.Before(filter.Since) ||
+			!filter.Until.IsZero() && !e.OccurredAt.Before(filter.Until) ||
+			filter.BeforeID != 0 && e.ID >= filter.BeforeID {
+			continue
+		}
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+func (m *MockAuditRepo) Walk(_ context.Context, fn func(*model.AuditEvent) error) error {
+	for _, e := range m.Events {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
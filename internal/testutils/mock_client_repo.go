@@ -0,0 +1,69 @@
+package testutils
+
+import (
+	"context"
+	"fmt"
+	"go-user-api/internal/model"
+)
+
+// MockClientRepo keeps clients in memory, keyed by their numeric ID.
+type MockClientRepo struct {
+	Clients map[int]*model.Client
+}
+
+func NewMockClientRepo(clients ...*model.Client) *MockClientRepo {
+	m := &MockClientRepo{Clients: map[int]*model.Client{}}
+	for _, c := range clients {
+		m.Clients[c.ID] = c
+	}
+	return m
+}
+
+func (m *MockClientRepo) Create(_ context.Context, c *model.Client) error {
+	c.ID = len(m.Clients) + 1
+	m.Clients[c.ID] = c
+	return nil
+}
+
+func (m *MockClientRepo) Get(_ context.Context, id int) (*model.Client, error) {
+	if c, ok := m.Clients[id]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("no clients found with id: %d", id)
+}
+
+func (m *MockClientRepo) GetByClientID(_ context.Context, clientID string) (*model.Client, error) {
+	for _, c := range m.Clients {
+		if c.ClientID == clientID {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("no clients found with client_id: %s", clientID)
+}
+
+func (m *MockClientRepo) GetAllClients(_ context.Context) ([]*model.Client, error) {
+	var clients []*model.Client
+	for _, c := range m.Clients {
+		clients = append(clients, c)
+	}
+	return clients, nil
+}
+
+func (m *MockClientRepo) Update(_ context.Context, c *model.Client) error {
+	m.Clients[c.ID] = c
+	return nil
+}
+
+func (m *MockClientRepo) UpdateSecret(ctx context.Context, id int, secretHash string) error {
+	c, err := m.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	c.SecretHash = secretHash
+	return nil
+}
+
+func (m *MockClientRepo) Delete(_ context.Context, id int) error {
+	delete(m.Clients, id)
+	return nil
+}
@@ -0,0 +1,107 @@
+package testutils
+
+import (
+	"context"
+	"errors"
+	"go-user-api/internal/model"
+	"time"
+)
+
+// MockGrantRepo keeps authorization codes and refresh tokens in memory.
+type MockGrantRepo struct {
+	Codes         map[string]*model.AuthorizationCode
+	RefreshTokens map[string]*model.RefreshToken
+	RevokedJTIs   map[string]time.Time
+}
+
+func NewMockGrantRepo() *MockGrantRepo {
+	return &MockGrantRepo{
+		Codes:         map[string]*model.AuthorizationCode{},
+		RefreshTokens: map[string]*model.RefreshToken{},
+		RevokedJTIs:   map[string]time.Time{},
+	}
+}
+
+func (m *MockGrantRepo) CreateAuthorizationCode(_ context.Context, c *model.AuthorizationCode) error {
+	m.Codes[c.CodeHash] = c
+	return nil
+}
+
+func (m *MockGrantRepo) GetAuthorizationCode(_ context.Context, codeHash string) (*model.AuthorizationCode, error) {
+	c, ok := m.Codes[codeHash]
+	if !ok || c.UsedAt != nil || time.Now().After(c.ExpiresAt) {
+		return nil, errors.New("authorization code not found")
+	}
+	return c, nil
+}
+
+func (m *MockGrantRepo) ConsumeAuthorizationCode(_ context.Context, codeHash string) (*model.AuthorizationCode, error) {
+	c, ok := m.Codes[codeHash]
+	if !ok || c.UsedAt != nil || time.Now().After(c.ExpiresAt) {
+		return nil, errors.New("authorization code not found")
+	}
+	now := time.Now()
+	c.UsedAt = &now
+	return c, nil
+}
+
+func (m *MockGrantRepo) CreateRefreshToken(_ context.Context, t *model.RefreshToken) error {
+	t.ID = len(m.RefreshTokens) + 1
+	t.CreatedAt = time.Now()
+	m.RefreshTokens[t.TokenHash] = t
+	return nil
+}
+
+func (m *MockGrantRepo) GetRefreshToken(_ context.Context, tokenHash string) (*model.RefreshToken, error) {
+	t, ok := m.RefreshTokens[tokenHash]
+	if !ok {
+		return nil, errors.New("refresh token not found")
+	}
+	return t, nil
+}
+
+func (m *MockGrantRepo) ConsumeRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	t, err := m.GetRefreshToken(ctx, tokenHash)
+	if err != nil || t.RevokedAt != nil || time.Now().After(t.ExpiresAt) {
+		return nil, errors.New("refresh token not found")
+	}
+	now := time.Now()
+	t.RevokedAt = &now
+	return t, nil
+}
+
+func (m *MockGrantRepo) RevokeRefreshTokens(_ context.Context, clientID string, userID int) error {
+	now := time.Now()
+	for _, t := range m.RefreshTokens {
+		if t.ClientID == clientID && t.UserID == userID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *MockGrantRepo) RevokeRefreshToken(_ context.Context, tokenHash string) error {
+	if t, ok := m.RefreshTokens[tokenHash]; ok && t.RevokedAt == nil {
+		now := time.Now()
+		t.RevokedAt = &now
+	}
+	return nil
+}
+
+func (m *MockGrantRepo) RevokeAccessToken(_ context.Context, jti string, expiresAt time.Time) error {
+	m.RevokedJTIs[jti] = expiresAt
+	return nil
+}
+
+func (m *MockGrantRepo) IsAccessTokenRevoked(_ context.Context, jti string) (bool, error) {
+	_, ok := m.RevokedJTIs[jti]
+	return ok, nil
+}
+
+func (m *MockGrantRepo) ConsumeTokenID(_ context.Context, jti string, expiresAt time.Time) (bool, error) {
+	if _, ok := m.RevokedJTIs[jti]; ok {
+		return false, nil
+	}
+	m.RevokedJTIs[jti] = expiresAt
+	return true, nil
+}
@@ -0,0 +1,187 @@
+package testutils
+
+import (
+	"context"
+	"fmt"
+	"go-user-api/internal/model"
+	"go-user-api/internal/repository"
+	"go-user-api/internal/tenant"
+	"slices"
+	"time"
+)
+
+// MockGroupRepo keeps groups in memory, in creation order. Subgroups are
+// expanded and groups scoped to the organization of the context like the
+// real repository does.
+type MockGroupRepo struct {
+	Groups []*model.Group
+	nextID int
+}
+
+// groupInTenant reports whether g belongs to the organization of ctx. Groups
+// without one belong to the default organization.
+func groupInTenant(ctx context.Context, g *model.Group) bool {
+	id := g.OrganizationID
+	if id == 0 {
+		id = tenant.DefaultID
+	}
+	return id == tenant.ID(ctx)
+}
+
+func (m *MockGroupRepo) Create(ctx context.Context, g *model.Group) error {
+	m.nextID++
+	g.ID = m.nextID
+	g.OrganizationID = tenant.ID(ctx)
+	g.CreatedAt, g.UpdatedAt = time.Now(), time.Now()
+	m.Groups = append(m.Groups, g)
+	return nil
+}
+
+func (m *MockGroupRepo) Get(ctx context.Context, id int) (*model.Group, error) {
+	for _, g := range m.Groups {
+		if g.ID == id && groupInTenant(ctx, g) {
+			return g, nil
+		}
+	}
+	return nil, fmt.Errorf("no groups found with id: %d", id)
+}
+
+func (m *MockGroupRepo) GetAllGroups(ctx context.Context) ([]*model.Group, error) {
+	var groups []*model.Group
+	for _, g := range m.Groups {
+		if groupInTenant(ctx, g) {
+			groups = append(groups, g)
+		}
+	}
+	return groups, nil
+}
+
+func (m *MockGroupRepo) Update(ctx context.Context, g *model.Group) error {
+	for i, existing := range m.Groups {
+		if existing.ID == g.ID && groupInTenant(ctx, existing) {
+			g.OrganizationID = existing.OrganizationID
+			g.UpdatedAt = time.Now()
+			m.Groups[i] = g
+			return nil
+		}
+	}
+	return fmt.Errorf("no groups found with id: %d", g.ID)
+}
+
+func (m *MockGroupRepo) Delete(ctx context.Context, id int) error {
+	for i, g := range m.Groups {
+		if g.ID == id && groupInTenant(ctx, g) {
+			m.Groups = append(m.Groups[:i], m.Groups[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no groups found with id: %d", id)
+}
+
+func (m *MockGroupRepo) GetGroupsForUser(ctx context.Context, userID int) ([]*model.Group, error) {
+	var groups []*model.Group
+	for _, g := range m.Groups {
+		if slices.Contains(g.Members, userID) && groupInTenant(ctx, g) {
+			groups = append(groups, g)
+		}
+	}
+	return groups, nil
+}
+
+func (m *MockGroupRepo) GetEffectiveGroups(ctx context.Context, userID int) ([]*model.Group, error) {
+	direct, _ := m.GetGroupsForUser(ctx, userID)
+	seen := map[int]bool{}
+	queue := direct
+	for len(queue) > 0 {
+		g := queue[0]
+		queue = queue[1:]
+		if seen[g.ID] {
+			continue
+		}
+		seen[g.ID] = true
+		for _, parent := range m.Groups {
+			if slices.Contains(parent.Subgroups, g.ID) && groupInTenant(ctx, parent) {
+				queue = append(queue, parent)
+			}
+		}
+	}
+
+	var groups []*model.Group
+	for _, g := range m.Groups {
+		if seen[g.ID] {
+			groups = append(groups, g)
+		}
+	}
+	return groups, nil
+}
+
+func (m *MockGroupRepo) AddMember(ctx context.Context, groupID int, userID int) error {
+	g, err := m.Get(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(g.Members, userID) {
+		g.Members = append(g.Members, userID)
+	}
+	return nil
+}
+
+func (m *MockGroupRepo) RemoveMember(ctx context.Context, groupID int, userID int) error {
+	g, err := m.Get(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	i := slices.Index(g.Members, userID)
+	if i < 0 {
+		return fmt.Errorf("user %d is not a member of group %d", userID, groupID)
+	}
+	g.Members = slices.Delete(g.Members, i, i+1)
+	return nil
+}
+
+func (m *MockGroupRepo) AddSubgroup(ctx context.Context, parentID int, childID int) error {
+	parent, err := m.Get(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	if _, err := m.Get(ctx, childID); err != nil {
+		return err
+	}
+	if m.contains(ctx, childID, parentID) {
+		return repository.ErrGroupCycle
+	}
+	if !slices.Contains(parent.Subgroups, childID) {
+		parent.Subgroups = append(parent.Subgroups, childID)
+	}
+	return nil
+}
+
+// contains reports whether id is groupID or one of its subgroups.
+func (m *MockGroupRepo) contains(ctx context.Context, groupID int, id int) bool {
+	if groupID == id {
+		return true
+	}
+	g, err := m.Get(ctx, groupID)
+	if err != nil {
+		return false
+	}
+	for _, child := range g.Subgroups {
+		if m.contains(ctx, child, id) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MockGroupRepo) RemoveSubgroup(ctx context.Context, parentID int, childID int) error {
+	parent, err := m.Get(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	i := slices.Index(parent.Subgroups, childID)
+	if i < 0 {
+		return fmt.Errorf("group %d is not a subgroup of group %d", childID, parentID)
+	}
+	parent.Subgroups = slices.Delete(parent.Subgroups, i, i+1)
+	return nil
+}
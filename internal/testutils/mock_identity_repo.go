@@ -0,0 +1,50 @@
+package testutils
+
+import (
+	"context"
+	"fmt"
+	"go-user-api/internal/model"
+	"go-user-api/internal/tenant"
+)
+
+// MockIdentityRepo keeps linked identities in memory, scoped to the
+// organization of the context like the real repository.
+type MockIdentityRepo struct {
+	Identities []*model.Identity
+}
+
+func (m *MockIdentityRepo) Create(ctx context.Context, i *model.Identity) error {
+	i.ID = len(m.Identities) + 1
+	i.OrganizationID = tenant.ID(ctx)
+	m.Identities = append(m.Identities, i)
+	return nil
+}
+
+func (m *MockIdentityRepo) GetByProviderSubject(ctx context.Context, provider string, subject string) (*model.Identity, error) {
+	for _, i := range m.Identities {
+		if i.Provider == provider && i.Subject == subject && i.OrganizationID == tenant.ID(ctx) {
+			return i, nil
+		}
+	}
+	return nil, fmt.Errorf("no identity for %s/%s", provider, subject)
+}
+
+func (m *MockIdentityRepo) GetAllByUser(ctx context.Context, userID int) ([]*model.Identity, error) {
+	var identities []*model.Identity
+	for _, i := range m.Identities {
+		if i.UserID == userID && i.OrganizationID == tenant.ID(ctx) {
+			identities = append(identities, i)
+		}
+	}
+	return identities, nil
+}
+
+func (m *MockIdentityRepo) Delete(ctx context.Context, id int, userID int) error {
+	for n, i := range m.Identities {
+		if i.ID == id && i.UserID == userID && i.OrganizationID == tenant.ID(ctx) {
+			m.Identities = append(m.Identities[:n], m.Identities[n+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no identities found with id: %d", id)
+}
@@ -0,0 +1,93 @@
+package testutils
+
+import (
+	"context"
+	"fmt"
+	"go-user-api/internal/model"
+	"go-user-api/internal/tenant"
+	"strings"
+	"time"
+)
+
+// MockInvitationRepo creates the users of accepted invitations in Users.
+type MockInvitationRepo struct {
+	Invitations []*model.Invitation
+	Users       *MockUserRepo
+}
+
+func (m *MockInvitationRepo) Create(_ context.Context, i *model.Invitation) error {
+	i.ID = len(m.Invitations) + 1
+	i.CreatedAt = time.Now()
+	m.Invitations = append(m.Invitations, i)
+	return nil
+}
+
+// Get only finds invitations of the organization of ctx, like the
+// row-level security policy.
+func (m *MockInvitationRepo) Get(ctx context.Context, id int) (*model.Invitation, error) {
+	for _, i := range m.Invitations {
+		if i.ID == id && i.OrganizationID == tenant.ID(ctx) {
+			return i, nil
+		}
+	}
+	return nil, fmt.Errorf("no invitations found with id: %d", id)
+}
+
+func (m *MockInvitationRepo) GetOpen(_ context.Context, organizationID int, email string) (*model.Invitation, error) {
+	for _, i := range m.Invitations {
+		if i.OrganizationID == organizationID && strings.EqualFold(i.Email, email) && i.AcceptedAt == nil && i.RevokedAt == nil {
+			return i, nil
+		}
+	}
+	return nil, fmt.Errorf("no open invitation for: %s", email)
+}
+
+func (m *MockInvitationRepo) GetByOrganization(_ context.Context, organizationID int) ([]*model.Invitation, error) {
+	invitations := []*model.Invitation{}
+	for _, i := range m.Invitations {
+		if i.OrganizationID == organizationID {
+			invitations = append(invitations, i)
+		}
+	}
+	return invitations, nil
+}
+
+func (m *MockInvitationRepo) SetToken(ctx context.Context, id int, tokenID string, expiresAt time.Time) error {
+	i, err := m.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	i.TokenID = tokenID
+	i.ExpiresAt = expiresAt
+	return nil
+}
+
+// Accept fails like the unique index on users when the email is taken, and
+// then leaves the invitation open.
+func (m *MockInvitationRepo) Accept(ctx context.Context, id int, user *model.User) (bool, error) {
+	i, err := m.Get(ctx, id)
+	if err != nil || i.StatusAt(time.Now()) != model.InvitationPending {
+		return false, nil
+	}
+	if existing, err := m.Users.GetByEmail(ctx, user.Email); err == nil && existing != nil {
+		return false, fmt.Errorf("email already taken: %s", user.Email)
+	}
+
+	user.Role = i.Role
+	if err := m.Users.Create(ctx, user); err != nil {
+		return false, err
+	}
+	now := time.Now()
+	i.AcceptedAt = &now
+	return true, nil
+}
+
+func (m *MockInvitationRepo) Revoke(ctx context.Context, id int) (bool, error) {
+	i, err := m.Get(ctx, id)
+	if err != nil || i.AcceptedAt != nil || i.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	i.RevokedAt = &now
+	return true, nil
+}
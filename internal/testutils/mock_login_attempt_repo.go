@@ -0,0 +1,47 @@
+package testutils
+
+import (
+	"context"
+	"go-user-api/internal/model"
+	"go-user-api/internal/tenant"
+	"time"
+)
+
+// MockLoginAttemptRepo is scoped to the organization of the context like the
+// real repository.
+type MockLoginAttemptRepo struct {
+	Attempts []*model.LoginAttempt
+}
+
+func (m *MockLoginAttemptRepo) Create(ctx context.Context, a *model.LoginAttempt) error {
+	a.ID = int64(len(m.Attempts) + 1)
+	a.OrganizationID = tenant.ID(ctx)
+	a.CreatedAt = time.Now()
+	m.Attempts = append(m.Attempts, a)
+	return nil
+}
+
+func (m *MockLoginAttemptRepo) GetByUser(ctx context.Context, userID int, limit int) ([]*model.LoginAttempt, error) {
+	attempts := []*model.LoginAttempt{}
+	for i := len(m.Attempts) - 1; i >= 0 && len(attempts) < limit; i-- {
+		if m.Attempts[i].UserID == userID && m.Attempts[i].OrganizationID == tenant.ID(ctx) {
+			attempts = append(attempts, m.Attempts[i])
+		}
+	}
+
+	return attempts, nil
+}
+
+func (m *MockLoginAttemptRepo) CountSuccesses(ctx context.Context, userID int, fingerprint string) (int, int, error) {
+	var fromDevice, total int
+	for _, a := range m.Attempts {
+		if a.UserID == userID && a.OrganizationID == tenant.ID(ctx) && a.Outcome == model.LoginSuccess {
+			total++
+			if a.Fingerprint == fingerprint {
+				fromDevice++
+			}
+		}
+	}
+
+	return fromDevice, total, nil
+}
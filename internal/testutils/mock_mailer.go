@@ -0,0 +1,39 @@
+package testutils
+
+import (
+	"context"
+	"go-user-api/internal/mail"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MockMailer records messages instead of sending them.
+type MockMailer struct {
+	mu       sync.Mutex
+	Messages []mail.Message
+}
+
+func (m *MockMailer) Send(_ context.Context, msg mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Messages = append(m.Messages, msg)
+	return nil
+}
+
+// Wait returns the messages once at least n were sent, or after a second,
+// for handlers that send in the background.
+func (m *MockMailer) Wait(n int) []mail.Message {
+	deadline := time.Now().Add(time.Second)
+	for {
+		m.mu.Lock()
+		messages := slices.Clone(m.Messages)
+		m.mu.Unlock()
+
+		if len(messages) >= n || time.Now().After(deadline) {
+			return messages
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,65 @@
+package testutils
+
+import (
+	"context"
+	"fmt"
+	"go-user-api/internal/model"
+	"go-user-api/internal/tenant"
+)
+
+// MockOrganizationRepo starts out with the default organization.
+type MockOrganizationRepo struct {
+	Organizations []*model.Organization
+}
+
+func NewMockOrganizationRepo(organizations ...*model.Organization) *MockOrganizationRepo {
+	defaultOrg := &model.Organization{ID: tenant.DefaultID, Slug: "default", Name: "Default"}
+	return &MockOrganizationRepo{Organizations: append([]*model.Organization{defaultOrg}, organizations...)}
+}
+
+func (m *MockOrganizationRepo) Create(_ context.Context, o *model.Organization) error {
+	o.ID = m.Organizations[len(m.Organizations)-1].ID + 1
+	m.Organizations = append(m.Organizations, o)
+	return nil
+}
+
+func (m *MockOrganizationRepo) Get(_ context.Context, id int) (*model.Organization, error) {
+	for _, o := range m.Organizations {
+		if o.ID == id {
+			return o, nil
+		}
+	}
+	return nil, fmt.Errorf("no organizations found with id: %d", id)
+}
+
+func (m *MockOrganizationRepo) GetBySlug(_ context.Context, slug string) (*model.Organization, error) {
+	for _, o := range m.Organizations {
+		if o.Slug == slug {
+			return o, nil
+		}
+	}
+	return nil, fmt.Errorf("no organizations found with slug: %s", slug)
+}
+
+func (m *MockOrganizationRepo) GetAllOrganizations(_ context.Context) ([]*model.Organization, error) {
+	return m.Organizations, nil
+}
+
+func (m *MockOrganizationRepo) Update(ctx context.Context, o *model.Organization) error {
+	existing, err := m.Get(ctx, o.ID)
+	if err != nil {
+		return err
+	}
+	existing.Name = o.Name
+	return nil
+}
+
+func (m *MockOrganizationRepo) Delete(_ context.Context, id int) error {
+	for i, o := range m.Organizations {
+		if o.ID == id {
+			m.Organizations = append(m.Organizations[:i], m.Organizations[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no organizations found with id: %d", id)
+}
@@ -0,0 +1,62 @@
+package testutils
+
+import (
+	"context"
+	"fmt"
+	"go-user-api/internal/model"
+	"go-user-api/internal/tenant"
+	"time"
+)
+
+// MockSessionRepo keeps sessions in memory, in creation order, scoped to
+// the organization of the context like the real repository.
+type MockSessionRepo struct {
+	Sessions []*model.Session
+}
+
+func (m *MockSessionRepo) Create(ctx context.Context, s *model.Session) error {
+	s.ID = len(m.Sessions) + 1
+	s.OrganizationID = tenant.ID(ctx)
+	s.CreatedAt, s.LastSeenAt = time.Now(), time.Now()
+	m.Sessions = append(m.Sessions, s)
+	return nil
+}
+
+func (m *MockSessionRepo) Get(ctx context.Context, id int) (*model.Session, error) {
+	for _, s := range m.Sessions {
+		if s.ID == id && s.OrganizationID == tenant.ID(ctx) {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("no sessions found with id: %d", id)
+}
+
+func (m *MockSessionRepo) GetActiveByUser(ctx context.Context, userID int) ([]*model.Session, error) {
+	var sessions []*model.Session
+	for _, s := range m.Sessions {
+		if s.UserID == userID && s.OrganizationID == tenant.ID(ctx) && s.Active() {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions, nil
+}
+
+func (m *MockSessionRepo) Touch(ctx context.Context, id int) error {
+	for _, s := range m.Sessions {
+		if s.ID == id && s.OrganizationID == tenant.ID(ctx) {
+			s.LastSeenAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (m *MockSessionRepo) Revoke(ctx context.Context, id int, userID int) error {
+	for _, s := range m.Sessions {
+		if s.ID == id && s.UserID == userID && s.OrganizationID == tenant.ID(ctx) && s.RevokedAt == nil {
+			now := time.Now()
+			s.RevokedAt = &now
+			return nil
+		}
+	}
+	return fmt.Errorf("no active sessions found with id: %d", id)
+}
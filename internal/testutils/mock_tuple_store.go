@@ -0,0 +1,48 @@
+package testutils
+
+import (
+	"context"
+	"go-user-api/internal/rebac"
+	"slices"
+	"sort"
+)
+
+// MockTupleStore keeps relation tuples in memory.
+type MockTupleStore struct {
+	Tuples []rebac.Tuple
+}
+
+func (m *MockTupleStore) WriteTuples(_ context.Context, tuples ...rebac.Tuple) error {
+	for _, t := range tuples {
+		if !slices.Contains(m.Tuples, t) {
+			m.Tuples = append(m.Tuples, t)
+		}
+	}
+	return nil
+}
+
+func (m *MockTupleStore) DeleteTuples(_ context.Context, tuples ...rebac.Tuple) error {
+	m.Tuples = slices.DeleteFunc(m.Tuples, func(t rebac.Tuple) bool { return slices.Contains(tuples, t) })
+	return nil
+}
+
+func (m *MockTupleStore) ReadTuples(_ context.Context, object rebac.Object, relation string) ([]rebac.Tuple, error) {
+	var tuples []rebac.Tuple
+	for _, t := range m.Tuples {
+		if t.Object == object && t.Relation == relation {
+			tuples = append(tuples, t)
+		}
+	}
+	return tuples, nil
+}
+
+func (m *MockTupleStore) ObjectIDs(_ context.Context, objectType string) ([]string, error) {
+	var ids []string
+	for _, t := range m.Tuples {
+		if t.Object.Type == objectType && !slices.Contains(ids, t.Object.ID) {
+			ids = append(ids, t.Object.ID)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
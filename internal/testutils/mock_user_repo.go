@@ -2,22 +2,108 @@ package testutils
 
 import (
 	"context"
+	"fmt"
 	"go-user-api/internal/model"
+	"go-user-api/internal/tenant"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
-type MockUserRepo struct{}
+// MockUserRepo serves Get and GetByEmail from Users. With no users it behaves
+// as an empty store. Like the real repository it only sees the users of the
+// organization of the context; users without one belong to the default.
+type MockUserRepo struct {
+	Users []*model.User
+}
+
+// NewMockUser returns a user whose password is hashed with the minimum bcrypt
+// cost, so tests don't pay for the production cost factor.
+func NewMockUser(id int, email string, password string) *model.User {
+	hash, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	return &model.User{ID: id, OrganizationID: tenant.DefaultID, Name: "User " + email, Email: email, Password: string(hash), Role: model.RoleUser}
+}
 
-func (m *MockUserRepo) Create(_ context.Context, u *model.User) error {
+func inTenant(ctx context.Context, u *model.User) bool {
+	id := u.OrganizationID
+	if id == 0 {
+		id = tenant.DefaultID
+	}
+	return id == tenant.ID(ctx)
+}
+
+func (m *MockUserRepo) Create(ctx context.Context, u *model.User) error {
 	u.ID = 1 // Simulate DB auto-increment
+	for _, existing := range m.Users {
+		u.ID = max(u.ID, existing.ID+1)
+	}
+	u.OrganizationID = tenant.ID(ctx)
+	m.Users = append(m.Users, u)
 	return nil
 }
-func (m *MockUserRepo) Get(_ context.Context, id int) (*model.User, error) { return nil, nil }
-func (m *MockUserRepo) GetByEmail(_ context.Context, email string) (*model.User, error) {
-	return nil, nil
+
+// Get leaves the password out, like the real query.
+func (m *MockUserRepo) Get(ctx context.Context, id int) (*model.User, error) {
+	u, err := m.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	user := *u
+	user.Password = ""
+	return &user, nil
+}
+func (m *MockUserRepo) find(ctx context.Context, id int) (*model.User, error) {
+	for _, u := range m.Users {
+		if u.ID == id && inTenant(ctx, u) {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("no users found with id: %d", id)
+}
+func (m *MockUserRepo) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	for _, u := range m.Users {
+		if u.Email == email && inTenant(ctx, u) {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("no users found with email: %s", email)
 }
 func (m *MockUserRepo) Update(_ context.Context, u *model.User) error { return nil }
-func (m *MockUserRepo) Delete(_ context.Context, id int) error        { return nil }
-func (m *MockUserRepo) GetAllUsers(_ context.Context) ([]*model.User, error) {
+func (m *MockUserRepo) UpdateProvisioned(ctx context.Context, u *model.User) error {
+	for i, existing := range m.Users {
+		if existing.ID == u.ID && inTenant(ctx, existing) {
+			m.Users[i] = u
+			return nil
+		}
+	}
+	return fmt.Errorf("no users found with id: %d", u.ID)
+}
+func (m *MockUserRepo) UpdateRole(ctx context.Context, id int, role string) error {
+	u, err := m.find(ctx, id)
+	if err != nil {
+		return err
+	}
+	u.Role = role
+	return nil
+}
+func (m *MockUserRepo) Delete(ctx context.Context, id int) error {
+	for i, u := range m.Users {
+		if u.ID == id && inTenant(ctx, u) {
+			m.Users = append(m.Users[:i], m.Users[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+func (m *MockUserRepo) GetAllUsers(ctx context.Context) ([]*model.User, error) {
+	if len(m.Users) > 0 {
+		users := []*model.User{}
+		for _, u := range m.Users {
+			if inTenant(ctx, u) {
+				users = append(users, u)
+			}
+		}
+		return users, nil
+	}
 	return []*model.User{
 		{ID: 1, Email: "user1@example.com", Name: "User One"},
 		{ID: 2, Email: "user2@example.com", Name: "User Two"},
@@ -0,0 +1,267 @@
+package testutils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+)
+
+// LDAP protocol operations and result codes used by the stub (RFC 4511).
+const (
+	ldapBindRequest      = 0
+	ldapBindResponse     = 1
+	ldapUnbindRequest    = 2
+	ldapSearchRequest    = 3
+	ldapSearchEntry      = 4
+	ldapSearchDone       = 5
+	ldapSuccess          = 0
+	ldapInvalidCreds     = 49
+	ldapUnwillingToServe = 53
+)
+
+type StubLDAPEntry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+// StubLDAPServer is an in-process LDAP server that understands simple binds
+// and searches with equality, presence, and, or and not filters. That is
+// all the directory authenticator needs.
+type StubLDAPServer struct {
+	URL string
+	// CACert is the PEM certificate of a TLS server, to trust in clients.
+	CACert []byte
+
+	BindDN       string
+	BindPassword string
+
+	mu       sync.Mutex
+	entries  []StubLDAPEntry
+	listener net.Listener
+}
+
+// NewStubLDAPServer starts a stub on a loopback port. With useTLS it serves
+// ldaps:// with a freshly generated certificate.
+func NewStubLDAPServer(useTLS bool, entries ...StubLDAPEntry) *StubLDAPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+
+	s := &StubLDAPServer{entries: entries, URL: "ldap://" + listener.Addr().String()}
+
+	if useTLS {
+		cert, certPEM := selfSignedCert()
+		s.CACert = certPEM
+		s.URL = "ldaps://" + listener.Addr().String()
+		listener = tls.NewListener(listener, &tls.Config{Certificates: []tls.Certificate{cert}})
+	}
+
+	s.listener = listener
+	go s.serve()
+
+	return s
+}
+
+func (s *StubLDAPServer) Close() {
+	s.listener.Close()
+}
+
+func (s *StubLDAPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *StubLDAPServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+
+		id := packet.Children[0].Value
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case ldapBindRequest:
+			dn, password := op.Children[1].Data.String(), op.Children[2].Data.String()
+			code := ldapInvalidCreds
+			if s.checkBind(dn, password) {
+				code = ldapSuccess
+			}
+			conn.Write(ldapMessage(id, ldapResult(ldapBindResponse, code)).Bytes())
+		case ldapSearchRequest:
+			base, filter := op.Children[0].Data.String(), op.Children[6]
+			for _, entry := range s.search(base, filter) {
+				conn.Write(ldapMessage(id, searchEntry(entry)).Bytes())
+			}
+			conn.Write(ldapMessage(id, ldapResult(ldapSearchDone, ldapSuccess)).Bytes())
+		case ldapUnbindRequest:
+			return
+		default:
+			// e.g. StartTLS; the stub only does TLS from the first byte
+			conn.Write(ldapMessage(id, ldapResult(op.Tag+1, ldapUnwillingToServe)).Bytes())
+		}
+	}
+}
+
+func (s *StubLDAPServer) checkBind(dn string, password string) bool {
+	if password == "" {
+		return false
+	}
+
+	if s.BindDN != "" && strings.EqualFold(dn, s.BindDN) {
+		return password == s.BindPassword
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.entries {
+		if strings.EqualFold(e.DN, dn) {
+			return e.Password == password
+		}
+	}
+
+	return false
+}
+
+func (s *StubLDAPServer) search(base string, filter *ber.Packet) []StubLDAPEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var found []StubLDAPEntry
+	for _, e := range s.entries {
+		if strings.HasSuffix(strings.ToLower(e.DN), strings.ToLower(base)) && matchLDAPFilter(e, filter) {
+			found = append(found, e)
+		}
+	}
+
+	return found
+}
+
+func matchLDAPFilter(e StubLDAPEntry, f *ber.Packet) bool {
+	switch f.Tag {
+	case 0: // and
+		for _, child := range f.Children {
+			if !matchLDAPFilter(e, child) {
+				return false
+			}
+		}
+		return true
+	case 1: // or
+		for _, child := range f.Children {
+			if matchLDAPFilter(e, child) {
+				return true
+			}
+		}
+		return false
+	case 2: // not
+		return !matchLDAPFilter(e, f.Children[0])
+	case 3: // equality
+		for _, v := range entryValues(e, f.Children[0].Data.String()) {
+			if strings.EqualFold(v, f.Children[1].Data.String()) {
+				return true
+			}
+		}
+		return false
+	case 7: // present
+		return len(entryValues(e, f.Data.String())) > 0
+	}
+
+	return false
+}
+
+func entryValues(e StubLDAPEntry, attr string) []string {
+	for name, values := range e.Attributes {
+		if strings.EqualFold(name, attr) {
+			return values
+		}
+	}
+
+	return nil
+}
+
+func ldapMessage(id any, op *ber.Packet) *ber.Packet {
+	msg := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	msg.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "Message ID"))
+	msg.AppendChild(op)
+	return msg
+}
+
+func ldapResult(tag ber.Tag, code int) *ber.Packet {
+	res := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	res.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	res.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	res.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return res
+}
+
+func searchEntry(e StubLDAPEntry) *ber.Packet {
+	entry := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldapSearchEntry, nil, "Search Result Entry")
+	entry.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.DN, "DN"))
+
+	attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for name, values := range e.Attributes {
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+
+		vals := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, v := range values {
+			vals.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "Value"))
+		}
+
+		attr.AppendChild(vals)
+		attrs.AppendChild(attr)
+	}
+
+	entry.AppendChild(attrs)
+	return entry
+}
+
+func selfSignedCert() (tls.Certificate, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "stub-ldap"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		panic(err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key},
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -0,0 +1,9 @@
+CREATE TABLE clients (
+  id SERIAL PRIMARY KEY,
+  client_id TEXT UNIQUE NOT NULL,
+  name TEXT NOT NULL,
+  secret_hash TEXT NOT NULL,
+  scopes TEXT[] NOT NULL DEFAULT '{}',
+  created_at TIMESTAMP DEFAULT now(),
+  secret_rotated_at TIMESTAMP DEFAULT now()
+);
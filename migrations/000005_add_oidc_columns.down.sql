@@ -0,0 +1,3 @@
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS auth_time;
+ALTER TABLE authorization_codes DROP COLUMN IF EXISTS nonce, DROP COLUMN IF EXISTS auth_time;
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified;
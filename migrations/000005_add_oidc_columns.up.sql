@@ -0,0 +1,7 @@
+ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE authorization_codes
+  ADD COLUMN nonce TEXT NOT NULL DEFAULT '',
+  ADD COLUMN auth_time TIMESTAMP NOT NULL DEFAULT now();
+
+ALTER TABLE refresh_tokens ADD COLUMN auth_time TIMESTAMP NOT NULL DEFAULT now();
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS groups;
+
+ALTER TABLE users
+  DROP COLUMN IF EXISTS updated_at,
+  DROP COLUMN IF EXISTS disabled,
+  DROP COLUMN IF EXISTS external_id;
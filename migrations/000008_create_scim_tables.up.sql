@@ -0,0 +1,20 @@
+ALTER TABLE users
+  ADD COLUMN external_id TEXT NOT NULL DEFAULT '',
+  ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT false,
+  ADD COLUMN updated_at TIMESTAMP DEFAULT now();
+
+CREATE TABLE groups (
+  id SERIAL PRIMARY KEY,
+  display_name TEXT UNIQUE NOT NULL,
+  external_id TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP DEFAULT now(),
+  updated_at TIMESTAMP DEFAULT now()
+);
+
+CREATE TABLE group_members (
+  group_id INTEGER NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+  user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX group_members_user_id_idx ON group_members (user_id);
@@ -0,0 +1,14 @@
+CREATE TABLE login_attempts (
+  id BIGSERIAL PRIMARY KEY,
+  user_id INTEGER REFERENCES users (id) ON DELETE CASCADE,
+  email TEXT NOT NULL DEFAULT '',
+  outcome TEXT NOT NULL,
+  ip TEXT NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  fingerprint TEXT NOT NULL DEFAULT '',
+  new_device BOOLEAN NOT NULL DEFAULT false,
+  created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX login_attempts_user_id_idx ON login_attempts (user_id, created_at DESC);
+CREATE INDEX login_attempts_fingerprint_idx ON login_attempts (user_id, fingerprint) WHERE outcome = 'success';
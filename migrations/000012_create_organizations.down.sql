@@ -0,0 +1,9 @@
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS organization_id;
+ALTER TABLE authorization_codes DROP COLUMN IF EXISTS organization_id;
+
+ALTER TABLE users
+  DROP CONSTRAINT IF EXISTS users_organization_email_key,
+  DROP COLUMN IF EXISTS organization_id,
+  ADD CONSTRAINT users_email_key UNIQUE (email);
+
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,21 @@
+CREATE TABLE organizations (
+  id SERIAL PRIMARY KEY,
+  slug TEXT UNIQUE NOT NULL,
+  name TEXT NOT NULL,
+  created_at TIMESTAMP DEFAULT now()
+);
+
+-- existing users and grants move to the default organization, which keeps id 1
+INSERT INTO organizations (id, slug, name) VALUES (1, 'default', 'Default');
+SELECT setval('organizations_id_seq', 1);
+
+ALTER TABLE users
+  ADD COLUMN organization_id INTEGER NOT NULL DEFAULT 1 REFERENCES organizations (id) ON DELETE CASCADE,
+  DROP CONSTRAINT users_email_key,
+  ADD CONSTRAINT users_organization_email_key UNIQUE (organization_id, email);
+
+ALTER TABLE authorization_codes
+  ADD COLUMN organization_id INTEGER NOT NULL DEFAULT 1 REFERENCES organizations (id) ON DELETE CASCADE;
+
+ALTER TABLE refresh_tokens
+  ADD COLUMN organization_id INTEGER NOT NULL DEFAULT 1 REFERENCES organizations (id) ON DELETE CASCADE;
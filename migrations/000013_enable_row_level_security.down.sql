@@ -0,0 +1,4 @@
+DROP POLICY IF EXISTS users_tenant_isolation ON users;
+
+ALTER TABLE users NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE users DISABLE ROW LEVEL SECURITY;
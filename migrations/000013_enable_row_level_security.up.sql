@@ -0,0 +1,11 @@
+-- A second line of defense behind the organization filter in every user
+-- query: rows are only visible in a transaction whose app.tenant_id names
+-- their organization, and with no tenant set nothing is visible at all.
+-- Superusers and roles with BYPASSRLS skip policies, so the application must
+-- connect as an ordinary role for this to take effect.
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE users FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY users_tenant_isolation ON users
+  USING (organization_id = NULLIF(current_setting('app.tenant_id', true), '')::integer)
+  WITH CHECK (organization_id = NULLIF(current_setting('app.tenant_id', true), '')::integer);
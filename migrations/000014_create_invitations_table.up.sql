@@ -0,0 +1,17 @@
+CREATE TABLE invitations (
+  id SERIAL PRIMARY KEY,
+  organization_id INTEGER NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+  email TEXT NOT NULL,
+  role TEXT NOT NULL DEFAULT 'user',
+  invited_by INTEGER REFERENCES users (id) ON DELETE SET NULL,
+  -- jti of the latest emailed token; resending replaces it, so earlier links stop working
+  token_id TEXT NOT NULL DEFAULT '',
+  expires_at TIMESTAMP NOT NULL,
+  accepted_at TIMESTAMP,
+  revoked_at TIMESTAMP,
+  created_at TIMESTAMP DEFAULT now()
+);
+
+-- one open invitation per address and organization; expired ones are resent
+CREATE UNIQUE INDEX invitations_open_email_idx ON invitations (organization_id, lower(email))
+  WHERE accepted_at IS NULL AND revoked_at IS NULL;
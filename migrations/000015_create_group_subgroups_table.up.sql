@@ -0,0 +1,9 @@
+-- nested groups: members of child_id are effective members of parent_id
+CREATE TABLE group_subgroups (
+  parent_id INTEGER NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+  child_id INTEGER NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+  PRIMARY KEY (parent_id, child_id),
+  CHECK (parent_id <> child_id)
+);
+
+CREATE INDEX group_subgroups_child_idx ON group_subgroups (child_id);
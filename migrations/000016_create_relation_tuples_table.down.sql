@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS users_relation_tuples ON users;
+DROP FUNCTION IF EXISTS relation_tuples_sync_user();
+DROP TABLE IF EXISTS relation_tuples;
@@ -0,0 +1,42 @@
+CREATE TABLE relation_tuples (
+  object_type TEXT NOT NULL,
+  object_id TEXT NOT NULL,
+  relation TEXT NOT NULL,
+  subject_type TEXT NOT NULL,
+  subject_id TEXT NOT NULL,
+  -- set for usersets such as team:1#member
+  subject_relation TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP DEFAULT now(),
+  PRIMARY KEY (object_type, object_id, relation, subject_type, subject_id, subject_relation)
+);
+
+CREATE INDEX relation_tuples_subject_idx ON relation_tuples (subject_type, subject_id);
+
+-- every user holds self on their own profile, and a deleted user leaves no
+-- tuples behind
+CREATE FUNCTION relation_tuples_sync_user() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    INSERT INTO relation_tuples (object_type, object_id, relation, subject_type, subject_id)
+    VALUES ('user', NEW.id::text, 'self', 'user', NEW.id::text)
+    ON CONFLICT DO NOTHING;
+    RETURN NEW;
+  END IF;
+
+  DELETE FROM relation_tuples
+  WHERE (object_type = 'user' AND object_id = OLD.id::text)
+     OR (subject_type = 'user' AND subject_id = OLD.id::text);
+  RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_relation_tuples
+  AFTER INSERT OR DELETE ON users
+  FOR EACH ROW EXECUTE FUNCTION relation_tuples_sync_user();
+
+-- the backfill reads the users of every organization, so row-level security
+-- is lifted for the table owner while it runs
+ALTER TABLE users NO FORCE ROW LEVEL SECURITY;
+INSERT INTO relation_tuples (object_type, object_id, relation, subject_type, subject_id)
+SELECT 'user', id::text, 'self', 'user', id::text FROM users;
+ALTER TABLE users FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,8 @@
+-- the state of the rate limits shared by every instance: tat is the time at
+-- which the burst of key is fully restored, after which the row can go
+CREATE TABLE rate_limits (
+  key TEXT PRIMARY KEY,
+  tat TIMESTAMP NOT NULL
+);
+
+CREATE INDEX rate_limits_tat_idx ON rate_limits (tat);
@@ -0,0 +1,10 @@
+DROP POLICY IF EXISTS invitations_tenant_isolation ON invitations;
+DROP POLICY IF EXISTS refresh_tokens_tenant_isolation ON refresh_tokens;
+DROP POLICY IF EXISTS authorization_codes_tenant_isolation ON authorization_codes;
+
+ALTER TABLE invitations NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE invitations DISABLE ROW LEVEL SECURITY;
+ALTER TABLE refresh_tokens NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE refresh_tokens DISABLE ROW LEVEL SECURITY;
+ALTER TABLE authorization_codes NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE authorization_codes DISABLE ROW LEVEL SECURITY;
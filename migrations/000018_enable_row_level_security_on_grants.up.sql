@@ -0,0 +1,28 @@
+-- The organization-owned grant and invitation tables get the policy of
+-- users. Codes and refresh tokens are also visible to a transaction holding
+-- their secret in app.secret_hash, as the token endpoint redeems them before
+-- it knows their organization.
+ALTER TABLE authorization_codes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE authorization_codes FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY authorization_codes_tenant_isolation ON authorization_codes
+  USING (organization_id = NULLIF(current_setting('app.tenant_id', true), '')::integer
+    OR code_hash = NULLIF(current_setting('app.secret_hash', true), ''))
+  WITH CHECK (organization_id = NULLIF(current_setting('app.tenant_id', true), '')::integer
+    OR code_hash = NULLIF(current_setting('app.secret_hash', true), ''));
+
+ALTER TABLE refresh_tokens ENABLE ROW LEVEL SECURITY;
+ALTER TABLE refresh_tokens FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY refresh_tokens_tenant_isolation ON refresh_tokens
+  USING (organization_id = NULLIF(current_setting('app.tenant_id', true), '')::integer
+    OR token_hash = NULLIF(current_setting('app.secret_hash', true), ''))
+  WITH CHECK (organization_id = NULLIF(current_setting('app.tenant_id', true), '')::integer
+    OR token_hash = NULLIF(current_setting('app.secret_hash', true), ''));
+
+ALTER TABLE invitations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE invitations FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY invitations_tenant_isolation ON invitations
+  USING (organization_id = NULLIF(current_setting('app.tenant_id', true), '')::integer)
+  WITH CHECK (organization_id = NULLIF(current_setting('app.tenant_id', true), '')::integer);
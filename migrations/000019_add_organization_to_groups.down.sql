@@ -0,0 +1,9 @@
+DROP POLICY IF EXISTS groups_tenant_isolation ON groups;
+
+ALTER TABLE groups NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE groups DISABLE ROW LEVEL SECURITY;
+
+ALTER TABLE groups
+  DROP CONSTRAINT groups_organization_display_name_key,
+  DROP COLUMN organization_id,
+  ADD CONSTRAINT groups_display_name_key UNIQUE (display_name);
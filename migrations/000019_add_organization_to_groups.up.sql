@@ -0,0 +1,14 @@
+-- Groups belong to an organization like users do; existing groups move to
+-- the default organization. Names only need to be unique per organization.
+ALTER TABLE groups
+  ADD COLUMN organization_id INTEGER NOT NULL DEFAULT 1 REFERENCES organizations (id) ON DELETE CASCADE,
+  DROP CONSTRAINT groups_display_name_key,
+  ADD CONSTRAINT groups_organization_display_name_key UNIQUE (organization_id, display_name);
+
+-- members and subgroups get policies of their own in 000021
+ALTER TABLE groups ENABLE ROW LEVEL SECURITY;
+ALTER TABLE groups FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY groups_tenant_isolation ON groups
+  USING (organization_id = NULLIF(current_setting('app.tenant_id', true), '')::integer)
+  WITH CHECK (organization_id = NULLIF(current_setting('app.tenant_id', true), '')::integer);
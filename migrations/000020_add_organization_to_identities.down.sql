@@ -0,0 +1,9 @@
+DROP POLICY IF EXISTS identities_tenant_isolation ON identities;
+
+ALTER TABLE identities NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE identities DISABLE ROW LEVEL SECURITY;
+
+ALTER TABLE identities
+  DROP CONSTRAINT identities_organization_provider_subject_key,
+  DROP COLUMN organization_id,
+  ADD CONSTRAINT identities_provider_subject_key UNIQUE (provider, subject);
@@ -0,0 +1,21 @@
+-- Identities belong to the organization of their user, so one external
+-- account can be linked in several organizations. The backfill reads users,
+-- which is hidden behind row-level security, so the policy is lifted while
+-- it runs.
+ALTER TABLE identities ADD COLUMN organization_id INTEGER REFERENCES organizations (id) ON DELETE CASCADE;
+
+ALTER TABLE users NO FORCE ROW LEVEL SECURITY;
+UPDATE identities i SET organization_id = u.organization_id FROM users u WHERE u.id = i.user_id;
+ALTER TABLE users FORCE ROW LEVEL SECURITY;
+
+ALTER TABLE identities
+  ALTER COLUMN organization_id SET NOT NULL,
+  DROP CONSTRAINT identities_provider_subject_key,
+  ADD CONSTRAINT identities_organization_provider_subject_key UNIQUE (organization_id, provider, subject);
+
+ALTER TABLE identities ENABLE ROW LEVEL SECURITY;
+ALTER TABLE identities FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY identities_tenant_isolation ON identities
+  USING (organization_id = NULLIF(current_setting('app.tenant_id', true), '')::integer)
+  WITH CHECK (organization_id = NULLIF(current_setting('app.tenant_id', true), '')::integer);
@@ -0,0 +1,16 @@
+DROP POLICY IF EXISTS group_subgroups_tenant_isolation ON group_subgroups;
+DROP POLICY IF EXISTS group_members_tenant_isolation ON group_members;
+DROP POLICY IF EXISTS login_attempts_tenant_isolation ON login_attempts;
+DROP POLICY IF EXISTS sessions_tenant_isolation ON sessions;
+
+ALTER TABLE group_subgroups NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE group_subgroups DISABLE ROW LEVEL SECURITY;
+ALTER TABLE group_members NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE group_members DISABLE ROW LEVEL SECURITY;
+ALTER TABLE login_attempts NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE login_attempts DISABLE ROW LEVEL SECURITY;
+ALTER TABLE sessions NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE sessions DISABLE ROW LEVEL SECURITY;
+
+ALTER TABLE login_attempts DROP COLUMN organization_id;
+ALTER TABLE sessions DROP COLUMN organization_id;
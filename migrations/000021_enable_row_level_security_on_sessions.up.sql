@@ -0,0 +1,50 @@
+-- Sessions and login attempts belong to the organization of their user.
+-- Attempts for an email that matched no account stay in the default
+-- organization. The backfill reads users, which is hidden behind row-level
+-- security, so the policy is lifted while it runs.
+ALTER TABLE sessions ADD COLUMN organization_id INTEGER REFERENCES organizations (id) ON DELETE CASCADE;
+ALTER TABLE login_attempts ADD COLUMN organization_id INTEGER REFERENCES organizations (id) ON DELETE CASCADE;
+
+ALTER TABLE users NO FORCE ROW LEVEL SECURITY;
+UPDATE sessions s SET organization_id = u.organization_id FROM users u WHERE u.id = s.user_id;
+UPDATE login_attempts a SET organization_id = u.organization_id FROM users u WHERE u.id = a.user_id;
+ALTER TABLE users FORCE ROW LEVEL SECURITY;
+
+UPDATE login_attempts SET organization_id = 1 WHERE organization_id IS NULL;
+
+ALTER TABLE sessions ALTER COLUMN organization_id SET NOT NULL;
+ALTER TABLE login_attempts ALTER COLUMN organization_id SET NOT NULL;
+
+ALTER TABLE sessions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE sessions FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY sessions_tenant_isolation ON sessions
+  USING (organization_id = NULLIF(current_setting('app.tenant_id', true), '')::integer)
+  WITH CHECK (organization_id = NULLIF(current_setting('app.tenant_id', true), '')::integer);
+
+ALTER TABLE login_attempts ENABLE ROW LEVEL SECURITY;
+ALTER TABLE login_attempts FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY login_attempts_tenant_isolation ON login_attempts
+  USING (organization_id = NULLIF(current_setting('app.tenant_id', true), '')::integer)
+  WITH CHECK (organization_id = NULLIF(current_setting('app.tenant_id', true), '')::integer);
+
+-- Members and subgroups have no organization of their own: a row is visible
+-- when its group is, which the policy on groups decides, and can only be
+-- written when the user or subgroup it links is visible too. Without these
+-- a query that forgets to join groups would see every organization's rows.
+ALTER TABLE group_members ENABLE ROW LEVEL SECURITY;
+ALTER TABLE group_members FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY group_members_tenant_isolation ON group_members
+  USING (EXISTS (SELECT 1 FROM groups g WHERE g.id = group_members.group_id))
+  WITH CHECK (EXISTS (SELECT 1 FROM groups g WHERE g.id = group_members.group_id)
+    AND EXISTS (SELECT 1 FROM users u WHERE u.id = group_members.user_id));
+
+ALTER TABLE group_subgroups ENABLE ROW LEVEL SECURITY;
+ALTER TABLE group_subgroups FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY group_subgroups_tenant_isolation ON group_subgroups
+  USING (EXISTS (SELECT 1 FROM groups g WHERE g.id = group_subgroups.parent_id))
+  WITH CHECK (EXISTS (SELECT 1 FROM groups g WHERE g.id = group_subgroups.parent_id)
+    AND EXISTS (SELECT 1 FROM groups g WHERE g.id = group_subgroups.child_id));